@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+	xmlNamespace   = "http://www.w3.org/XML/1998/namespace"
+)
+
+// knownNamespaces maps commonly used prefixes to their namespace URI.
+var knownNamespaces = map[string]string{
+	"inkscape": inkscapeNamespace,
+	"sodipodi": sodipodiNamespace,
+	"xlink":    xlinkNamespace,
+	"xml":      xmlNamespace,
+}
+
+// Attributes holds the XML attributes of an element which are not
+// stored in one of its fields, keyed by their namespace qualified name.
+// The Space of a key is the namespace URI if the document declared the
+// namespace, otherwise it is the prefix used in the document.
+type Attributes map[xml.Name]string
+
+// UnmarshalXMLAttr implements the encoding.xml.UnmarshalerAttr interface
+func (a *Attributes) UnmarshalXMLAttr(attr xml.Attr) error {
+	a.Set(attr.Name, attr.Value)
+	return nil
+}
+
+// Set stores the value of an attribute, creating the map if needed.
+func (a *Attributes) Set(name xml.Name, value string) {
+	if *a == nil {
+		*a = make(Attributes)
+	}
+	(*a)[name] = value
+}
+
+// Get returns the value of an attribute. The name is either a local
+// name like "data-power" or a prefixed name like "inkscape:label". The
+// prefixes inkscape, sodipodi, xlink and xml are resolved to their
+// namespace URI, other prefixes match undeclared namespaces and
+// declared namespaces whose URI is given in place of the prefix as in
+// "http://example.com/cad:power".
+func (a Attributes) Get(name string) (string, bool) {
+	var n xml.Name
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		n.Space, n.Local = name[:i], name[i+1:]
+	} else {
+		n.Local = name
+	}
+	if v, ok := a[n]; ok {
+		return v, true
+	}
+	if uri, ok := knownNamespaces[n.Space]; ok {
+		v, ok := a[xml.Name{Space: uri, Local: n.Local}]
+		return v, ok
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package svg
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributes(t *testing.T) {
+	content := `<svg xmlns="http://www.w3.org/2000/svg"
+	xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+	xmlns:cad="http://example.com/cad"
+	version="1.1" viewBox="0 0 10 10">
+<g id="g1" class="parts" inkscape:label="Cut" cad:job="42">
+	<path d="M0 0 L1 1" data-power="80" data-speed="1200" sodipodi:nodetypes="cc"/>
+	<circle cx="1" cy="1" r="1" class="hole"/>
+	<rect width="1" height="1" data-power="20"/>
+</g>
+</svg>`
+	s, err := ParseSvg(content, "attributes", 1)
+	require.NoError(t, err)
+
+	v, ok := s.Attrs.Get("version")
+	require.True(t, ok)
+	require.Equal(t, "1.1", v)
+	require.Equal(t, "http://example.com/cad", s.Attrs[xml.Name{Space: "xmlns", Local: "cad"}])
+
+	g := s.Groups[0]
+	v, _ = g.Attrs.Get("class")
+	require.Equal(t, "parts", v)
+	v, _ = g.Attrs.Get("inkscape:label")
+	require.Equal(t, "Cut", v)
+	v, _ = g.Attrs.Get("http://example.com/cad:job")
+	require.Equal(t, "42", v)
+	_, ok = g.Attrs.Get("id")
+	require.False(t, ok)
+
+	p := g.Elements[0].(*Path)
+	v, _ = p.Attrs.Get("data-power")
+	require.Equal(t, "80", v)
+	v, _ = p.Attrs.Get("data-speed")
+	require.Equal(t, "1200", v)
+	v, _ = p.Attrs.Get("sodipodi:nodetypes")
+	require.Equal(t, "cc", v)
+	_, ok = p.Attrs.Get("d")
+	require.False(t, ok)
+
+	v, _ = g.Elements[1].(*Circle).Attrs.Get("class")
+	require.Equal(t, "hole", v)
+	v, _ = g.Elements[2].(*Rect).Attrs.Get("data-power")
+	require.Equal(t, "20", v)
+}
@@ -0,0 +1,257 @@
+package svg
+
+import "math"
+
+// Box is an axis aligned rectangle, for example the area a drawing
+// occupies. A box with a minimum above its maximum is empty.
+type Box struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// emptyBox contains nothing, and is the identity of Union.
+var emptyBox = Box{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+// Empty reports whether the box contains no point.
+func (b Box) Empty() bool {
+	return b.MinX > b.MaxX || b.MinY > b.MaxY
+}
+
+// Width returns the width of the box, 0 when it is empty.
+func (b Box) Width() float64 {
+	if b.Empty() {
+		return 0
+	}
+	return b.MaxX - b.MinX
+}
+
+// Height returns the height of the box, 0 when it is empty.
+func (b Box) Height() float64 {
+	if b.Empty() {
+		return 0
+	}
+	return b.MaxY - b.MinY
+}
+
+// Union returns the smallest box containing both boxes.
+func (b Box) Union(o Box) Box {
+	return Box{
+		math.Min(b.MinX, o.MinX), math.Min(b.MinY, o.MinY),
+		math.Max(b.MaxX, o.MaxX), math.Max(b.MaxY, o.MaxY),
+	}
+}
+
+// extend returns the smallest box containing b and p.
+func (b Box) extend(p [2]float64) Box {
+	return b.Union(Box{p[0], p[1], p[0], p[1]})
+}
+
+// Bounds returns the box spanned by the end points.
+func (c LineCurve) Bounds() Box {
+	return emptyBox.extend(c.P0).extend(c.P1)
+}
+
+// Bounds returns the box around the end points and the extrema of the
+// curve, which is tighter than the box around its control points.
+func (c QuadraticCurve) Bounds() Box {
+	b := emptyBox.extend(c.P0).extend(c.P1)
+	for k := 0; k < 2; k++ {
+		if d := c.P0[k] - 2*c.C[k] + c.P1[k]; d != 0 {
+			if t := (c.P0[k] - c.C[k]) / d; t > 0 && t < 1 {
+				b = b.extend(c.PointAt(t))
+			}
+		}
+	}
+	return b
+}
+
+// Bounds returns the box around the end points and the points where
+// the curve turns horizontally or vertically.
+func (c CubicCurve) Bounds() Box {
+	b := emptyBox.extend(c.P0).extend(c.P1)
+	for k := 0; k < 2; k++ {
+		// The derivative divided by 3 is a t² + b t + c.
+		qa := -c.P0[k] + 3*c.C1[k] - 3*c.C2[k] + c.P1[k]
+		qb := 2 * (c.P0[k] - 2*c.C1[k] + c.C2[k])
+		qc := c.C1[k] - c.P0[k]
+		for _, t := range quadraticRoots(qa, qb, qc) {
+			if t > 0 && t < 1 {
+				b = b.extend(c.PointAt(t))
+			}
+		}
+	}
+	return b
+}
+
+// Bounds returns the box around the end points of the arc and the
+// extrema of its ellipse that lie within the sweep.
+func (c ArcCurve) Bounds() Box {
+	b := emptyBox.extend(c.PointAt(0)).extend(c.PointAt(1))
+	if c.Sweep == 0 {
+		return b
+	}
+	for k := 0; k < 2; k++ {
+		// U cos a + V sin a is extreme where tan a = V / U.
+		a := math.Atan2(c.V[k], c.U[k])
+		for _, e := range []float64{a, a + math.Pi} {
+			d := e - c.Start
+			if c.Sweep < 0 {
+				d = -d
+			}
+			d = math.Mod(d, 2*math.Pi)
+			if d < 0 {
+				d += 2 * math.Pi
+			}
+			if t := d / math.Abs(c.Sweep); t < 1 {
+				b = b.extend(c.PointAt(t))
+			}
+		}
+	}
+	return b
+}
+
+// quadraticRoots returns the real roots of a x² + b x + c.
+func quadraticRoots(a, b, c float64) []float64 {
+	if math.Abs(a) < 1e-12*(math.Abs(b)+math.Abs(c)) || a == 0 {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return nil
+	}
+	// Avoid cancellation by computing the larger root first.
+	q := -(b + math.Copysign(math.Sqrt(disc), b)) / 2
+	if q == 0 {
+		return []float64{0}
+	}
+	return []float64{q / a, c / q}
+}
+
+// Bounds returns the smallest box containing all curves of the path.
+func (g *PathGeometry) Bounds() Box {
+	b := emptyBox
+	for _, c := range g.curves() {
+		b = b.Union(c.Bounds())
+	}
+	return b
+}
+
+// boxer is implemented by elements with a bounding box.
+type boxer interface {
+	BBox() Box
+	StrokeBBox() Box
+}
+
+// elementBox returns the fill or stroke box of an element, or an empty
+// box for elements without one.
+func elementBox(e DrawingInstructionParser, stroke bool) Box {
+	b, ok := e.(boxer)
+	switch {
+	case !ok:
+		return emptyBox
+	case stroke:
+		return b.StrokeBBox()
+	}
+	return b.BBox()
+}
+
+// shape is implemented by the elements drawn from curves and a paint.
+type shape interface {
+	Geometry() (*PathGeometry, error)
+	Paint() *DrawingInstruction
+}
+
+// shapeBox returns the box of the curves of a shape and, if stroke is
+// set and the shape is stroked, of its stroke.
+func shapeBox(s shape, stroke bool) Box {
+	g, _ := s.Geometry()
+	if g == nil {
+		return emptyBox
+	}
+	b := g.Bounds()
+	if paint := s.Paint(); stroke && stroked(paint) {
+		for _, o := range shapeOutlines(g, paint) {
+			for _, pt := range o.Points {
+				b = b.extend(pt)
+			}
+		}
+	}
+	return b
+}
+
+// BBox returns the exact box of the geometry of the path, with its
+// transforms applied, from the extrema of its curves rather than their
+// control points. A path without curves has an empty box.
+func (p *Path) BBox() Box {
+	return shapeBox(p, false)
+}
+
+// StrokeBBox is like BBox but includes the stroke with its width, caps
+// and joins when the path is stroked. The stroke is measured on its
+// outline, which is accurate to a thousandth of the stroke width.
+func (p *Path) StrokeBBox() Box {
+	return shapeBox(p, true)
+}
+
+// BBox returns the box of the circle with its transforms applied. See
+// Path.BBox.
+func (c *Circle) BBox() Box {
+	return shapeBox(c, false)
+}
+
+// StrokeBBox is like BBox but includes the stroke. See Path.StrokeBBox.
+func (c *Circle) StrokeBBox() Box {
+	return shapeBox(c, true)
+}
+
+// BBox returns the box of the rect with its transforms applied. See
+// Path.BBox.
+func (r *Rect) BBox() Box {
+	return shapeBox(r, false)
+}
+
+// StrokeBBox is like BBox but includes the stroke. See Path.StrokeBBox.
+func (r *Rect) StrokeBBox() Box {
+	return shapeBox(r, true)
+}
+
+// BBox returns the box of the elements of the group that would be drawn
+// by ParseDrawingInstructions. See Path.BBox.
+func (g *Group) BBox() Box {
+	return g.box(false)
+}
+
+// StrokeBBox is like BBox but includes strokes. See Path.StrokeBBox.
+func (g *Group) StrokeBBox() Box {
+	return g.box(true)
+}
+
+func (g *Group) box(stroke bool) Box {
+	b := emptyBox
+	g.drawnElements(func(e DrawingInstructionParser, _ *Group) {
+		b = b.Union(elementBox(e, stroke))
+	})
+	return b
+}
+
+// BBox returns the box of all elements of the document that would be
+// drawn by ParseDrawingInstructions, the area the drawing occupies. See
+// Path.BBox.
+func (s *Svg) BBox() Box {
+	return s.box(false)
+}
+
+// StrokeBBox is like BBox but includes strokes. See Path.StrokeBBox.
+func (s *Svg) StrokeBBox() Box {
+	return s.box(true)
+}
+
+func (s *Svg) box(stroke bool) Box {
+	b := emptyBox
+	s.VisitElements(func(e DrawingInstructionParser, _ *Group) {
+		b = b.Union(elementBox(e, stroke))
+	})
+	return b
+}
@@ -0,0 +1,117 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireBox(t *testing.T, expected, actual Box, delta float64) {
+	require.InDelta(t, expected.MinX, actual.MinX, delta)
+	require.InDelta(t, expected.MinY, actual.MinY, delta)
+	require.InDelta(t, expected.MaxX, actual.MaxX, delta)
+	require.InDelta(t, expected.MaxY, actual.MaxY, delta)
+}
+
+func TestCurveBounds(t *testing.T) {
+	for _, tc := range []struct {
+		d   string
+		box Box
+	}{
+		{"M0 0 L10 -5", Box{0, -5, 10, 0}},
+		// The control points reach y = 100, the curve only y = 50.
+		{"M0 0 Q50 100 100 0", Box{0, 0, 100, 50}},
+		{"M0 0 C0 100 100 100 100 0", Box{0, 0, 100, 75}},
+		// An S shaped curve with extrema on both sides of its chord.
+		{"M0 0 C100 100 -100 100 0 0", Box{-28.867513459481287, 0, 28.867513459481287, 75}},
+		{"M10 0 A10 10 0 0 1 -10 0", Box{-10, 0, 10, 10}},
+		{"M10 0 A10 10 0 0 0 -10 0", Box{-10, -10, 10, 0}},
+		{"M10 0 A10 10 0 1 1 0 -10", Box{-10, -10, 10, 10}},
+		// An ellipse rotated by 45 degrees.
+		{"M0 0 a20 10 45 1 1 0 .001 z", Box{}},
+	} {
+		g := parseGeometry(t, tc.d)
+		b := g.Bounds()
+		if tc.box == (Box{}) {
+			// Compare with a dense sampling instead.
+			tc.box = emptyBox
+			for _, c := range g.curves() {
+				for i := 0; i <= 100000; i++ {
+					tc.box = tc.box.extend(c.PointAt(float64(i) / 100000))
+				}
+			}
+			requireBox(t, tc.box, b, 1e-6)
+			// The radius along the axes of a rotated ellipse.
+			require.InDelta(t, 2*math.Sqrt((400+100)/2.0), b.Width(), 1e-6)
+			continue
+		}
+		requireBox(t, tc.box, b, 1e-9)
+	}
+	require.True(t, parseGeometry(t, "M0 0").Bounds().Empty())
+}
+
+func TestBBox(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<path d="M10 10 L20 10" stroke="black" stroke-width="2"/>
+		<g transform="translate(50 50)">
+			<path d="M0 0 Q10 20 20 0" fill="red"/>
+			<path d="M0 0 L100 100" display="none"/>
+			<g visibility="hidden">
+				<path d="M0 0 L-100 -100"/>
+			</g>
+		</g>
+		<circle cx="0" cy="80" r="5"/>
+	</svg>`, "bbox", 1)
+	require.NoError(t, err)
+
+	path := s.Elements[0].(*Path)
+	requireBox(t, Box{10, 10, 20, 10}, path.BBox(), 0)
+	requireBox(t, Box{10, 9, 20, 11}, path.StrokeBBox(), 1e-9)
+	path.StrokeLineCap = strPtr("square")
+	requireBox(t, Box{9, 9, 21, 11}, path.StrokeBBox(), 1e-9)
+
+	group := &s.Groups[0]
+	requireBox(t, Box{50, 50, 70, 60}, group.BBox(), 1e-9)
+	require.Equal(t, group.BBox(), group.StrokeBBox())
+
+	requireBox(t, Box{-5, 10, 70, 85}, s.BBox(), 1e-9)
+	requireBox(t, Box{-5, 9, 70, 85}, s.StrokeBBox(), 1e-9)
+
+	hidden, err := ParseSvg(`<svg><g visibility="hidden"><path d="M0 0 L1 1"/></g></svg>`, "hidden", 1)
+	require.NoError(t, err)
+	require.True(t, hidden.BBox().Empty())
+	require.Equal(t, 0.0, hidden.BBox().Width())
+}
+
+func TestShapeBBox(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<g transform="translate(50 0)">
+			<circle cx="10" cy="10" r="5" transform="scale(2 1)" stroke="blue" stroke-width="2"/>
+			<rect x="0" y="0" width="20" height="10" rx="4" transform="matrix(0 1 -1 0 0 0)"/>
+		</g>
+	</svg>`, "shapes", 1)
+	require.NoError(t, err)
+	elements := s.Groups[0].Elements
+
+	// The circle is stretched into an ellipse by its own transform and
+	// moved by the transform of its group.
+	circle := elements[0].(*Circle)
+	requireBox(t, Box{60, 5, 80, 15}, circle.BBox(), 1e-9)
+	requireBox(t, Box{59, 4, 81, 16}, circle.StrokeBBox(), 1e-2)
+
+	// Rotated by 90 degrees the rect covers x from -10 to 0, around 50.
+	rect := elements[1].(*Rect)
+	requireBox(t, Box{40, 0, 50, 20}, rect.BBox(), 1e-9)
+	require.Equal(t, rect.BBox(), rect.StrokeBBox())
+
+	requireBox(t, Box{40, 0, 80, 20}, s.Groups[0].BBox(), 1e-9)
+	requireBox(t, Box{40, 0, 81, 20}, s.StrokeBBox(), 1e-2)
+
+	empty := &Rect{Width: "10"}
+	require.True(t, empty.BBox().Empty())
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -2,105 +2,73 @@ package svg
 
 import "math"
 
-// cubicBezier
-//
+// DefaultTolerance is the maximum distance between a curve and the line
+// segments approximating it when no tolerance is given.
+const DefaultTolerance = 0.1
+
+// maxFlattenDepth bounds the subdivision of a curve, so that degenerate
+// input cannot recurse forever.
+const maxFlattenDepth = 16
+
+// cubicBezier is a cubic bezier curve given by its four control points.
 type cubicBezier struct {
 	controlpoints [4][2]float64
-	vertices      [][2]float64
-	level         int
 }
 
-func (c *cubicBezier) interpolate(n int) [][2]float64 {
-	var vertices [][2]float64
-	for i := 0; i < n+1; i++ {
-		var t float64
-		t = float64(i) / float64(n)
-
-		var v [2]float64
-		v[0] = (1-t)*(1-t)*(1-t)*c.controlpoints[0][0] + 3*(1-t)*(1-t)*t*c.controlpoints[1][0] + 3*(1-t)*t*t*c.controlpoints[2][0] + t*t*t*c.controlpoints[3][0]
-		v[1] = (1-t)*(1-t)*(1-t)*c.controlpoints[0][1] + 3*(1-t)*(1-t)*t*c.controlpoints[1][1] + 3*(1-t)*t*t*c.controlpoints[2][1] + t*t*t*c.controlpoints[3][1]
-		vertices = append(vertices, v)
-	}
-	return vertices
+// split divides the curve at t = 0.5 into two curves.
+func (c *cubicBezier) split() (cubicBezier, cubicBezier) {
+	p := c.controlpoints
+	mid := func(a, b [2]float64) [2]float64 { return [2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2} }
+	m12, m23, m34 := mid(p[0], p[1]), mid(p[1], p[2]), mid(p[2], p[3])
+	m123, m234 := mid(m12, m23), mid(m23, m34)
+	m1234 := mid(m123, m234)
+	return cubicBezier{controlpoints: [4][2]float64{p[0], m12, m123, m1234}},
+		cubicBezier{controlpoints: [4][2]float64{m1234, m234, m34, p[3]}}
 }
 
-func (c *cubicBezier) recursiveInterpolate(limit int, level int) [][2]float64 {
-	c.level = level
-	//	fmt.Println(level)
-	var m12 [2]float64
-	var m23 [2]float64
-	var m34 [2]float64
-	var m123 [2]float64
-	var m234 [2]float64
-	var m1234 [2]float64
-	m12[0] = (c.controlpoints[0][0] + c.controlpoints[1][0]) / 2.0
-	m12[1] = (c.controlpoints[0][1] + c.controlpoints[1][1]) / 2.0
-
-	m23[0] = (c.controlpoints[1][0] + c.controlpoints[2][0]) / 2.0
-	m23[1] = (c.controlpoints[1][1] + c.controlpoints[2][1]) / 2.0
-
-	m34[0] = (c.controlpoints[2][0] + c.controlpoints[3][0]) / 2.0
-	m34[1] = (c.controlpoints[2][1] + c.controlpoints[3][1]) / 2.0
-
-	m123[0] = (m12[0] + m23[0]) / 2.0
-	m123[1] = (m12[1] + m23[1]) / 2.0
-
-	m234[0] = (m23[0] + m34[0]) / 2.0
-	m234[1] = (m23[1] + m34[1]) / 2.0
-
-	m1234[0] = (m123[0] + m234[0]) / 2.0
-	m1234[1] = (m123[1] + m234[1]) / 2.0
-
-	if limit == 0 {
-		var vertices [][2]float64
-		vertices = append(vertices, c.controlpoints[0])
-		vertices = append(vertices, m1234)
-		vertices = append(vertices, c.controlpoints[3])
-		return vertices
-	}
-
-	a12 := math.Atan2(c.controlpoints[0][1]-c.controlpoints[1][1], c.controlpoints[1][0]-c.controlpoints[0][0])
-	a34 := math.Atan2(c.controlpoints[2][1]-c.controlpoints[3][1], c.controlpoints[3][0]-c.controlpoints[2][0])
-	a1234 := math.Atan2(m123[1]-m1234[1], m1234[0]-m123[0])
+// deviation returns a bound on the distance between the curve and the
+// chord from its first to its last control point.
+func (c *cubicBezier) deviation() float64 {
+	p := c.controlpoints
+	return 0.75 * math.Max(
+		math.Hypot(p[0][0]-2*p[1][0]+p[2][0], p[0][1]-2*p[1][1]+p[2][1]),
+		math.Hypot(p[1][0]-2*p[2][0]+p[3][0], p[1][1]-2*p[2][1]+p[3][1]))
+}
 
-	//	fmt.Println("a12", a12*180.0/math.Pi)
-	//	fmt.Println("a34", a34*180.0/math.Pi)
-	//	fmt.Println("a1234", a1234*180.0/math.Pi)
+// hullLength returns the length of the control polygon, which is at
+// least the length of the curve.
+func (c *cubicBezier) hullLength() float64 {
+	p := c.controlpoints
+	return vlen(vsub(p[1], p[0])) + vlen(vsub(p[2], p[1])) + vlen(vsub(p[3], p[2]))
+}
 
-	d1 := math.Abs(a1234 - a12)
-	d2 := math.Abs(a34 - a1234)
-	if d1 >= math.Pi {
-		d1 = 2*math.Pi - d1
-	}
-	if d2 >= math.Pi {
-		d2 = 2*math.Pi - d2
+// flatten appends the end points of line segments approximating the
+// curve to vertices, leaving out the first control point. No point of
+// the curve is further than tolerance from the line segments, and no
+// line segment is longer than maxLength unless it is 0. The last point
+// appended is exactly the last control point.
+func (c *cubicBezier) flatten(vertices [][2]float64, tolerance, maxLength float64, depth int) [][2]float64 {
+	if depth < maxFlattenDepth && (c.deviation() > tolerance || (maxLength > 0 && c.hullLength() > maxLength)) {
+		c1, c2 := c.split()
+		vertices = c1.flatten(vertices, tolerance, maxLength, depth+1)
+		return c2.flatten(vertices, tolerance, maxLength, depth+1)
 	}
-
-	//	fmt.Println("angle d1", d1*180.0/math.Pi)
-	//fmt.Println("angle d2", d2*180.0/math.Pi)
-
-	if d1+d2 > 5.0*math.Pi/180.0 {
-		var vertices [][2]float64
-		var c1 cubicBezier
-		c1.controlpoints[0] = c.controlpoints[0]
-		c1.controlpoints[1] = m12
-		c1.controlpoints[2] = m123
-		c1.controlpoints[3] = m1234
-
-		vertices = append(vertices, c1.recursiveInterpolate(limit-1, c.level+1)...)
-
-		var c2 cubicBezier
-		c2.controlpoints[0] = m1234
-		c2.controlpoints[1] = m234
-		c2.controlpoints[2] = m34
-		c2.controlpoints[3] = c.controlpoints[3]
-		vertices = append(vertices, c2.recursiveInterpolate(limit-1, c.level+1)...)
+	p := c.controlpoints[3]
+	if n := len(vertices); n > 0 && vertices[n-1] == p {
 		return vertices
 	}
+	return append(vertices, p)
+}
 
-	var vertices [][2]float64
-	vertices = append(vertices, c.controlpoints[0])
-	vertices = append(vertices, m1234)
-	vertices = append(vertices, c.controlpoints[3])
-	return vertices
+// FlattenCubic approximates the cubic bezier curve from p0 to p3 with
+// control points c1 and c2 by line segments. No point of the curve is
+// further than tolerance from the segments, and when maxLength is not 0
+// no segment is longer than maxLength. The returned points start with
+// p0 and end with p3 exactly, and contain no consecutive duplicates.
+func FlattenCubic(p0, c1, c2, p3 [2]float64, tolerance, maxLength float64) [][2]float64 {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	c := cubicBezier{controlpoints: [4][2]float64{p0, c1, c2, p3}}
+	return c.flatten([][2]float64{p0}, tolerance, maxLength, 0)
 }
@@ -0,0 +1,112 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/stretchr/testify/require"
+)
+
+// distanceToPolyline returns the distance from p to the closest line
+// segment of pts.
+func distanceToPolyline(p [2]float64, pts [][2]float64) float64 {
+	best := math.Inf(1)
+	for i := 0; i+1 < len(pts); i++ {
+		a, b := pts[i], pts[i+1]
+		d := vsub(b, a)
+		t := 0.0
+		if l2 := vdot(d, d); l2 > 0 {
+			t = math.Max(0, math.Min(1, vdot(vsub(p, a), d)/l2))
+		}
+		best = math.Min(best, vlen(vsub(p, vadd(a, vscale(d, t)))))
+	}
+	return best
+}
+
+func TestFlattenCubic(t *testing.T) {
+	p0, c1, c2, p3 := [2]float64{0, 0}, [2]float64{10, 40}, [2]float64{90, -40}, [2]float64{100, 0}
+	c := CubicCurve{P0: p0, C1: c1, C2: c2, P1: p3}
+	var samples [][2]float64
+	for i := 0; i <= 1000; i++ {
+		samples = append(samples, c.PointAt(float64(i)/1000))
+	}
+
+	var previous int
+	for _, tolerance := range []float64{1, 0.1, 0.01} {
+		pts := FlattenCubic(p0, c1, c2, p3, tolerance, 0)
+		require.Equal(t, p0, pts[0])
+		require.Equal(t, p3, pts[len(pts)-1])
+		for i := 1; i < len(pts); i++ {
+			require.NotEqual(t, pts[i-1], pts[i])
+		}
+		for _, s := range samples {
+			require.LessOrEqual(t, distanceToPolyline(s, pts), tolerance)
+		}
+		require.Greater(t, len(pts), previous)
+		previous = len(pts)
+	}
+
+	pts := FlattenCubic(p0, c1, c2, p3, 1, 5)
+	for i := 1; i < len(pts); i++ {
+		require.LessOrEqual(t, vlen(vsub(pts[i], pts[i-1])), 5.0)
+	}
+
+	// A straight curve needs no points in between, a degenerate one
+	// collapses to its start.
+	require.Equal(t, [][2]float64{{0, 0}, {30, 0}}, FlattenCubic([2]float64{0, 0}, [2]float64{10, 0}, [2]float64{20, 0}, [2]float64{30, 0}, 0.1, 0))
+	require.Equal(t, [][2]float64{{5, 5}}, FlattenCubic([2]float64{5, 5}, [2]float64{5, 5}, [2]float64{5, 5}, [2]float64{5, 5}, 0.1, 0))
+}
+
+func TestParseSegmentsWith(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 10 10">
+		<path d="M0 0 C0 10 10 10 10 0" stroke-width="0.5"/>
+	</svg>`, "flatten", 1)
+	require.NoError(t, err)
+
+	collect := func(opts FlattenOptions) Segment {
+		segs, errs := s.ParseSegmentsWith(opts)
+		var all []Segment
+		for seg := range segs {
+			all = append(all, seg)
+		}
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.Len(t, all, 1)
+		return all[0]
+	}
+
+	plain := collect(FlattenOptions{Tolerance: 0.01})
+	require.Equal(t, [2]float64{10, 0}, plain.Points[len(plain.Points)-1])
+	require.Equal(t, 0.5, plain.Width)
+
+	// The tolerance applies after the transform, so scaling up gives
+	// more points and scales the stroke width along.
+	scale := mt.Identity()
+	scale.Scale(100, 100)
+	scaled := collect(FlattenOptions{Tolerance: 0.01, Transform: &scale})
+	require.Greater(t, len(scaled.Points), len(plain.Points))
+	require.Equal(t, [2]float64{1000, 0}, scaled.Points[len(scaled.Points)-1])
+	require.Equal(t, 50.0, scaled.Width)
+
+	limited := collect(FlattenOptions{Tolerance: 1, MaxSegmentLength: 0.5})
+	for i := 1; i < len(limited.Points); i++ {
+		require.LessOrEqual(t, vlen(vsub(limited.Points[i], limited.Points[i-1])), 0.5)
+	}
+}
+
+func TestParseSegmentsZeroRadiusCircle(t *testing.T) {
+	s, err := ParseSvg(`<svg><circle cx="5" cy="5" r="0"/><circle cx="5" cy="5" r="1"/></svg>`, "dot", 1)
+	require.NoError(t, err)
+	segs, errs := s.ParseSegments()
+	var all []Segment
+	for seg := range segs {
+		all = append(all, seg)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, all, 1)
+	require.True(t, all[0].Closed)
+}
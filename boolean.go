@@ -0,0 +1,480 @@
+package svg
+
+import (
+	"math"
+	"sort"
+)
+
+// FillRule decides which parts of a self-intersecting shape are inside.
+type FillRule int
+
+// Fill rules as defined by the SVG fill-rule property.
+const (
+	NonZero FillRule = iota
+	EvenOdd
+)
+
+// ParseFillRule returns the fill rule named by the value of a fill-rule
+// property. Anything but "evenodd" is the default nonzero rule.
+func ParseFillRule(s string) FillRule {
+	if s == "evenodd" {
+		return EvenOdd
+	}
+	return NonZero
+}
+
+func (r FillRule) inside(winding int) bool {
+	switch r {
+	case EvenOdd:
+		return winding%2 != 0
+	case positive:
+		return winding > 0
+	}
+	return winding != 0
+}
+
+// BooleanOp is a boolean operation on the areas of two shapes.
+type BooleanOp int
+
+// Boolean operations supported by Clip.
+const (
+	Union BooleanOp = iota
+	Intersection
+	Difference
+	Xor
+)
+
+func (op BooleanOp) inside(a, b bool) bool {
+	switch op {
+	case Intersection:
+		return a && b
+	case Difference:
+		return a && !b
+	case Xor:
+		return a != b
+	}
+	return a || b
+}
+
+// clipEdge is a directed edge of one of the two operands.
+type clipEdge struct {
+	a, b    [2]float64
+	operand int
+	splits  [][2]float64
+}
+
+// subEdge is an undirected piece of the arrangement of all edges with the
+// net direction of the operand edges lying on it.
+type subEdge struct {
+	a, b [2]float64
+	dir  [2]int
+}
+
+// Clip returns the area resulting from applying op to the area of
+// subject and the area of clip, each interpreted with its fill rule.
+// Every segment is treated as closed. The result consists of closed,
+// non-overlapping segments; outer boundaries have a positive signed area
+// and holes a negative one, so the result can be filled with either
+// fill rule.
+//
+// Union with an empty clip removes overlaps and self-intersections from
+// the subject.
+func Clip(op BooleanOp, subject []Segment, subjectRule FillRule, clip []Segment, clipRule FillRule) []Segment {
+	rules := [2]FillRule{subjectRule, clipRule}
+	w := newWelder(subject, clip)
+
+	var edges []*clipEdge
+	for operand, segments := range [2][]Segment{subject, clip} {
+		for _, s := range segments {
+			var pts [][2]float64
+			for _, p := range s.Points {
+				p = w.weld(p)
+				if len(pts) == 0 || p != pts[len(pts)-1] {
+					pts = append(pts, p)
+				}
+			}
+			for len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+				pts = pts[:len(pts)-1]
+			}
+			if len(pts) < 3 {
+				continue
+			}
+			for i, p := range pts {
+				edges = append(edges, &clipEdge{a: p, b: pts[(i+1)%len(pts)], operand: operand})
+			}
+		}
+	}
+
+	splitEdges(edges, w)
+	pieces := arrangement(edges)
+
+	left, right := windings(pieces)
+	var kept [][2][2]float64
+	for i := range pieces {
+		inLeft := op.inside(rules[0].inside(left[i][0]), rules[1].inside(left[i][1]))
+		inRight := op.inside(rules[0].inside(right[i][0]), rules[1].inside(right[i][1]))
+		switch {
+		case inLeft && !inRight:
+			kept = append(kept, [2][2]float64{pieces[i].a, pieces[i].b})
+		case inRight && !inLeft:
+			kept = append(kept, [2][2]float64{pieces[i].b, pieces[i].a})
+		}
+	}
+	return linkLoops(kept)
+}
+
+// welder merges points closer than a small distance relative to the
+// extent of the input, so that a point computed in several ways ends up
+// as one vertex.
+type welder struct {
+	size  float64
+	cells map[[2]int64][][2]float64
+}
+
+func newWelder(sets ...[]Segment) *welder {
+	var m float64
+	for _, segments := range sets {
+		for _, s := range segments {
+			for _, p := range s.Points {
+				m = math.Max(m, math.Max(math.Abs(p[0]), math.Abs(p[1])))
+			}
+		}
+	}
+	if m == 0 {
+		m = 1
+	}
+	return &welder{size: m * 1e-9, cells: make(map[[2]int64][][2]float64)}
+}
+
+// weld returns the first point seen within the welding distance of p,
+// or p itself.
+func (w *welder) weld(p [2]float64) [2]float64 {
+	cx, cy := int64(math.Floor(p[0]/w.size)), int64(math.Floor(p[1]/w.size))
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for _, q := range w.cells[[2]int64{cx + dx, cy + dy}] {
+				if math.Abs(q[0]-p[0]) <= w.size && math.Abs(q[1]-p[1]) <= w.size {
+					return q
+				}
+			}
+		}
+	}
+	key := [2]int64{cx, cy}
+	w.cells[key] = append(w.cells[key], p)
+	return p
+}
+
+// splitEdges finds all points where edges cross or touch and records
+// them as splits on the edges concerned.
+func splitEdges(edges []*clipEdge, w *welder) {
+	minX := func(e *clipEdge) float64 { return math.Min(e.a[0], e.b[0]) }
+	maxX := func(e *clipEdge) float64 { return math.Max(e.a[0], e.b[0]) }
+	sorted := append([]*clipEdge(nil), edges...)
+	sort.Slice(sorted, func(i, j int) bool { return minX(sorted[i]) < minX(sorted[j]) })
+
+	for i, e := range sorted {
+		for _, f := range sorted[i+1:] {
+			if minX(f) > maxX(e) {
+				break
+			}
+			if math.Max(e.a[1], e.b[1]) < math.Min(f.a[1], f.b[1]) ||
+				math.Max(f.a[1], f.b[1]) < math.Min(e.a[1], e.b[1]) {
+				continue
+			}
+			intersectEdges(e, f, w)
+		}
+	}
+}
+
+// onEdge reports whether p lies within distance tolerance of the inside
+// of the edge.
+func onEdge(e *clipEdge, p [2]float64, tolerance float64) bool {
+	r := vsub(e.b, e.a)
+	l2 := vdot(r, r)
+	t := vdot(vsub(p, e.a), r) / l2
+	if t <= 0 || t >= 1 {
+		return false
+	}
+	return math.Abs(vcross(vsub(p, e.a), r))/math.Sqrt(l2) <= tolerance
+}
+
+func intersectEdges(e, f *clipEdge, w *welder) {
+	// Endpoints lying on the other edge cover touching and collinear
+	// overlapping edges.
+	touching := false
+	for _, c := range []struct {
+		edge *clipEdge
+		p    [2]float64
+	}{{e, f.a}, {e, f.b}, {f, e.a}, {f, e.b}} {
+		if onEdge(c.edge, c.p, w.size) {
+			c.edge.splits = append(c.edge.splits, c.p)
+			touching = true
+		}
+	}
+	if touching {
+		return
+	}
+
+	r, s := vsub(e.b, e.a), vsub(f.b, f.a)
+	den := vcross(r, s)
+	if den == 0 {
+		return
+	}
+	q := vsub(f.a, e.a)
+	t, u := vcross(q, s)/den, vcross(q, r)/den
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return
+	}
+	p := w.weld(vadd(e.a, vscale(r, t)))
+	e.splits = append(e.splits, p)
+	f.splits = append(f.splits, p)
+}
+
+// arrangement cuts the edges at their splits and merges coinciding
+// pieces.
+func arrangement(edges []*clipEdge) []subEdge {
+	index := make(map[[2][2]float64]int)
+	var pieces []subEdge
+	for _, e := range edges {
+		r := vsub(e.b, e.a)
+		pts := append([][2]float64{e.a, e.b}, e.splits...)
+		sort.Slice(pts, func(i, j int) bool {
+			return vdot(vsub(pts[i], e.a), r) < vdot(vsub(pts[j], e.a), r)
+		})
+		for i := 0; i+1 < len(pts); i++ {
+			a, b := pts[i], pts[i+1]
+			if a == b {
+				continue
+			}
+			sign := 1
+			if b[0] < a[0] || (b[0] == a[0] && b[1] < a[1]) {
+				a, b, sign = b, a, -1
+			}
+			key := [2][2]float64{a, b}
+			k, ok := index[key]
+			if !ok {
+				k = len(pieces)
+				index[key] = k
+				pieces = append(pieces, subEdge{a: a, b: b})
+			}
+			pieces[k].dir[e.operand] += sign
+		}
+	}
+	return pieces
+}
+
+// windings returns the winding numbers of both operands just left and
+// just right of every piece, looking along its direction from a to b.
+//
+// The faces of the arrangement are traced, and winding numbers are
+// propagated from the outer face of each connected component across
+// the pieces. The outer face of a component is placed by casting a ray
+// from its leftmost vertex against the other components.
+func windings(pieces []subEdge) (left, right [][2]int) {
+	// Half-edge 2i runs along piece i from a to b, 2i+1 from b to a.
+	from := func(h int) [2]float64 {
+		if h%2 == 0 {
+			return pieces[h/2].a
+		}
+		return pieces[h/2].b
+	}
+	to := func(h int) [2]float64 { return from(h ^ 1) }
+	angle := func(h int) float64 {
+		d := vsub(to(h), from(h))
+		return math.Atan2(d[1], d[0])
+	}
+
+	outgoing := make(map[[2]float64][]int)
+	for h := 0; h < 2*len(pieces); h++ {
+		outgoing[from(h)] = append(outgoing[from(h)], h)
+	}
+	position := make([]int, 2*len(pieces))
+	for _, hs := range outgoing {
+		sort.Slice(hs, func(i, j int) bool { return angle(hs[i]) < angle(hs[j]) })
+		for i, h := range hs {
+			position[h] = i
+		}
+	}
+	// next returns the half-edge following h around the face on its left.
+	next := func(h int) int {
+		hs := outgoing[to(h)]
+		return hs[(position[h^1]+len(hs)-1)%len(hs)]
+	}
+
+	face := make([]int, 2*len(pieces))
+	for h := range face {
+		face[h] = -1
+	}
+	var boundary [][]int
+	for h := range face {
+		if face[h] >= 0 {
+			continue
+		}
+		var b []int
+		for e := h; face[e] < 0; e = next(e) {
+			face[e] = len(boundary)
+			b = append(b, e)
+		}
+		boundary = append(boundary, b)
+	}
+
+	winding := make([][2]int, len(boundary))
+	known := make([]bool, len(boundary))
+	component := make([]int, 2*len(pieces))
+	for h := range component {
+		component[h] = -1
+	}
+	components := 0
+	for h := range face {
+		if known[face[h]] {
+			continue
+		}
+		// h belongs to a component not reached yet. Find its leftmost
+		// vertex and the half-edges of the component on the way.
+		c := components
+		components++
+		component[h] = c
+		v := from(h)
+		for stack := []int{h}; len(stack) > 0; {
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if p := from(e); p[0] < v[0] || (p[0] == v[0] && p[1] < v[1]) {
+				v = p
+			}
+			for _, f := range []int{e ^ 1, next(e)} {
+				if component[f] < 0 {
+					component[f] = c
+					stack = append(stack, f)
+				}
+			}
+		}
+
+		// The outer face lies in the sector after the outgoing half-edge
+		// with the largest angle at the leftmost vertex.
+		hs := outgoing[v]
+		outer := face[hs[len(hs)-1]]
+		var w [2]int
+		for i, p := range pieces {
+			if component[2*i] == c {
+				continue
+			}
+			if (p.a[1] > v[1]) == (p.b[1] > v[1]) {
+				continue
+			}
+			if p.a[0]+(v[1]-p.a[1])*(p.b[0]-p.a[0])/(p.b[1]-p.a[1]) >= v[0] {
+				continue
+			}
+			// A counter-clockwise boundary passes left of a point inside
+			// it going down.
+			sign := 1
+			if p.b[1] > p.a[1] {
+				sign = -1
+			}
+			for k := range w {
+				w[k] += sign * p.dir[k]
+			}
+		}
+		winding[outer], known[outer] = w, true
+
+		queue := []int{outer}
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+			for _, e := range boundary[f] {
+				if known[face[e^1]] {
+					continue
+				}
+				// Crossing from the left of e to its right subtracts the
+				// operand edges running along e.
+				g := face[e^1]
+				dir := pieces[e/2].dir
+				if e%2 == 1 {
+					dir = [2]int{-dir[0], -dir[1]}
+				}
+				winding[g] = [2]int{winding[f][0] - dir[0], winding[f][1] - dir[1]}
+				known[g] = true
+				queue = append(queue, g)
+			}
+		}
+	}
+
+	left = make([][2]int, len(pieces))
+	right = make([][2]int, len(pieces))
+	for i := range pieces {
+		left[i], right[i] = winding[face[2*i]], winding[face[2*i+1]]
+	}
+	return left, right
+}
+
+// linkLoops joins directed edges into closed loops. At vertices shared
+// by several loops the sharpest left turn is taken so that loops touch
+// without crossing.
+func linkLoops(edges [][2][2]float64) []Segment {
+	out := make(map[[2]float64][]int)
+	for i, e := range edges {
+		out[e[0]] = append(out[e[0]], i)
+	}
+	used := make([]bool, len(edges))
+	var loops []Segment
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		pts := [][2]float64{edges[i][0]}
+		cur := i
+		for {
+			end := edges[cur][1]
+			if end == pts[0] {
+				break
+			}
+			pts = append(pts, end)
+			din := vsub(end, edges[cur][0])
+			next, best := -1, math.Inf(-1)
+			for _, j := range out[end] {
+				if used[j] {
+					continue
+				}
+				dout := vsub(edges[j][1], end)
+				a := math.Atan2(vcross(din, dout), vdot(din, dout))
+				if a > best {
+					next, best = j, a
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			cur = next
+		}
+		pts = removeCollinear(pts)
+		if len(pts) >= 3 {
+			loops = append(loops, Segment{Closed: true, Points: pts})
+		}
+	}
+	return loops
+}
+
+// removeCollinear drops the points of a closed polygon lying on a
+// straight line between their neighbours.
+func removeCollinear(pts [][2]float64) [][2]float64 {
+	for changed := true; changed && len(pts) >= 3; {
+		changed = false
+		var kept [][2]float64
+		n := len(pts)
+		for i, p := range pts {
+			prev, next := pts[(i+n-1)%n], pts[(i+1)%n]
+			if len(kept) > 0 {
+				prev = kept[len(kept)-1]
+			}
+			d1, d2 := vsub(p, prev), vsub(next, p)
+			if vcross(d1, d2) == 0 && vdot(d1, d2) > 0 {
+				changed = true
+				continue
+			}
+			kept = append(kept, p)
+		}
+		pts = kept
+	}
+	return pts
+}
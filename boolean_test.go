@@ -0,0 +1,161 @@
+package svg
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rect(x0, y0, x1, y1 float64) Segment {
+	return Segment{Closed: true, Points: [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}}
+}
+
+func reverse(s Segment) Segment {
+	s.Points = reversed(s.Points)
+	return s
+}
+
+func star(cx, cy float64) Segment {
+	var pts [][2]float64
+	for i := 0; i < 5; i++ {
+		a := math.Pi/2 + float64(i)*4*math.Pi/5
+		pts = append(pts, [2]float64{cx + math.Cos(a), cy + math.Sin(a)})
+	}
+	return Segment{Closed: true, Points: pts}
+}
+
+// starAreas returns the area of a unit pentagram filled with the nonzero
+// and the evenodd rule.
+func starAreas() (nonzero, evenodd float64) {
+	r := (3 - math.Sqrt(5)) / 2 // radius of the inner pentagon
+	nonzero = 5 * r * math.Sin(math.Pi/5)
+	return nonzero, nonzero - 5.0/2*r*r*math.Sin(2*math.Pi/5)
+}
+
+var clipTests = []struct {
+	name          string
+	subject, clip []Segment
+	rule          FillRule
+	areas         [4]float64 // union, intersection, difference, xor
+	loops         [4]int
+}{
+	{"overlapping", []Segment{rect(0, 0, 2, 2)}, []Segment{rect(1, 1, 3, 3)}, NonZero,
+		[4]float64{7, 1, 3, 6}, [4]int{1, 1, 1, 2}},
+	{"disjoint", []Segment{rect(0, 0, 2, 2)}, []Segment{rect(5, 5, 6, 6)}, NonZero,
+		[4]float64{5, 0, 4, 5}, [4]int{2, 0, 1, 2}},
+	{"identical", []Segment{rect(0, 0, 2, 2)}, []Segment{rect(0, 0, 2, 2)}, NonZero,
+		[4]float64{4, 4, 0, 0}, [4]int{1, 1, 0, 0}},
+	{"opposite orientation", []Segment{rect(0, 0, 2, 2)}, []Segment{reverse(rect(1, 1, 3, 3))}, NonZero,
+		[4]float64{7, 1, 3, 6}, [4]int{1, 1, 1, 2}},
+	{"shared edge", []Segment{rect(0, 0, 2, 2)}, []Segment{rect(2, 0, 4, 2)}, NonZero,
+		[4]float64{8, 0, 4, 8}, [4]int{1, 0, 1, 1}},
+	{"partly shared edge", []Segment{rect(0, 0, 2, 2)}, []Segment{rect(2, 1, 4, 4)}, NonZero,
+		[4]float64{10, 0, 4, 10}, [4]int{1, 0, 1, 1}},
+	{"touching corners", []Segment{rect(0, 0, 1, 1)}, []Segment{rect(1, 1, 2, 2)}, NonZero,
+		[4]float64{2, 0, 1, 2}, [4]int{2, 0, 1, 2}},
+	{"contained", []Segment{rect(0, 0, 4, 4)}, []Segment{rect(1, 1, 3, 3)}, NonZero,
+		[4]float64{16, 4, 12, 12}, [4]int{1, 1, 2, 2}},
+	{"collinear overlap", []Segment{rect(0, 0, 4, 1)}, []Segment{rect(1, 0, 3, 2)}, NonZero,
+		[4]float64{6, 2, 2, 4}, [4]int{1, 1, 2, 3}},
+	{"cross", []Segment{rect(0, 1, 3, 2)}, []Segment{rect(1, 0, 2, 3)}, NonZero,
+		[4]float64{5, 1, 2, 4}, [4]int{1, 1, 2, 4}},
+	{"nested nonzero", []Segment{rect(0, 0, 4, 4), rect(1, 1, 3, 3)}, []Segment{rect(2, 0, 4, 4)}, NonZero,
+		[4]float64{16, 8, 8, 8}, [4]int{1, 1, 1, 1}},
+	{"nested evenodd", []Segment{rect(0, 0, 4, 4), rect(1, 1, 3, 3)}, []Segment{rect(2, 0, 4, 4)}, EvenOdd,
+		[4]float64{14, 6, 6, 8}, [4]int{2, 1, 1, 2}},
+	{"hole", []Segment{rect(0, 0, 4, 4), reverse(rect(1, 1, 3, 3))}, []Segment{rect(2, 2, 5, 5)}, NonZero,
+		[4]float64{18, 3, 9, 15}, [4]int{2, 1, 1, 3}},
+}
+
+func TestClip(t *testing.T) {
+	ops := []BooleanOp{Union, Intersection, Difference, Xor}
+	for _, tc := range clipTests {
+		for i, op := range ops {
+			result := Clip(op, tc.subject, tc.rule, tc.clip, tc.rule)
+			require.InDelta(t, tc.areas[i], signedArea(result), 1e-9, "%s op %d", tc.name, op)
+			require.Len(t, result, tc.loops[i], "%s op %d", tc.name, op)
+			for _, s := range result {
+				require.True(t, s.Closed)
+			}
+		}
+	}
+}
+
+func TestClipSelfIntersecting(t *testing.T) {
+	nonzero, evenodd := starAreas()
+
+	result := Clip(Union, []Segment{star(0, 0)}, NonZero, nil, NonZero)
+	require.InDelta(t, nonzero, signedArea(result), 1e-9)
+	require.Len(t, result, 1)
+	require.Len(t, result[0].Points, 10)
+
+	result = Clip(Union, []Segment{star(0, 0)}, EvenOdd, nil, NonZero)
+	require.InDelta(t, evenodd, signedArea(result), 1e-9)
+	require.Len(t, result, 5)
+
+	// A bow tie: two triangles meeting in a point, one of them clockwise.
+	bowtie := Segment{Closed: true, Points: [][2]float64{{0, 0}, {2, 2}, {2, 0}, {0, 2}}}
+	result = Clip(Union, []Segment{bowtie}, NonZero, nil, NonZero)
+	require.InDelta(t, 2, signedArea(result), 1e-9)
+	require.Len(t, result, 2)
+}
+
+func TestClipResultFillRules(t *testing.T) {
+	// The result of a clip must describe the same area with both rules.
+	result := Clip(Difference, []Segment{rect(0, 0, 10, 10)}, NonZero,
+		[]Segment{rect(2, 2, 4, 4), rect(6, 6, 8, 8), star(5, 3)}, NonZero)
+	for _, rule := range []FillRule{NonZero, EvenOdd} {
+		again := Clip(Union, result, rule, nil, NonZero)
+		require.InDelta(t, signedArea(result), signedArea(again), 1e-9)
+	}
+	nonzero, _ := starAreas()
+	require.InDelta(t, 100-8-nonzero, signedArea(result), 1e-6)
+}
+
+func TestClipCurves(t *testing.T) {
+	// Two flattened circles overlapping by half their radius.
+	circle := func(cx float64) Segment {
+		var pts [][2]float64
+		for i := 0; i < 360; i++ {
+			a := float64(i) * math.Pi / 180
+			pts = append(pts, [2]float64{cx + math.Cos(a), math.Sin(a)})
+		}
+		return Segment{Closed: true, Points: pts}
+	}
+	a, b := []Segment{circle(0)}, []Segment{circle(1)}
+	areaA := signedArea(a)
+	union := signedArea(Clip(Union, a, NonZero, b, NonZero))
+	inter := signedArea(Clip(Intersection, a, NonZero, b, NonZero))
+	require.InDelta(t, 2*areaA, union+inter, 1e-9)
+	// The lens of two unit circles one radius apart.
+	require.InDelta(t, 2*math.Pi/3-math.Sqrt(3)/2, inter, 1e-3)
+}
+
+func TestClipIdentities(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	polygon := func(n int) Segment {
+		s := Segment{Closed: true}
+		for i := 0; i < n; i++ {
+			// Integer coordinates give many collinear and touching edges.
+			s.Points = append(s.Points, [2]float64{float64(rnd.Intn(8)), float64(rnd.Intn(8))})
+		}
+		return s
+	}
+	for i := 0; i < 300; i++ {
+		a, b := []Segment{polygon(3 + i%5)}, []Segment{polygon(3 + i%4), polygon(3)}
+		for _, rule := range []FillRule{NonZero, EvenOdd} {
+			areaA := signedArea(Clip(Union, a, rule, nil, rule))
+			areaB := signedArea(Clip(Union, b, rule, nil, rule))
+			union := signedArea(Clip(Union, a, rule, b, rule))
+			inter := signedArea(Clip(Intersection, a, rule, b, rule))
+			diff := signedArea(Clip(Difference, a, rule, b, rule))
+			xor := signedArea(Clip(Xor, a, rule, b, rule))
+			require.InDelta(t, areaA+areaB, union+inter, 1e-6, "case %d", i)
+			require.InDelta(t, areaA, diff+inter, 1e-6, "case %d", i)
+			require.InDelta(t, union-inter, xor, 1e-6, "case %d", i)
+			require.True(t, inter >= -1e-9 && diff >= -1e-9, "case %d", i)
+		}
+	}
+}
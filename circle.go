@@ -1,23 +1,32 @@
 package svg
 
-import mt "github.com/rustyoz/Mtransform"
+import (
+	"math"
+
+	mt "github.com/rustyoz/Mtransform"
+)
 
 // Circle is an SVG circle element
 type Circle struct {
-	ID        string  `xml:"id,attr"`
-	Transform string  `xml:"transform,attr"`
-	Style     string  `xml:"style,attr"`
-	Cx        float64 `xml:"cx,attr"`
-	Cy        float64 `xml:"cy,attr"`
-	Radius    float64 `xml:"r,attr"`
-	Fill      string  `xml:"fill,attr"`
+	ID         string     `xml:"id,attr"`
+	Transform  string     `xml:"transform,attr"`
+	Style      string     `xml:"style,attr"`
+	Cx         float64    `xml:"cx,attr"`
+	Cy         float64    `xml:"cy,attr"`
+	Radius     float64    `xml:"r,attr"`
+	Fill       string     `xml:"fill,attr"`
+	Display    string     `xml:"display,attr"`
+	Visibility string     `xml:"visibility,attr"`
+	Attrs      Attributes `xml:",any,attr"`
 
 	transform mt.Transform
 	group     *Group
+	source    *xmlSource
 }
 
 // ParseDrawingInstructions implements the DrawingInstructionParser
-// interface
+// interface. Circles mapped by a transform that keeps them circles are
+// drawn with a circle instruction, others with cubic curves.
 func (c *Circle) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
 	draw := make(chan *DrawingInstruction)
 	errs := make(chan error)
@@ -26,14 +35,61 @@ func (c *Circle) ParseDrawingInstructions() (chan *DrawingInstruction, chan erro
 		defer close(draw)
 		defer close(errs)
 
-		draw <- &DrawingInstruction{
-			Kind:   CircleInstruction,
-			M:      &Tuple{c.Cx, c.Cy},
-			Radius: &c.Radius,
+		m := elementTransform(c.group, c.Transform)
+		if scale, ok := similarity(m); ok {
+			center := Tuple(applyPoint(m, [2]float64{c.Cx, c.Cy}))
+			radius := c.Radius * scale
+			draw <- &DrawingInstruction{
+				Kind:   CircleInstruction,
+				M:      &center,
+				Radius: &radius,
+			}
+		} else {
+			g, _ := c.Geometry()
+			g.sendInstructions(draw)
 		}
 
-		draw <- &DrawingInstruction{Kind: PaintInstruction, Fill: &c.Fill}
+		draw <- c.Paint()
 	}()
 
 	return draw, errs
 }
+
+// Geometry returns the circle as a closed arc, starting at its right,
+// with its transform and the transform of its group applied. Circles
+// without a radius have no curves.
+func (c *Circle) Geometry() (*PathGeometry, error) {
+	g := &PathGeometry{}
+	if c.Radius > 0 {
+		g.Subpaths = []Subpath{{
+			Curves: []Curve{ArcCurve{
+				Center: [2]float64{c.Cx, c.Cy},
+				U:      [2]float64{c.Radius, 0},
+				V:      [2]float64{0, c.Radius},
+				Sweep:  2 * math.Pi,
+			}},
+			Closed: true,
+		}}
+	}
+	return g.Transform(elementTransform(c.group, c.Transform)), nil
+}
+
+// Paint returns the paint instruction ending the drawing instructions
+// of the circle. The fill attribute is given precedence over other
+// attributes; see shapePaint.
+func (c *Circle) Paint() *DrawingInstruction {
+	fill := c.Fill
+	return shapePaint(c.Style, c.Attrs, &fill, c.group)
+}
+
+// similarity returns the factor by which a transform scales lengths, if
+// it maps circles to circles.
+func similarity(m mt.Transform) (float64, bool) {
+	a, b, c, d := m[0][0], m[0][1], m[1][0], m[1][1]
+	// The columns must be orthogonal and of the same length.
+	eps := 1e-12 * (a*a + b*b + c*c + d*d)
+	if math.Abs(a*a+c*c-b*b-d*d) > eps || math.Abs(a*b+c*d) > eps {
+		return 0, false
+	}
+	return math.Sqrt(math.Abs(a*d - b*c)), true
+}
@@ -29,16 +29,18 @@ type CurvePoints struct {
 // The struct contains all necessary fields but only the ones needed (as
 // indicated byt the InstructionType) will be non-nil.
 type DrawingInstruction struct {
-	Kind           InstructionType
-	M              *Tuple
-	CurvePoints    *CurvePoints
-	Radius         *float64
-	StrokeWidth    *float64
-	Opacity		   *float64
-	Fill           *string
-	Stroke         *string
-	StrokeLineCap  *string
-	StrokeLineJoin *string
+	Kind             InstructionType
+	M                *Tuple
+	CurvePoints      *CurvePoints
+	Radius           *float64
+	StrokeWidth      *float64
+	Opacity          *float64
+	Fill             *string
+	Stroke           *string
+	StrokeLineCap    *string
+	StrokeLineJoin   *string
+	StrokeMiterLimit *float64
+	FillRule         *string
 }
 
 func (di *DrawingInstruction) String() string {
@@ -72,6 +74,12 @@ func (di *DrawingInstruction) String() string {
 		if di.StrokeLineJoin != nil {
 			pt = fmt.Sprintf("%vstroke-linejoin=\"%v\" ", pt, *di.StrokeLineJoin)
 		}
+		if di.StrokeMiterLimit != nil {
+			pt = fmt.Sprintf("%vstroke-miterlimit=\"%v\" ", pt, *di.StrokeMiterLimit)
+		}
+		if di.FillRule != nil && *di.FillRule != "" {
+			pt = fmt.Sprintf("%vfill-rule=\"%v\" ", pt, *di.FillRule)
+		}
 		return pt
 	}
 	return ""
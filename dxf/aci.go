@@ -0,0 +1,60 @@
+package dxf
+
+import (
+	"image/color"
+	"math"
+)
+
+// aciPalette holds the colours of the AutoCAD Color Index. Colours 10 to
+// 249 run through 24 hues, each in five shades at full and at half
+// saturation; 250 to 255 are greys.
+var aciPalette = func() [256]color.NRGBA {
+	var p [256]color.NRGBA
+	for i, c := range [][3]uint8{
+		{255, 0, 0}, {255, 255, 0}, {0, 255, 0}, {0, 255, 255},
+		{0, 0, 255}, {255, 0, 255}, {255, 255, 255}, {128, 128, 128}, {192, 192, 192},
+	} {
+		p[i+1] = color.NRGBA{c[0], c[1], c[2], 255}
+	}
+	shades := []float64{255, 165, 127, 76, 38}
+	for i := 10; i < 250; i++ {
+		hue := float64(i/10-1) * 15
+		v := shades[i%10/2]
+		var rgb [3]float64
+		for k, offset := range []float64{0, 120, 240} {
+			// Full saturation: 1 within 60 degrees of the channel,
+			// falling off to 0 at 120 degrees.
+			d := math.Abs(math.Mod(hue-offset+540, 360) - 180)
+			rgb[k] = math.Max(0, math.Min(1, 2-d/60))
+			if i%2 == 1 {
+				rgb[k] = 0.5 + rgb[k]/2
+			}
+		}
+		p[i] = color.NRGBA{uint8(rgb[0] * v), uint8(rgb[1] * v), uint8(rgb[2] * v), 255}
+	}
+	for i, g := range []uint8{51, 91, 132, 173, 214, 255} {
+		p[250+i] = color.NRGBA{g, g, g, 255}
+	}
+	return p
+}()
+
+// aci returns the AutoCAD Color Index closest to c. Black and white map
+// to colour 7, which CAD programs show in the colour contrasting with
+// the background.
+func aci(c color.NRGBA) int {
+	if c.R == c.G && c.G == c.B && (c.R == 0 || c.R == 255) {
+		return 7
+	}
+	best, bestDist := 1, math.Inf(1)
+	for i := 1; i < 256; i++ {
+		if i == 7 {
+			continue
+		}
+		p := aciPalette[i]
+		dr, dg, db := float64(c.R)-float64(p.R), float64(c.G)-float64(p.G), float64(c.B)-float64(p.B)
+		if d := dr*dr + dg*dg + db*db; d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
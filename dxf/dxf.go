@@ -0,0 +1,370 @@
+// Package dxf writes SVG documents as DXF drawings for CAD programs.
+// Lines, polylines, circles, circular arcs and bezier curves are written
+// as LINE, LWPOLYLINE, CIRCLE, ARC and SPLINE entities.
+package dxf
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+)
+
+// DefaultTolerance is the default maximum distance in drawing units
+// between an elliptical arc and the polyline it is written as.
+const DefaultTolerance = 0.01
+
+// Values of the $INSUNITS header variable.
+const (
+	unitsInches      = 1
+	unitsMillimeters = 4
+)
+
+// Options control the written drawing.
+type Options struct {
+	// Tolerance is the maximum distance in drawing units between an
+	// elliptical arc and the polyline it is written as. Zero means
+	// DefaultTolerance.
+	Tolerance float64
+}
+
+// entity is a DXF entity with its layer and colour.
+type entity struct {
+	kind   string
+	layer  string
+	color  *color.NRGBA
+	groups [][2]string
+}
+
+// drawing collects the entities of a document.
+type drawing struct {
+	tolerance float64
+	entities  []entity
+	layers    []string
+	box       svg.Box
+}
+
+// Write writes the elements of the document that would be drawn by
+// ParseDrawingInstructions as a DXF drawing. Its units are inches if the
+// width of the document is given in inches and millimetres otherwise,
+// and its y axis points up. Elements are put on a layer named after
+// their closest Inkscape layer or group with an ID, and coloured with
+// their stroke colour, or their fill colour if they have no stroke.
+//
+// Only the header, the layer table and the entities are written, which
+// CAD programs generally accept. Errors in elements are returned once
+// the rest of the document is written.
+func Write(w io.Writer, s *svg.Svg, opts Options) error {
+	d := &drawing{tolerance: opts.Tolerance, box: svg.Box{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}}
+	if d.tolerance <= 0 {
+		d.tolerance = DefaultTolerance
+	}
+	units, t := documentTransform(s)
+
+	var first error
+	s.VisitElements(func(e svg.DrawingInstructionParser, g *svg.Group) {
+		var geometry *svg.PathGeometry
+		var paint *svg.DrawingInstruction
+		if p, ok := e.(*svg.Path); ok {
+			pg, err := p.Geometry()
+			if err != nil && first == nil {
+				first = err
+			}
+			geometry, paint = pg, p.Paint()
+		} else {
+			instrs, err := svg.CollectInstructions(e)
+			if err != nil && first == nil {
+				first = err
+			}
+			for _, di := range instrs {
+				if di.Kind == svg.PaintInstruction {
+					paint = di
+				}
+			}
+			geometry = svg.GeometryFromInstructions(instrs)
+		}
+		d.add(geometry.Transform(t), layerName(g), paintColor(paint))
+	})
+
+	bw := bufio.NewWriter(w)
+	d.write(bw, units)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return first
+}
+
+// documentTransform returns the units of the drawing and the transform
+// from the user units of the document to them, turning the y axis up.
+func documentTransform(s *svg.Svg) (int, mt.Transform) {
+	units, scale := unitsMillimeters, 1.0
+	if strings.HasSuffix(strings.ToLower(strings.TrimSpace(s.Width)), "in") {
+		units, scale = unitsInches, 1/25.4
+	}
+	_, height, _ := s.PhysicalSize()
+	t := mt.Identity()
+	t.Scale(scale, -scale)
+	t.Translate(0, -height)
+	return units, mt.MultiplyTransforms(t, s.MillimeterTransform())
+}
+
+// layerName returns the name of the closest Inkscape layer or group
+// with an ID, or the default layer 0. Characters DXF does not allow in
+// layer names are replaced.
+func layerName(g *svg.Group) string {
+	name := "0"
+	for ; g != nil; g = g.Parent {
+		if g.Layer != nil && g.Layer.Label != "" {
+			name = g.Layer.Label
+			break
+		}
+		if g.Layer != nil && g.Layer.ID != "" {
+			name = g.Layer.ID
+			break
+		}
+		if g.ID != "" {
+			name = g.ID
+			break
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>/\":;?*|=,`+"`", r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// paintColor returns the stroke colour of a painted element, or its
+// fill colour if it is not stroked. Paths are filled black unless told
+// otherwise.
+func paintColor(paint *svg.DrawingInstruction) *color.NRGBA {
+	if paint == nil {
+		return nil
+	}
+	if paint.Stroke != nil {
+		if c, ok := svg.ParseColor(*paint.Stroke); ok {
+			return &c
+		}
+	}
+	fill := "black"
+	if paint.Fill != nil && strings.TrimSpace(*paint.Fill) != "" {
+		fill = *paint.Fill
+	}
+	if c, ok := svg.ParseColor(fill); ok {
+		return &c
+	}
+	return nil
+}
+
+// add adds the entities drawing the subpaths of a path.
+func (d *drawing) add(g *svg.PathGeometry, layer string, c *color.NRGBA) {
+	if !contains(d.layers, layer) {
+		d.layers = append(d.layers, layer)
+	}
+	d.box = d.box.Union(g.Bounds())
+
+	emit := func(kind string, groups [][2]string) {
+		d.entities = append(d.entities, entity{kind: kind, layer: layer, color: c, groups: groups})
+	}
+	for _, sp := range g.Subpaths {
+		var run [][2]float64
+		flush := func(closed bool) {
+			switch {
+			case len(run) == 2 && !closed:
+				emit("LINE", [][2]string{{"100", "AcDbLine"},
+					pair(10, run[0][0]), pair(20, run[0][1]), pair(30, 0),
+					pair(11, run[1][0]), pair(21, run[1][1]), pair(31, 0)})
+			case len(run) > 1:
+				if closed {
+					run = run[:len(run)-1]
+				}
+				groups := [][2]string{{"100", "AcDbPolyline"}, ipair(90, len(run)), ipair(70, 0)}
+				if closed {
+					groups[2] = ipair(70, 1)
+				}
+				for _, p := range run {
+					groups = append(groups, pair(10, p[0]), pair(20, p[1]))
+				}
+				emit("LWPOLYLINE", groups)
+			}
+			run = nil
+		}
+		lines := func(pts [][2]float64) {
+			if len(run) == 0 {
+				run = append(run, pts[0])
+			}
+			run = append(run, pts[1:]...)
+		}
+
+		polyline := true
+		for _, curve := range sp.Curves {
+			switch c := curve.(type) {
+			case svg.LineCurve:
+				lines([][2]float64{c.P0, c.P1})
+				continue
+			case svg.ArcCurve:
+				if r, ok := radius(c); ok {
+					flush(false)
+					emit(arc(c, r))
+				} else {
+					lines(svg.FlattenCurve(c, d.tolerance))
+					continue
+				}
+			case svg.QuadraticCurve:
+				flush(false)
+				emit(spline(svg.CubicCurve{P0: c.P0, C1: lerp(c.P0, c.C, 2.0/3), C2: lerp(c.P1, c.C, 2.0/3), P1: c.P1}))
+			case svg.CubicCurve:
+				flush(false)
+				emit(spline(c))
+			default:
+				lines(svg.FlattenCurve(c, d.tolerance))
+				continue
+			}
+			polyline = false
+		}
+		flush(polyline && sp.Closed)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func lerp(a, b [2]float64, t float64) [2]float64 {
+	return [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+}
+
+// radius returns the radius of an arc on a circle.
+func radius(c svg.ArcCurve) (float64, bool) {
+	ru, rv := math.Hypot(c.U[0], c.U[1]), math.Hypot(c.V[0], c.V[1])
+	r := math.Max(ru, rv)
+	dot := c.U[0]*c.V[0] + c.U[1]*c.V[1]
+	return r, r > 0 && math.Abs(ru-rv) <= r*1e-9 && math.Abs(dot) <= r*r*1e-9
+}
+
+// arc returns a circle or a counter-clockwise arc.
+func arc(c svg.ArcCurve, r float64) (string, [][2]string) {
+	groups := [][2]string{{"100", "AcDbCircle"}, pair(10, c.Center[0]), pair(20, c.Center[1]), pair(30, 0), pair(40, r)}
+	if math.Abs(c.Sweep) >= 2*math.Pi-1e-9 {
+		return "CIRCLE", groups
+	}
+	angle := func(t float64) float64 {
+		p := c.PointAt(t)
+		a := math.Atan2(p[1]-c.Center[1], p[0]-c.Center[0]) * 180 / math.Pi
+		if a < 0 {
+			a += 360
+		}
+		return a
+	}
+	start, end := angle(0), angle(1)
+	if (c.U[0]*c.V[1]-c.U[1]*c.V[0])*c.Sweep < 0 {
+		start, end = end, start
+	}
+	return "ARC", append(groups, [2]string{"100", "AcDbArc"}, pair(50, start), pair(51, end))
+}
+
+// spline returns a cubic bezier curve as a cubic spline.
+func spline(c svg.CubicCurve) (string, [][2]string) {
+	groups := [][2]string{
+		{"100", "AcDbSpline"},
+		pair(210, 0), pair(220, 0), pair(230, 1),
+		ipair(70, 8), ipair(71, 3), ipair(72, 8), ipair(73, 4), ipair(74, 0),
+	}
+	for _, k := range []float64{0, 0, 0, 0, 1, 1, 1, 1} {
+		groups = append(groups, pair(40, k))
+	}
+	for _, p := range [][2]float64{c.P0, c.C1, c.C2, c.P1} {
+		groups = append(groups, pair(10, p[0]), pair(20, p[1]), pair(30, 0))
+	}
+	return "SPLINE", groups
+}
+
+func number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func pair(code int, v float64) [2]string {
+	return [2]string{strconv.Itoa(code), number(v)}
+}
+
+func ipair(code, v int) [2]string {
+	return [2]string{strconv.Itoa(code), strconv.Itoa(v)}
+}
+
+// write writes the drawing, giving every object a handle.
+func (d *drawing) write(w *bufio.Writer, units int) {
+	handle := 0x100
+	next := func() string {
+		handle++
+		return fmt.Sprintf("%X", handle)
+	}
+	put := func(code, value string) {
+		fmt.Fprintf(w, "%3s\n%s\n", code, value)
+	}
+	puts := func(groups ...[2]string) {
+		for _, g := range groups {
+			put(g[0], g[1])
+		}
+	}
+
+	measurement := 1 // metric
+	if units == unitsInches {
+		measurement = 0
+	}
+	box := d.box
+	if box.Empty() {
+		box = svg.Box{}
+	}
+	puts([2]string{"0", "SECTION"}, [2]string{"2", "HEADER"},
+		[2]string{"9", "$ACADVER"}, [2]string{"1", "AC1018"},
+		[2]string{"9", "$INSUNITS"}, ipair(70, units),
+		[2]string{"9", "$MEASUREMENT"}, ipair(70, measurement),
+		[2]string{"9", "$EXTMIN"}, pair(10, box.MinX), pair(20, box.MinY), pair(30, 0),
+		[2]string{"9", "$EXTMAX"}, pair(10, box.MaxX), pair(20, box.MaxY), pair(30, 0),
+		[2]string{"0", "ENDSEC"})
+
+	// The default layer 0 always exists.
+	layers := d.layers
+	if !contains(layers, "0") {
+		layers = append([]string{"0"}, layers...)
+	}
+	table := next()
+	puts([2]string{"0", "SECTION"}, [2]string{"2", "TABLES"},
+		[2]string{"0", "TABLE"}, [2]string{"2", "LAYER"}, [2]string{"5", table},
+		[2]string{"100", "AcDbSymbolTable"}, ipair(70, len(layers)))
+	for _, l := range layers {
+		puts([2]string{"0", "LAYER"}, [2]string{"5", next()}, [2]string{"330", table},
+			[2]string{"100", "AcDbSymbolTableRecord"}, [2]string{"100", "AcDbLayerTableRecord"},
+			[2]string{"2", l}, ipair(70, 0), ipair(62, 7), [2]string{"6", "CONTINUOUS"})
+	}
+	puts([2]string{"0", "ENDTAB"}, [2]string{"0", "ENDSEC"})
+
+	puts([2]string{"0", "SECTION"}, [2]string{"2", "ENTITIES"})
+	for _, e := range d.entities {
+		puts([2]string{"0", e.kind}, [2]string{"5", next()},
+			[2]string{"100", "AcDbEntity"}, [2]string{"8", e.layer})
+		if e.color != nil {
+			c := *e.color
+			puts(ipair(62, aci(c)), ipair(420, int(c.R)<<16|int(c.G)<<8|int(c.B)))
+		}
+		puts(e.groups...)
+	}
+	puts([2]string{"0", "ENDSEC"}, [2]string{"0", "EOF"})
+}
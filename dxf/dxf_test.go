@@ -0,0 +1,146 @@
+package dxf
+
+import (
+	"bufio"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+const drawingSvg = `<svg xmlns="http://www.w3.org/2000/svg"
+	xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+	width="100mm" height="50mm" viewBox="0 0 200 100">
+	<g inkscape:groupmode="layer" inkscape:label="Cut">
+		<path d="M0 0 H20 V20 Z" stroke="#ff0000"/>
+		<path d="M50 50 A10 10 0 0 1 70 50"/>
+	</g>
+	<g id="parts">
+		<circle cx="100" cy="50" r="10" fill="blue"/>
+		<path d="M0 100 C10 90 20 90 30 100 L40 100" fill="none" stroke="#336699"/>
+		<path d="M0 0 Q10 10 20 0" fill="none"/>
+	</g>
+</svg>`
+
+// readEntities returns the entities of a DXF file as maps from group
+// codes to their values in order, and the names of the layers.
+func readEntities(t *testing.T, dxf string) ([]map[string][]string, []string) {
+	sc := bufio.NewScanner(strings.NewReader(dxf))
+	var pairs [][2]string
+	for sc.Scan() {
+		code := strings.TrimSpace(sc.Text())
+		require.True(t, sc.Scan())
+		pairs = append(pairs, [2]string{code, sc.Text()})
+	}
+	require.Equal(t, [2]string{"0", "EOF"}, pairs[len(pairs)-1])
+
+	var entities []map[string][]string
+	var layers []string
+	var section string
+	for i, p := range pairs {
+		if p[0] == "2" && i > 0 && pairs[i-1] == [2]string{"0", "SECTION"} {
+			section = p[1]
+		}
+		if p[0] == "0" && p[1] == "LAYER" {
+			for _, q := range pairs[i+1:] {
+				if q[0] == "2" {
+					layers = append(layers, q[1])
+					break
+				}
+			}
+		}
+		if section != "ENTITIES" || p[0] != "0" || p[1] == "SECTION" || p[1] == "ENDSEC" || p[1] == "EOF" {
+			continue
+		}
+		e := map[string][]string{"0": {p[1]}}
+		for _, q := range pairs[i+1:] {
+			if q[0] == "0" {
+				break
+			}
+			e[q[0]] = append(e[q[0]], q[1])
+		}
+		entities = append(entities, e)
+	}
+	return entities, layers
+}
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(drawingSvg, "drawing", 1)
+	require.NoError(t, err)
+	var b strings.Builder
+	require.NoError(t, Write(&b, s, Options{}))
+	out := b.String()
+	require.Contains(t, out, "$INSUNITS\n 70\n4\n")
+
+	entities, layers := readEntities(t, out)
+	require.Equal(t, []string{"0", "Cut", "parts"}, layers)
+	var kinds []string
+	for _, e := range entities {
+		kinds = append(kinds, e["0"][0])
+	}
+	require.Equal(t, []string{"LWPOLYLINE", "ARC", "CIRCLE", "SPLINE", "LINE", "SPLINE"}, kinds)
+
+	// Millimetres with the y axis up.
+	poly := entities[0]
+	require.Equal(t, []string{"Cut"}, poly["8"])
+	require.Equal(t, []string{"3"}, poly["90"])
+	require.Equal(t, []string{"1"}, poly["70"])
+	require.Equal(t, []string{"0", "10", "10"}, poly["10"])
+	require.Equal(t, []string{"50", "50", "40"}, poly["20"])
+	require.Equal(t, []string{"1"}, poly["62"])
+	require.Equal(t, []string{"16711680"}, poly["420"])
+
+	// The arc bulges up and is counter-clockwise.
+	arc := entities[1]
+	require.Equal(t, []string{"30"}, arc["10"])
+	require.Equal(t, []string{"25"}, arc["20"])
+	require.Equal(t, []string{"5"}, arc["40"])
+	require.Equal(t, []string{"0"}, arc["50"])
+	require.Equal(t, []string{"180"}, arc["51"])
+	require.Equal(t, []string{"7"}, arc["62"])
+
+	circle := entities[2]
+	require.Equal(t, []string{"parts"}, circle["8"])
+	require.Equal(t, []string{"5"}, circle["62"])
+	require.Equal(t, []string{"50"}, circle["10"])
+
+	spline := entities[3]
+	require.Equal(t, []string{"3"}, spline["71"])
+	require.Equal(t, []string{"0", "0", "0", "0", "1", "1", "1", "1"}, spline["40"])
+	require.Equal(t, []string{"0", "5", "10", "15"}, spline["10"])
+	require.Equal(t, []string{"0", "5", "5", "0"}, spline["20"])
+
+	line := entities[4]
+	require.Equal(t, []string{"15"}, line["10"])
+	require.Equal(t, []string{"20"}, line["11"])
+
+	// Quadratic curves are raised to cubic ones.
+	quad := entities[5]
+	require.Equal(t, []string{"0", "3.333333", "6.666667", "10"}, quad["10"])
+}
+
+func TestWriteInches(t *testing.T) {
+	s, err := svg.ParseSvg(`<svg width="2in" height="1in" viewBox="0 0 2 1">
+		<path d="M0 0 L2 1"/>
+	</svg>`, "inches", 1)
+	require.NoError(t, err)
+	var b strings.Builder
+	require.NoError(t, Write(&b, s, Options{}))
+	require.Contains(t, b.String(), "$INSUNITS\n 70\n1\n")
+	entities, _ := readEntities(t, b.String())
+	require.Len(t, entities, 1)
+	require.Equal(t, []string{"0", "1"}, []string{entities[0]["10"][0], entities[0]["20"][0]})
+	require.Equal(t, []string{"2", "0"}, []string{entities[0]["11"][0], entities[0]["21"][0]})
+}
+
+func TestACI(t *testing.T) {
+	require.Equal(t, 1, aci(color.NRGBA{255, 0, 0, 255}))
+	require.Equal(t, 5, aci(color.NRGBA{0, 0, 255, 255}))
+	require.Equal(t, 7, aci(color.NRGBA{0, 0, 0, 255}))
+	require.Equal(t, 7, aci(color.NRGBA{255, 255, 255, 255}))
+	require.Equal(t, 8, aci(color.NRGBA{128, 128, 128, 255}))
+	require.Equal(t, 30, aci(color.NRGBA{250, 130, 5, 255}))
+	require.Equal(t, color.NRGBA{165, 82, 82, 255}, aciPalette[13])
+}
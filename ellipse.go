@@ -4,13 +4,14 @@ import mt "github.com/rustyoz/Mtransform"
 
 // Ellipse is an SVG ellipse XML element
 type Ellipse struct {
-	ID        string `xml:"id,attr"`
-	Transform string `xml:"transform,attr"`
-	Style     string `xml:"style,attr"`
-	Cx        string `xml:"cx,attr"`
-	Cy        string `xml:"cy,attr"`
-	Rx        string `xml:"rx,attr"`
-	Ry        string `xml:"ry,attr"`
+	ID        string     `xml:"id,attr"`
+	Transform string     `xml:"transform,attr"`
+	Style     string     `xml:"style,attr"`
+	Cx        string     `xml:"cx,attr"`
+	Cy        string     `xml:"cy,attr"`
+	Rx        string     `xml:"rx,attr"`
+	Ry        string     `xml:"ry,attr"`
+	Attrs     Attributes `xml:",any,attr"`
 
 	transform mt.Transform
 	group     *Group
@@ -0,0 +1,223 @@
+// Package eps writes SVG documents as Encapsulated PostScript for print.
+// It consumes the drawing instructions of a parsed document and is
+// written in pure Go.
+package eps
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+	"github.com/rustyoz/svg/internal/pathops"
+)
+
+// PointsPerMillimeter is the number of PostScript points in a
+// millimetre.
+const PointsPerMillimeter = 72 / 25.4
+
+// prolog abbreviates the path operators.
+const prolog = `/m /moveto load def
+/l /lineto load def
+/c /curveto load def
+/h /closepath load def
+`
+
+// Page draws drawing instructions as PostScript. Instructions are
+// collected into a path until a paint instruction fills and strokes it.
+// PostScript has no transparency, so translucent paint is drawn opaque
+// and invisible paint is left out.
+type Page struct {
+	// Width and Height are the size of the page in points.
+	Width, Height float64
+	// Transform maps the coordinates of the drawing instructions to
+	// points with the origin in the bottom left corner of the page.
+	Transform mt.Transform
+	// BoundingBox is the area of the page in points that is drawn on.
+	// The zero box or an empty one means the whole page.
+	BoundingBox svg.Box
+
+	path    pathops.Builder
+	content strings.Builder
+}
+
+// NewPage returns a page of the given size in points. Its transform
+// puts the origin of the drawing instructions in the top left corner
+// with the y axis pointing down, as in SVG.
+func NewPage(width, height float64) *Page {
+	t := mt.Identity()
+	t.Translate(0, height)
+	t.Scale(1, -1)
+	return &Page{Width: width, Height: height, Transform: t}
+}
+
+// Write writes the document as an EPS file on a page the physical size
+// of the document, bounded by the box of the document with its
+// strokes. Documents without a size are taken to be 300 by 150 pixels,
+// the default size of SVG images.
+func Write(w io.Writer, s *svg.Svg) error {
+	width, height, ok := s.PhysicalSize()
+	m := s.MillimeterTransform()
+	if !ok {
+		px := 25.4 / 96
+		width, height = 300*px, 150*px
+	}
+	p := NewPage(width*PointsPerMillimeter, height*PointsPerMillimeter)
+	p.Transform.Scale(PointsPerMillimeter, PointsPerMillimeter)
+	p.Transform = mt.MultiplyTransforms(p.Transform, m)
+	if b := s.StrokeBBox(); !b.Empty() {
+		p.BoundingBox = transformBox(p.Transform, b)
+	}
+
+	instrs, first := svg.CollectInstructions(s)
+	for _, di := range instrs {
+		p.Draw(di)
+	}
+	if _, err := p.WriteTo(w); err != nil {
+		return err
+	}
+	return first
+}
+
+// transformBox returns the box around a box mapped by a transform.
+func transformBox(t mt.Transform, b svg.Box) svg.Box {
+	out := svg.Box{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, c := range [][2]float64{{b.MinX, b.MinY}, {b.MaxX, b.MinY}, {b.MaxX, b.MaxY}, {b.MinX, b.MaxY}} {
+		x, y := t.Apply(c[0], c[1])
+		out = out.Union(svg.Box{MinX: x, MinY: y, MaxX: x, MaxY: y})
+	}
+	return out
+}
+
+// DrawInstructions draws all instructions received from the channel.
+func (p *Page) DrawInstructions(instrs chan *svg.DrawingInstruction) {
+	for di := range instrs {
+		p.Draw(di)
+	}
+}
+
+// Draw processes a single drawing instruction.
+func (p *Page) Draw(di *svg.DrawingInstruction) {
+	if di.Kind == svg.PaintInstruction {
+		p.paint(di)
+		p.path.Reset()
+		return
+	}
+	p.path.Add(di)
+}
+
+// paint fills and strokes the current path. The path is written in the
+// coordinates of the drawing instructions under the page transform, so
+// stroke widths are transformed along with it.
+func (p *Page) paint(di *svg.DrawingInstruction) {
+	if p.path.Len() == 0 || di.Opacity != nil && *di.Opacity <= 0 {
+		return
+	}
+	fill := "black"
+	if di.Fill != nil && strings.TrimSpace(*di.Fill) != "" {
+		fill = *di.Fill
+	}
+	fc, filled := svg.ParseColor(fill)
+	filled = filled && fc.A > 0
+	var sc color.NRGBA
+	stroked := false
+	if di.Stroke != nil && di.StrokeWidth != nil && *di.StrokeWidth > 0 {
+		sc, stroked = svg.ParseColor(*di.Stroke)
+		stroked = stroked && sc.A > 0
+	}
+	if !filled && !stroked {
+		return
+	}
+
+	b := &p.content
+	t := p.Transform
+	b.WriteString("gsave\n[")
+	for i, v := range []float64{t[0][0], t[1][0], t[0][1], t[1][1], t[0][2], t[1][2]} {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	b.WriteString("] concat\nnewpath\n")
+	b.WriteString(p.path.String())
+
+	if filled {
+		op := "fill"
+		if di.FillRule != nil && svg.ParseFillRule(strings.TrimSpace(*di.FillRule)) == svg.EvenOdd {
+			op = "eofill"
+		}
+		if stroked {
+			// Keep the path for the stroke.
+			fmt.Fprintf(b, "gsave %s %s grestore\n", rgb(fc), op)
+		} else {
+			fmt.Fprintf(b, "%s %s\n", rgb(fc), op)
+		}
+	}
+	if stroked {
+		fmt.Fprintf(b, "%s %s setlinewidth\n", rgb(sc), pathops.Number(*di.StrokeWidth))
+		if di.StrokeLineCap != nil {
+			switch strings.TrimSpace(*di.StrokeLineCap) {
+			case "round":
+				b.WriteString("1 setlinecap\n")
+			case "square":
+				b.WriteString("2 setlinecap\n")
+			}
+		}
+		if di.StrokeLineJoin != nil {
+			switch strings.TrimSpace(*di.StrokeLineJoin) {
+			case "round":
+				b.WriteString("1 setlinejoin\n")
+			case "bevel":
+				b.WriteString("2 setlinejoin\n")
+			}
+		}
+		// SVG and PostScript differ in the default miter limit.
+		limit := 4.0
+		if di.StrokeMiterLimit != nil && *di.StrokeMiterLimit >= 1 {
+			limit = *di.StrokeMiterLimit
+		}
+		b.WriteString(pathops.Number(limit) + " setmiterlimit\nstroke\n")
+	}
+	b.WriteString("grestore\n")
+}
+
+// WriteTo writes the page as an EPS file.
+func (p *Page) WriteTo(w io.Writer) (int64, error) {
+	box := p.BoundingBox
+	if box == (svg.Box{}) || box.Empty() {
+		box = svg.Box{MaxX: p.Width, MaxY: p.Height}
+	}
+	bw := bufio.NewWriter(w)
+	var n int64
+	var err error
+	write := func(format string, args ...interface{}) {
+		if err == nil {
+			var k int
+			k, err = fmt.Fprintf(bw, format, args...)
+			n += int64(k)
+		}
+	}
+	write("%%!PS-Adobe-3.0 EPSF-3.0\n")
+	write("%%%%BoundingBox: %d %d %d %d\n",
+		int(math.Floor(box.MinX)), int(math.Floor(box.MinY)), int(math.Ceil(box.MaxX)), int(math.Ceil(box.MaxY)))
+	write("%%%%HiResBoundingBox: %s %s %s %s\n", pathops.Number(box.MinX), pathops.Number(box.MinY), pathops.Number(box.MaxX), pathops.Number(box.MaxY))
+	write("%%%%Creator: github.com/rustyoz/svg\n")
+	write("%%%%LanguageLevel: 2\n%%%%Pages: 1\n%%%%EndComments\n")
+	write("%%%%BeginProlog\n%s%%%%EndProlog\n", prolog)
+	write("%%%%Page: 1 1\n%s", p.content.String())
+	write("showpage\n%%%%EOF\n")
+	if err == nil {
+		err = bw.Flush()
+	}
+	return n, err
+}
+
+// rgb returns the operator setting a colour.
+func rgb(c color.NRGBA) string {
+	return fmt.Sprintf("%s %s %s setrgbcolor", pathops.Number(float64(c.R)/255), pathops.Number(float64(c.G)/255), pathops.Number(float64(c.B)/255))
+}
@@ -0,0 +1,63 @@
+package eps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(`<svg width="100mm" height="50mm" viewBox="0 0 200 100">
+		<path d="M10 10 H90 V90 H10 Z M30 30 V70 H70 V30 Z" fill="#3366cc" fill-rule="evenodd"
+			stroke="black" stroke-width="2" stroke-linejoin="round"/>
+		<path d="M110 10 C150 10 150 90 190 90" fill="none" stroke="red" stroke-width="6" stroke-linecap="round"/>
+		<circle cx="150" cy="50" r="10" fill="green"/>
+	</svg>`, "eps", 1)
+	require.NoError(t, err)
+	var b strings.Builder
+	require.NoError(t, Write(&b, s))
+	out := b.String()
+
+	require.True(t, strings.HasPrefix(out, "%!PS-Adobe-3.0 EPSF-3.0\n"))
+	require.True(t, strings.HasSuffix(out, "showpage\n%%EOF\n"))
+	// The box of the strokes in points with the y axis up: the square
+	// on the left, the round caps of the curve at the top right and
+	// bottom.
+	require.Contains(t, out, "\n%%BoundingBox: 12 9 274 132\n")
+	require.Contains(t, out, "\n%%HiResBoundingBox: 12.7559 9.9218 273.5386 131.8105\n")
+
+	elements := strings.Split(out[strings.Index(out, "%%Page: 1 1\n"):], "gsave\n[")[1:]
+	require.Len(t, elements, 3)
+	for _, e := range elements {
+		require.True(t, strings.HasPrefix(e, "1.4173228346456692 0 0 -1.4173228346456692 0 141.73228346456693] concat\nnewpath\n"))
+	}
+	require.Contains(t, elements[0], "10 10 m\n90 10 l\n")
+	require.Contains(t, elements[0], "70 30 l\nh\ngsave 0.2 0.4 0.8 setrgbcolor eofill grestore\n"+
+		"0 0 0 setrgbcolor 2 setlinewidth\n1 setlinejoin\n4 setmiterlimit\nstroke\ngrestore\n")
+	require.Contains(t, elements[1], "150 10 150 90 190 90 c\n1 0 0 setrgbcolor 6 setlinewidth\n1 setlinecap\n4 setmiterlimit\nstroke\n")
+	require.NotContains(t, elements[1], "fill")
+	require.Contains(t, elements[2], "h\n0 0.502 0 setrgbcolor fill\ngrestore\n")
+}
+
+func TestPage(t *testing.T) {
+	p := NewPage(100, 50)
+	p.Draw(&svg.DrawingInstruction{Kind: svg.LineInstruction, M: &svg.Tuple{10, 10}})
+	none, zero := "none", 0.0
+	p.Draw(&svg.DrawingInstruction{Kind: svg.PaintInstruction, Fill: &none})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.LineInstruction, M: &svg.Tuple{10, 10}})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.PaintInstruction, Opacity: &zero})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.MoveInstruction, M: &svg.Tuple{5, 5}})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.LineInstruction, M: &svg.Tuple{10, 10}})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.PaintInstruction})
+
+	var b strings.Builder
+	n, err := p.WriteTo(&b)
+	require.NoError(t, err)
+	require.EqualValues(t, b.Len(), n)
+	out := b.String()
+	require.Contains(t, out, "\n%%BoundingBox: 0 0 100 50\n")
+	require.Equal(t, 1, strings.Count(out, "gsave"))
+	require.Contains(t, out, "[1 0 0 -1 0 50] concat\nnewpath\n5 5 m\n10 10 l\n0 0 0 setrgbcolor fill\ngrestore\n")
+}
@@ -0,0 +1,396 @@
+// Package gcode turns SVG documents into G-code for laser cutters, pen
+// plotters and mills. Every drawn element is followed along its outline
+// with the tool on; fills are not produced.
+package gcode
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+)
+
+// DefaultTolerance is the default maximum distance in output units
+// between a curve and the moves it is cut with.
+const DefaultTolerance = 0.01
+
+// Units are the units of the output, selected with G21 or G20.
+type Units int
+
+// The units of the output.
+const (
+	Millimeters Units = iota
+	Inches
+)
+
+// Settings control how the tool follows a path.
+type Settings struct {
+	// Feed is the speed of cutting moves in units per minute. Zero
+	// leaves the feed rate of the machine unchanged.
+	Feed float64
+	// Power is the laser power set with the laser on command. Zero
+	// means the laser is not switched.
+	Power float64
+	// Passes is the number of times each path is cut. Zero means once.
+	Passes int
+	// Skip leaves the paths out of the output.
+	Skip bool
+}
+
+// Options control the generated G-code. The zero value cuts all paths
+// once in millimetres without feed rates, tool heights or laser
+// commands, with coordinates as in the document.
+type Options struct {
+	Units Units
+	// Transform maps the points of the document to output units, for
+	// example to scale pixels to millimetres and to turn the Y axis up.
+	// Nil means the identity.
+	Transform *mt.Transform
+	// Tolerance is the maximum distance in output units between a
+	// curve and the moves it is cut with. Zero means DefaultTolerance.
+	Tolerance float64
+	// Precision is the number of decimals of coordinates. Zero means 3.
+	Precision int
+
+	// Settings are used for paths matched by neither Layers nor Colors.
+	Settings
+	// Layers holds settings by the label or ID of layers. Paths in
+	// sublayers use the settings of their closest listed layer.
+	Layers map[string]Settings
+	// Colors holds settings by colour, in any form accepted by
+	// svg.ParseColor. The stroke colour of a path is used if it has
+	// one, its fill colour otherwise. Colors take precedence over
+	// Layers.
+	Colors map[string]Settings
+
+	// TravelSpeed is the feed rate of moves with the tool off. Zero
+	// writes rapid moves without a feed rate.
+	TravelSpeed float64
+	// ZUp and ZDown are the heights the tool is raised to between paths
+	// and lowered to for cutting. Nil means the Z axis is not moved.
+	ZUp, ZDown *float64
+	// PlungeFeed is the feed rate of lowering the tool. Zero means no
+	// feed rate is given.
+	PlungeFeed float64
+	// LaserOn and LaserOff switch the laser for paths with a power.
+	// They default to M3 and M5.
+	LaserOn, LaserOff string
+	// Up and Down are commands written after raising and after lowering
+	// the tool, for machines such as servo pen plotters.
+	Up, Down []string
+	// Arcs writes circular arcs as G2 and G3 moves rather than as
+	// lines.
+	Arcs bool
+
+	// Header and Footer are written at the start and at the end of the
+	// program.
+	Header, Footer []string
+}
+
+// Write writes a program cutting the elements of the document that
+// would be drawn by ParseDrawingInstructions, in paint order. Paths are
+// cut from their exact geometry so their arcs can be written as arcs.
+// Errors in elements are returned once the rest of the document is
+// written.
+func Write(w io.Writer, s *svg.Svg, opts Options) error {
+	g := newGenerator(w, opts)
+	g.begin()
+	var first error
+	s.VisitElements(func(e svg.DrawingInstructionParser, group *svg.Group) {
+		if p, ok := e.(*svg.Path); ok {
+			geometry, err := p.Geometry()
+			if err != nil && first == nil {
+				first = err
+			}
+			g.cut(geometry, g.settings(p.Paint(), group))
+			return
+		}
+		instrs, err := svg.CollectInstructions(e)
+		if err != nil && first == nil {
+			first = err
+		}
+		var paint *svg.DrawingInstruction
+		for _, di := range instrs {
+			if di.Kind == svg.PaintInstruction {
+				paint = di
+			}
+		}
+		g.cut(svg.GeometryFromInstructions(instrs), g.settings(paint, group))
+	})
+	if err := g.end(); err != nil {
+		return err
+	}
+	return first
+}
+
+// WriteInstructions writes a program cutting the paths described by the
+// drawing instructions received from the channel. Layers are not known
+// from instructions, so only Colors select settings.
+func WriteInstructions(w io.Writer, instrs chan *svg.DrawingInstruction, opts Options) error {
+	g := newGenerator(w, opts)
+	g.begin()
+	var path []*svg.DrawingInstruction
+	for di := range instrs {
+		if di.Kind != svg.PaintInstruction {
+			path = append(path, di)
+			continue
+		}
+		g.cut(svg.GeometryFromInstructions(path), g.settings(di, nil))
+		path = path[:0]
+	}
+	g.cut(svg.GeometryFromInstructions(path), g.settings(nil, nil))
+	return g.end()
+}
+
+// WriteSegments writes a program cutting the segments received from the
+// channel with the default settings.
+func WriteSegments(w io.Writer, segments chan svg.Segment, opts Options) error {
+	g := newGenerator(w, opts)
+	g.begin()
+	for seg := range segments {
+		var sp svg.Subpath
+		for i := 1; i < len(seg.Points); i++ {
+			sp.Curves = append(sp.Curves, svg.LineCurve{P0: seg.Points[i-1], P1: seg.Points[i]})
+		}
+		sp.Closed = seg.Closed
+		g.cut(&svg.PathGeometry{Subpaths: []svg.Subpath{sp}}, opts.Settings)
+	}
+	return g.end()
+}
+
+// paintColor returns the colour selecting the settings of a path: its
+// stroke colour if it is stroked, its fill colour otherwise. Paths are
+// filled black unless told otherwise.
+func paintColor(paint *svg.DrawingInstruction) (color.NRGBA, bool) {
+	if paint != nil && paint.Stroke != nil {
+		if c, ok := svg.ParseColor(*paint.Stroke); ok {
+			return c, true
+		}
+	}
+	fill := "black"
+	if paint != nil && paint.Fill != nil && strings.TrimSpace(*paint.Fill) != "" {
+		fill = *paint.Fill
+	}
+	return svg.ParseColor(fill)
+}
+
+// generator writes G-code, keeping track of the state of the machine to
+// leave out words that do not change it.
+type generator struct {
+	w         *bufio.Writer
+	opts      Options
+	transform mt.Transform
+	colors    map[color.NRGBA]Settings
+	err       error
+
+	pos  [2]float64
+	feed float64
+}
+
+func newGenerator(w io.Writer, opts Options) *generator {
+	g := &generator{w: bufio.NewWriter(w), opts: opts, transform: mt.Identity(), feed: -1}
+	if opts.Transform != nil {
+		g.transform = *opts.Transform
+	}
+	if g.opts.Tolerance <= 0 {
+		g.opts.Tolerance = DefaultTolerance
+	}
+	if g.opts.Precision <= 0 {
+		g.opts.Precision = 3
+	}
+	if g.opts.LaserOn == "" {
+		g.opts.LaserOn = "M3"
+	}
+	if g.opts.LaserOff == "" {
+		g.opts.LaserOff = "M5"
+	}
+	g.colors = make(map[color.NRGBA]Settings)
+	for k, v := range opts.Colors {
+		if c, ok := svg.ParseColor(k); ok {
+			g.colors[c] = v
+		}
+	}
+	return g
+}
+
+// settings returns the settings for a path painted by the paint
+// instruction in the given group.
+func (g *generator) settings(paint *svg.DrawingInstruction, group *svg.Group) Settings {
+	if c, ok := paintColor(paint); ok {
+		if s, ok := g.colors[c]; ok {
+			return s
+		}
+	}
+	for grp := group; grp != nil; grp = grp.Parent {
+		if l := grp.Layer; l != nil {
+			if s, ok := g.opts.Layers[l.Label]; ok {
+				return s
+			}
+			if s, ok := g.opts.Layers[l.ID]; ok {
+				return s
+			}
+		}
+	}
+	return g.opts.Settings
+}
+
+func (g *generator) println(line string) {
+	if g.err == nil {
+		_, g.err = g.w.WriteString(line + "\n")
+	}
+}
+
+func (g *generator) number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', g.opts.Precision, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// move writes a move to p, with the feed rate if it changes.
+func (g *generator) move(code string, p [2]float64, feed float64, extra ...string) {
+	line := fmt.Sprintf("%s X%s Y%s", code, g.number(p[0]), g.number(p[1]))
+	for _, e := range extra {
+		line += " " + e
+	}
+	if feed > 0 && feed != g.feed {
+		line += " F" + g.number(feed)
+		g.feed = feed
+	}
+	g.println(line)
+	g.pos = p
+}
+
+func (g *generator) begin() {
+	if g.opts.Units == Inches {
+		g.println("G20")
+	} else {
+		g.println("G21")
+	}
+	g.println("G90")
+	for _, l := range g.opts.Header {
+		g.println(l)
+	}
+	g.up()
+}
+
+func (g *generator) end() error {
+	for _, l := range g.opts.Footer {
+		g.println(l)
+	}
+	if g.err == nil {
+		g.err = g.w.Flush()
+	}
+	return g.err
+}
+
+func (g *generator) up() {
+	if g.opts.ZUp != nil {
+		g.println("G0 Z" + g.number(*g.opts.ZUp))
+	}
+	for _, l := range g.opts.Up {
+		g.println(l)
+	}
+}
+
+func (g *generator) down(s Settings) {
+	if g.opts.ZDown != nil {
+		line := "G1 Z" + g.number(*g.opts.ZDown)
+		if g.opts.PlungeFeed > 0 && g.opts.PlungeFeed != g.feed {
+			line += " F" + g.number(g.opts.PlungeFeed)
+			g.feed = g.opts.PlungeFeed
+		}
+		g.println(line)
+	}
+	for _, l := range g.opts.Down {
+		g.println(l)
+	}
+	if s.Power > 0 {
+		g.println(g.opts.LaserOn + " S" + g.number(s.Power))
+	}
+}
+
+func (g *generator) off(s Settings) {
+	if s.Power > 0 {
+		g.println(g.opts.LaserOff)
+	}
+	g.up()
+}
+
+// cut writes the moves following the subpaths of a path.
+func (g *generator) cut(geometry *svg.PathGeometry, s Settings) {
+	if s.Skip || geometry == nil {
+		return
+	}
+	geometry = geometry.Transform(g.transform)
+	passes := s.Passes
+	if passes < 1 {
+		passes = 1
+	}
+	for pass := 0; pass < passes; pass++ {
+		for _, sp := range geometry.Subpaths {
+			if len(sp.Curves) == 0 {
+				continue
+			}
+			g.move("G0", sp.Curves[0].PointAt(0), g.opts.TravelSpeed)
+			g.down(s)
+			for _, c := range sp.Curves {
+				g.curve(c, s.Feed)
+			}
+			g.off(s)
+		}
+	}
+}
+
+func (g *generator) curve(c svg.Curve, feed float64) {
+	switch c := c.(type) {
+	case svg.LineCurve:
+		g.move("G1", c.P1, feed)
+		return
+	case svg.ArcCurve:
+		if g.opts.Arcs && circular(c) {
+			g.arc(c, feed)
+			return
+		}
+	}
+	for _, p := range svg.FlattenCurve(c, g.opts.Tolerance)[1:] {
+		g.move("G1", p, feed)
+	}
+}
+
+// circular reports whether an arc lies on a circle.
+func circular(c svg.ArcCurve) bool {
+	ru, rv := math.Hypot(c.U[0], c.U[1]), math.Hypot(c.V[0], c.V[1])
+	r := math.Max(ru, rv)
+	dot := c.U[0]*c.V[0] + c.U[1]*c.V[1]
+	return r > 0 && math.Abs(ru-rv) <= r*1e-9 && math.Abs(dot) <= r*r*1e-9
+}
+
+// arc writes a circular arc as G2 or G3 moves of at most half a turn
+// each, with the centre relative to the start of each move.
+func (g *generator) arc(c svg.ArcCurve, feed float64) {
+	code := "G2"
+	if (c.U[0]*c.V[1]-c.U[1]*c.V[0])*c.Sweep > 0 {
+		code = "G3"
+	}
+	n := int(math.Ceil(math.Abs(c.Sweep)/math.Pi - 1e-9))
+	if n < 1 {
+		n = 1
+	}
+	for k := 1; k <= n; k++ {
+		from := g.pos
+		i := "I" + g.number(c.Center[0]-from[0])
+		j := "J" + g.number(c.Center[1]-from[1])
+		g.move(code, c.PointAt(float64(k)/float64(n)), feed, i, j)
+	}
+}
@@ -0,0 +1,168 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+const layeredSvg = `<svg xmlns="http://www.w3.org/2000/svg"
+	xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape">
+	<g inkscape:groupmode="layer" inkscape:label="cut">
+		<path d="M10 0 A10 10 0 0 1 -10 0" stroke="red"/>
+		<circle cx="5" cy="5" r="2"/>
+		<g inkscape:groupmode="layer" inkscape:label="inner">
+			<path d="M0 0 H1"/>
+		</g>
+	</g>
+	<g inkscape:groupmode="layer" inkscape:label="engrave">
+		<path d="M0 0 L1 1 Z" stroke="blue"/>
+	</g>
+	<g inkscape:groupmode="layer" inkscape:label="notes">
+		<path d="M0 0 H100"/>
+	</g>
+</svg>`
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSpace(s), "\n")
+}
+
+func TestWriteSegments(t *testing.T) {
+	segments := make(chan svg.Segment, 2)
+	segments <- svg.Segment{Points: [][2]float64{{0, 0}, {10.5, 0}, {10.5, 1.0 / 3}}, Closed: true}
+	segments <- svg.Segment{Points: [][2]float64{{20, 20}, {30, 20}}}
+	close(segments)
+
+	var b strings.Builder
+	require.NoError(t, WriteSegments(&b, segments, Options{
+		Units:       Inches,
+		Settings:    Settings{Feed: 500, Power: 800},
+		TravelSpeed: 3000,
+		Header:      []string{"; start"},
+		Footer:      []string{"M2"},
+	}))
+	require.Equal(t, []string{
+		"G20",
+		"G90",
+		"; start",
+		"G0 X0 Y0 F3000",
+		"M3 S800",
+		"G1 X10.5 Y0 F500",
+		"G1 X10.5 Y0.333",
+		"M5",
+		"G0 X20 Y20 F3000",
+		"M3 S800",
+		"G1 X30 Y20 F500",
+		"M5",
+		"M2",
+	}, lines(b.String()))
+}
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(layeredSvg, "layers", 1)
+	require.NoError(t, err)
+
+	up, down := 5.0, -1.0
+	opts := Options{
+		Arcs:       true,
+		Settings:   Settings{Feed: 100},
+		ZUp:        &up,
+		ZDown:      &down,
+		PlungeFeed: 50,
+		Layers: map[string]Settings{
+			"cut":     {Feed: 150},
+			"engrave": {Feed: 200, Power: 10, Passes: 2},
+			"notes":   {Skip: true},
+		},
+		Colors: map[string]Settings{"#f00": {Feed: 300, Power: 100}},
+	}
+	var b strings.Builder
+	require.NoError(t, Write(&b, s, opts))
+	require.Equal(t, []string{
+		"G21",
+		"G90",
+		"G0 Z5",
+		// The red stroke takes precedence over the layer.
+		"G0 X10 Y0",
+		"G1 Z-1 F50",
+		"M3 S100",
+		"G3 X-10 Y0 I-10 J0 F300",
+		"M5",
+		"G0 Z5",
+		// Circles are cut as two half turns.
+		"G0 X7 Y5",
+		"G1 Z-1 F50",
+		"G3 X3 Y5 I-2 J0 F150",
+		"G3 X7 Y5 I2 J0",
+		"G0 Z5",
+		// Sublayers use the settings of their parent layer.
+		"G0 X0 Y0",
+		"G1 Z-1 F50",
+		"G1 X1 Y0 F150",
+		"G0 Z5",
+		"G0 X0 Y0",
+		"G1 Z-1 F50",
+		"M3 S10",
+		"G1 X1 Y1 F200",
+		"G1 X0 Y0",
+		"M5",
+		"G0 Z5",
+		"G0 X0 Y0",
+		"G1 Z-1 F50",
+		"M3 S10",
+		"G1 X1 Y1 F200",
+		"G1 X0 Y0",
+		"M5",
+		"G0 Z5",
+	}, lines(b.String()))
+
+	// Turning the Y axis up reverses the direction of arcs.
+	flip := mt.Identity()
+	flip.Scale(1, -1)
+	b.Reset()
+	require.NoError(t, Write(&b, s, Options{Arcs: true, Transform: &flip}))
+	require.Contains(t, lines(b.String()), "G2 X-10 Y0 I-10 J0")
+
+	// Without Arcs curves are cut as lines within the tolerance.
+	b.Reset()
+	require.NoError(t, Write(&b, s, Options{Tolerance: 0.001}))
+	out := b.String()
+	require.NotContains(t, out, "G2 ")
+	require.NotContains(t, out, "G3 ")
+	require.Greater(t, strings.Count(out, "G1"), 100)
+}
+
+func TestWriteInstructions(t *testing.T) {
+	s, err := svg.ParseSvg(`<svg>
+		<path d="M0 0 H10 V10 Z" stroke="blue"/>
+		<path d="M0 0 C0 10 10 10 10 0"/>
+	</svg>`, "instructions", 1)
+	require.NoError(t, err)
+	instrs, errs := s.ParseDrawingInstructions()
+	go func() {
+		for range errs {
+		}
+	}()
+
+	var b strings.Builder
+	require.NoError(t, WriteInstructions(&b, instrs, Options{
+		Settings: Settings{Feed: 100},
+		Colors:   map[string]Settings{"blue": {Feed: 200}},
+	}))
+	out := lines(b.String())
+	require.Equal(t, []string{
+		"G21",
+		"G90",
+		"G0 X0 Y0",
+		"G1 X10 Y0 F200",
+		"G1 X10 Y10",
+		"G1 X0 Y0",
+		"G0 X0 Y0",
+	}, out[:7])
+	require.Equal(t, "G1 X10 Y0", out[len(out)-1])
+	require.Contains(t, out, "G1 X5 Y7.5")
+	require.Contains(t, out[7], "F100")
+}
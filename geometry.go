@@ -0,0 +1,691 @@
+package svg
+
+import (
+	"math"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// A Curve is one piece of a path: a line, a quadratic or cubic bezier
+// curve or an elliptical arc, running from t = 0 to t = 1.
+type Curve interface {
+	// PointAt returns the point of the curve at parameter t.
+	PointAt(t float64) [2]float64
+	// Derivative returns the derivative of the curve with respect to t.
+	Derivative(t float64) [2]float64
+	// Split divides the curve at parameter t.
+	Split(t float64) (Curve, Curve)
+	// Transform returns the curve mapped by an affine transform.
+	Transform(m mt.Transform) Curve
+	// Bounds returns the smallest box containing the curve.
+	Bounds() Box
+}
+
+// LineCurve is a straight line from P0 to P1.
+type LineCurve struct {
+	P0, P1 [2]float64
+}
+
+// QuadraticCurve is a quadratic bezier curve from P0 to P1 with control
+// point C.
+type QuadraticCurve struct {
+	P0, C, P1 [2]float64
+}
+
+// CubicCurve is a cubic bezier curve from P0 to P1 with control points
+// C1 and C2.
+type CubicCurve struct {
+	P0, C1, C2, P1 [2]float64
+}
+
+// ArcCurve is an elliptical arc. Its points are
+// Center + U cos(a) + V sin(a) for angles a from Start to Start + Sweep,
+// so U and V are conjugate semi-diameters, which keeps arcs exact under
+// any affine transform.
+type ArcCurve struct {
+	Center, U, V [2]float64
+	Start, Sweep float64
+}
+
+// PointAt returns the point at t between P0 at t = 0 and P1 at t = 1.
+func (c LineCurve) PointAt(t float64) [2]float64 {
+	return vadd(c.P0, vscale(vsub(c.P1, c.P0), t))
+}
+
+// Derivative returns P1 - P0, the same for every t.
+func (c LineCurve) Derivative(t float64) [2]float64 {
+	return vsub(c.P1, c.P0)
+}
+
+// Split returns the lines from P0 to the point at t and from there to
+// P1.
+func (c LineCurve) Split(t float64) (Curve, Curve) {
+	p := c.PointAt(t)
+	return LineCurve{c.P0, p}, LineCurve{p, c.P1}
+}
+
+// Transform returns the line between the mapped end points.
+func (c LineCurve) Transform(m mt.Transform) Curve {
+	return LineCurve{applyPoint(m, c.P0), applyPoint(m, c.P1)}
+}
+
+// PointAt returns the point of the curve at t, from P0 at t = 0 to P1
+// at t = 1.
+func (c QuadraticCurve) PointAt(t float64) [2]float64 {
+	u := 1 - t
+	return vadd(vadd(vscale(c.P0, u*u), vscale(c.C, 2*u*t)), vscale(c.P1, t*t))
+}
+
+// Derivative returns the tangent vector of the curve at t.
+func (c QuadraticCurve) Derivative(t float64) [2]float64 {
+	return vadd(vscale(vsub(c.C, c.P0), 2*(1-t)), vscale(vsub(c.P1, c.C), 2*t))
+}
+
+// Split divides the curve at t with de Casteljau's algorithm into the
+// parts before and after t, each running from 0 to 1 again.
+func (c QuadraticCurve) Split(t float64) (Curve, Curve) {
+	a, b := lerp(c.P0, c.C, t), lerp(c.C, c.P1, t)
+	p := lerp(a, b, t)
+	return QuadraticCurve{c.P0, a, p}, QuadraticCurve{p, b, c.P1}
+}
+
+// Transform maps the end and control points, which maps the curve
+// exactly.
+func (c QuadraticCurve) Transform(m mt.Transform) Curve {
+	return QuadraticCurve{applyPoint(m, c.P0), applyPoint(m, c.C), applyPoint(m, c.P1)}
+}
+
+// PointAt returns the point of the curve at t, from P0 at t = 0 to P1
+// at t = 1.
+func (c CubicCurve) PointAt(t float64) [2]float64 {
+	u := 1 - t
+	return vadd(vadd(vscale(c.P0, u*u*u), vscale(c.C1, 3*u*u*t)),
+		vadd(vscale(c.C2, 3*u*t*t), vscale(c.P1, t*t*t)))
+}
+
+// Derivative returns the tangent vector of the curve at t.
+func (c CubicCurve) Derivative(t float64) [2]float64 {
+	u := 1 - t
+	return vadd(vadd(vscale(vsub(c.C1, c.P0), 3*u*u), vscale(vsub(c.C2, c.C1), 6*u*t)),
+		vscale(vsub(c.P1, c.C2), 3*t*t))
+}
+
+// Split divides the curve at t with de Casteljau's algorithm into the
+// parts before and after t, each running from 0 to 1 again.
+func (c CubicCurve) Split(t float64) (Curve, Curve) {
+	a, b, d := lerp(c.P0, c.C1, t), lerp(c.C1, c.C2, t), lerp(c.C2, c.P1, t)
+	ab, bd := lerp(a, b, t), lerp(b, d, t)
+	p := lerp(ab, bd, t)
+	return CubicCurve{c.P0, a, ab, p}, CubicCurve{p, bd, d, c.P1}
+}
+
+// Transform maps the end and control points, which maps the curve
+// exactly.
+func (c CubicCurve) Transform(m mt.Transform) Curve {
+	return CubicCurve{applyPoint(m, c.P0), applyPoint(m, c.C1), applyPoint(m, c.C2), applyPoint(m, c.P1)}
+}
+
+// PointAt returns the point at angle Start + Sweep t, so t runs from 0
+// to 1 along the arc.
+func (c ArcCurve) PointAt(t float64) [2]float64 {
+	s, co := math.Sincos(c.Start + c.Sweep*t)
+	return vadd(c.Center, vadd(vscale(c.U, co), vscale(c.V, s)))
+}
+
+// Derivative returns the tangent vector of the arc at t, which is
+// Sweep times the derivative with respect to the angle.
+func (c ArcCurve) Derivative(t float64) [2]float64 {
+	s, co := math.Sincos(c.Start + c.Sweep*t)
+	return vscale(vadd(vscale(c.U, -s), vscale(c.V, co)), c.Sweep)
+}
+
+// Split divides the sweep at t into the arc before t and the arc after
+// it.
+func (c ArcCurve) Split(t float64) (Curve, Curve) {
+	a, b := c, c
+	a.Sweep = c.Sweep * t
+	b.Start = c.Start + a.Sweep
+	b.Sweep = c.Sweep - a.Sweep
+	return a, b
+}
+
+// Transform maps the centre and the semi-diameters. The result is the
+// exact image of the arc, also under shears and uneven scales.
+func (c ArcCurve) Transform(m mt.Transform) Curve {
+	o := applyPoint(m, [2]float64{})
+	c.Center = applyPoint(m, c.Center)
+	c.U = vsub(applyPoint(m, c.U), o)
+	c.V = vsub(applyPoint(m, c.V), o)
+	return c
+}
+
+// cubic returns the quadratic curve as the cubic curve it is.
+func (c QuadraticCurve) cubic() CubicCurve {
+	return CubicCurve{c.P0, lerp(c.P0, c.C, 2.0/3), lerp(c.P1, c.C, 2.0/3), c.P1}
+}
+
+// cubics approximates the arc by cubic curves, one for every quarter
+// turn or less. The error is below 3e-4 of the radius.
+func (c ArcCurve) cubics() []CubicCurve {
+	n := int(math.Ceil(math.Abs(c.Sweep) / (math.Pi / 2)))
+	if n < 1 {
+		n = 1
+	}
+	var out []CubicCurve
+	p0 := c.PointAt(0)
+	for i := 1; i <= n; i++ {
+		t0, t1 := float64(i-1)/float64(n), float64(i)/float64(n)
+		// The control points lie along the tangents at a distance
+		// given by the sweep of the piece.
+		k := 4.0 / 3 * math.Tan(c.Sweep/float64(n)/4) / c.Sweep
+		p1 := c.PointAt(t1)
+		out = append(out, CubicCurve{p0, vadd(p0, vscale(c.Derivative(t0), k)), vsub(p1, vscale(c.Derivative(t1), k)), p1})
+		p0 = p1
+	}
+	return out
+}
+
+func lerp(a, b [2]float64, t float64) [2]float64 {
+	return vadd(a, vscale(vsub(b, a), t))
+}
+
+func applyPoint(m mt.Transform, p [2]float64) [2]float64 {
+	x, y := m.Apply(p[0], p[1])
+	return [2]float64{x, y}
+}
+
+// gaussLegendre holds the nodes on [-1, 1] and weights of the five point
+// Gauss-Legendre rule.
+var gaussLegendre = [5][2]float64{
+	{0, 0.5688888888888889},
+	{-0.5384693101056831, 0.4786286704993665},
+	{0.5384693101056831, 0.4786286704993665},
+	{-0.9061798459386640, 0.2369268850561891},
+	{0.9061798459386640, 0.2369268850561891},
+}
+
+// speedIntegral integrates the speed of c from t0 to t1 with the five
+// point Gauss-Legendre rule.
+func speedIntegral(c Curve, t0, t1 float64) float64 {
+	h := (t1 - t0) / 2
+	var sum float64
+	for _, n := range gaussLegendre {
+		sum += n[1] * vlen(c.Derivative(t0+h*(n[0]+1)))
+	}
+	return sum * h
+}
+
+// arcLength integrates the speed of c from t0 to t1, halving the
+// interval until both halves agree with the whole to within eps.
+func arcLength(c Curve, t0, t1, whole, eps float64, depth int) float64 {
+	m := (t0 + t1) / 2
+	left, right := speedIntegral(c, t0, m), speedIntegral(c, m, t1)
+	if depth >= maxFlattenDepth || math.Abs(left+right-whole) <= eps {
+		return left + right
+	}
+	return arcLength(c, t0, m, left, eps/2, depth+1) + arcLength(c, m, t1, right, eps/2, depth+1)
+}
+
+// lengthEpsilon is the accuracy of computed lengths relative to the
+// length of a curve.
+const lengthEpsilon = 1e-10
+
+// CurveLength returns the length of c from t = 0 to t.
+func CurveLength(c Curve, t float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	whole := speedIntegral(c, 0, t)
+	return arcLength(c, 0, t, whole, lengthEpsilon*math.Max(whole, 1e-12), 0)
+}
+
+// CurveParameterAt returns the parameter at which the length of c from
+// its start is s, clamped to the curve.
+func CurveParameterAt(c Curve, s float64) float64 {
+	total := CurveLength(c, 1)
+	if s <= 0 || total == 0 {
+		return 0
+	}
+	if s >= total {
+		return 1
+	}
+	// Newton's method on the length, falling back to bisection when a
+	// step leaves the bracket around the solution.
+	lo, hi := 0.0, 1.0
+	t := s / total
+	for i := 0; i < 64; i++ {
+		f := CurveLength(c, t) - s
+		if math.Abs(f) <= lengthEpsilon*total {
+			break
+		}
+		if f > 0 {
+			hi = t
+		} else {
+			lo = t
+		}
+		next := lo - 1
+		if v := vlen(c.Derivative(t)); v > 0 {
+			next = t - f/v
+		}
+		if next <= lo || next >= hi {
+			next = (lo + hi) / 2
+		}
+		t = next
+	}
+	return t
+}
+
+// CurveTangent returns the unit tangent of c at parameter t. Where the
+// derivative vanishes, as at cusps and at coinciding control points,
+// the direction of the curve around t is used instead.
+func CurveTangent(c Curve, t float64) [2]float64 {
+	d := c.Derivative(t)
+	if vlen(d) <= 1e-12*math.Max(vlen(c.Derivative(0.5)), 1) {
+		d = vsub(c.PointAt(math.Min(t+1e-6, 1)), c.PointAt(math.Max(t-1e-6, 0)))
+	}
+	if l := vlen(d); l > 0 {
+		return vscale(d, 1/l)
+	}
+	return [2]float64{}
+}
+
+// Subpath is a connected run of curves, each starting where the one
+// before ends. The curves of a closed subpath include the line back to
+// its start unless it has no length.
+type Subpath struct {
+	Curves []Curve
+	Closed bool
+}
+
+// PathGeometry holds the exact curves of a path for arc-length queries.
+// Lengths are measured along all subpaths in order, without the moves
+// in between; parameters run from 0 to the number of curves, curve i
+// covering the parameters from i to i + 1.
+type PathGeometry struct {
+	Subpaths []Subpath
+}
+
+// Geometry returns the curves of the path with its transform and the
+// transforms of its groups applied. On errors in the path data the
+// curves before the error are returned.
+func (p *Path) Geometry() (*PathGeometry, error) {
+	g, err := ParsePathGeometry(p.D)
+	return g.Transform(p.transform()), err
+}
+
+// sendInstructions sends the drawing instructions of the curves, without
+// a paint instruction.
+func (g *PathGeometry) sendInstructions(out chan *DrawingInstruction) {
+	w := &instructionWriter{instructions: out, transform: mt.Identity()}
+	for _, sp := range g.Subpaths {
+		if len(sp.Curves) == 0 {
+			continue
+		}
+		start := sp.Curves[0].PointAt(0)
+		w.moveTo(start)
+		for _, c := range sp.Curves {
+			w.curveTo(c)
+		}
+		if sp.Closed {
+			w.closePath(start, start)
+		}
+	}
+}
+
+// GeometryFromInstructions returns the curves drawn by drawing
+// instructions. Circles become closed arcs; paint instructions are
+// ignored.
+func GeometryFromInstructions(instrs []*DrawingInstruction) *PathGeometry {
+	g := &PathGeometry{}
+	var start, pos [2]float64
+	current := func() *Subpath {
+		if n := len(g.Subpaths); n == 0 || g.Subpaths[n-1].Closed {
+			g.Subpaths = append(g.Subpaths, Subpath{})
+			start = pos
+		}
+		return &g.Subpaths[len(g.Subpaths)-1]
+	}
+	for _, di := range instrs {
+		switch di.Kind {
+		case MoveInstruction:
+			pos = [2]float64(*di.M)
+			start = pos
+			g.Subpaths = append(g.Subpaths, Subpath{})
+		case LineInstruction:
+			p := [2]float64(*di.M)
+			sp := current()
+			sp.Curves = append(sp.Curves, LineCurve{pos, p})
+			pos = p
+		case CurveInstruction:
+			cp := di.CurvePoints
+			p := [2]float64(*cp.T)
+			sp := current()
+			sp.Curves = append(sp.Curves, CubicCurve{pos, [2]float64(*cp.C1), [2]float64(*cp.C2), p})
+			pos = p
+		case CloseInstruction:
+			if n := len(g.Subpaths); n > 0 && !g.Subpaths[n-1].Closed {
+				sp := &g.Subpaths[n-1]
+				if pos != start {
+					sp.Curves = append(sp.Curves, LineCurve{pos, start})
+				}
+				sp.Closed = true
+			}
+			pos = start
+		case CircleInstruction:
+			c, r := [2]float64(*di.M), *di.Radius
+			g.Subpaths = append(g.Subpaths, Subpath{
+				Curves: []Curve{ArcCurve{Center: c, U: [2]float64{r, 0}, V: [2]float64{0, r}, Sweep: 2 * math.Pi}},
+				Closed: true,
+			})
+			start = [2]float64{c[0] + r, c[1]}
+			pos = start
+		}
+	}
+	// Moves without drawing leave empty subpaths.
+	out := g.Subpaths[:0]
+	for _, sp := range g.Subpaths {
+		if len(sp.Curves) > 0 {
+			out = append(out, sp)
+		}
+	}
+	g.Subpaths = out
+	return g
+}
+
+// Transform returns the geometry mapped by an affine transform.
+func (g *PathGeometry) Transform(m mt.Transform) *PathGeometry {
+	out := &PathGeometry{Subpaths: make([]Subpath, len(g.Subpaths))}
+	for i, sp := range g.Subpaths {
+		out.Subpaths[i].Closed = sp.Closed
+		for _, c := range sp.Curves {
+			out.Subpaths[i].Curves = append(out.Subpaths[i].Curves, c.Transform(m))
+		}
+	}
+	return out
+}
+
+// curves returns the curves of all subpaths in order.
+func (g *PathGeometry) curves() []Curve {
+	var all []Curve
+	for _, sp := range g.Subpaths {
+		all = append(all, sp.Curves...)
+	}
+	return all
+}
+
+// Length returns the total length of the path.
+func (g *PathGeometry) Length() float64 {
+	var l float64
+	for _, c := range g.curves() {
+		l += CurveLength(c, 1)
+	}
+	return l
+}
+
+// locate returns the index of the curve at length s and the parameter
+// on that curve.
+func (g *PathGeometry) locate(s float64) (int, float64) {
+	all := g.curves()
+	if len(all) == 0 {
+		return -1, 0
+	}
+	for i, c := range all {
+		l := CurveLength(c, 1)
+		if s <= l || i == len(all)-1 {
+			return i, CurveParameterAt(c, s)
+		}
+		s -= l
+	}
+	return -1, 0
+}
+
+// at returns the curve and its parameter for a path parameter.
+func (g *PathGeometry) at(t float64) (Curve, float64) {
+	all := g.curves()
+	if len(all) == 0 {
+		return nil, 0
+	}
+	i := int(math.Floor(t))
+	if i < 0 {
+		return all[0], 0
+	}
+	if i >= len(all) {
+		return all[len(all)-1], 1
+	}
+	return all[i], t - float64(i)
+}
+
+// PointAt returns the point at path parameter t.
+func (g *PathGeometry) PointAt(t float64) [2]float64 {
+	c, u := g.at(t)
+	if c == nil {
+		return [2]float64{}
+	}
+	return c.PointAt(u)
+}
+
+// TangentAt returns the unit tangent at path parameter t.
+func (g *PathGeometry) TangentAt(t float64) [2]float64 {
+	c, u := g.at(t)
+	if c == nil {
+		return [2]float64{}
+	}
+	return CurveTangent(c, u)
+}
+
+// NormalAt returns the unit normal at path parameter t, pointing to the
+// left of the direction of the path.
+func (g *PathGeometry) NormalAt(t float64) [2]float64 {
+	d := g.TangentAt(t)
+	return [2]float64{-d[1], d[0]}
+}
+
+// ParameterAtLength returns the path parameter at length s from the
+// start, clamped to the path.
+func (g *PathGeometry) ParameterAtLength(s float64) float64 {
+	i, t := g.locate(s)
+	if i < 0 {
+		return 0
+	}
+	return float64(i) + t
+}
+
+// PointAtLength returns the point at length s from the start.
+func (g *PathGeometry) PointAtLength(s float64) [2]float64 {
+	return g.PointAt(g.ParameterAtLength(s))
+}
+
+// TangentAtLength returns the unit tangent at length s from the start.
+func (g *PathGeometry) TangentAtLength(s float64) [2]float64 {
+	return g.TangentAt(g.ParameterAtLength(s))
+}
+
+// NormalAtLength returns the unit normal at length s from the start,
+// pointing to the left of the direction of the path.
+func (g *PathGeometry) NormalAtLength(s float64) [2]float64 {
+	return g.NormalAt(g.ParameterAtLength(s))
+}
+
+// SplitAtLength divides the path at length s from the start. The
+// subpath that is split is open in both halves.
+func (g *PathGeometry) SplitAtLength(s float64) (*PathGeometry, *PathGeometry) {
+	i, t := g.locate(s)
+	first, second := &PathGeometry{}, &PathGeometry{}
+	if i < 0 {
+		return first, second
+	}
+	for _, sp := range g.Subpaths {
+		if i >= len(sp.Curves) {
+			first.Subpaths = append(first.Subpaths, sp)
+			i -= len(sp.Curves)
+			continue
+		}
+		if i < 0 {
+			second.Subpaths = append(second.Subpaths, sp)
+			continue
+		}
+		a, b := sp.Curves[i].Split(t)
+		head := append(append([]Curve{}, sp.Curves[:i]...), a)
+		tail := append([]Curve{b}, sp.Curves[i+1:]...)
+		first.Subpaths = append(first.Subpaths, Subpath{Curves: head})
+		second.Subpaths = append(second.Subpaths, Subpath{Curves: tail})
+		i = -1
+	}
+	return first, second
+}
+
+// Flatten approximates the subpaths by segments of line segments no
+// further than tolerance from the curves. Zero means DefaultTolerance.
+func (g *PathGeometry) Flatten(tolerance float64) []Segment {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var segments []Segment
+	for _, sp := range g.Subpaths {
+		if len(sp.Curves) == 0 {
+			continue
+		}
+		pts := [][2]float64{sp.Curves[0].PointAt(0)}
+		for _, c := range sp.Curves {
+			pts = flattenCurve(c, tolerance, pts)
+		}
+		segments = append(segments, Segment{Closed: sp.Closed, Points: pts})
+	}
+	return segments
+}
+
+// FlattenCurve approximates a curve by line segments no further than
+// tolerance from it. The returned points start and end on the ends of
+// the curve. Zero tolerance means DefaultTolerance.
+func FlattenCurve(c Curve, tolerance float64) [][2]float64 {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return flattenCurve(c, tolerance, [][2]float64{c.PointAt(0)})
+}
+
+// flattenCurve appends the points approximating c after its start to
+// pts.
+func flattenCurve(c Curve, tolerance float64, pts [][2]float64) [][2]float64 {
+	switch c := c.(type) {
+	case LineCurve:
+		return append(pts, c.P1)
+	case QuadraticCurve:
+		c1 := lerp(c.P0, c.C, 2.0/3)
+		c2 := lerp(c.P1, c.C, 2.0/3)
+		return append(pts, FlattenCubic(c.P0, c1, c2, c.P1, tolerance, 0)[1:]...)
+	case CubicCurve:
+		return append(pts, FlattenCubic(c.P0, c.C1, c.C2, c.P1, tolerance, 0)[1:]...)
+	case ArcCurve:
+		// The semi-major axis of an ellipse is at most the length of two
+		// of its conjugate semi-diameters together.
+		r := math.Sqrt(vdot(c.U, c.U) + vdot(c.V, c.V))
+		step := math.Pi / 2
+		if tolerance < r {
+			step = math.Min(step, 2*math.Acos(1-tolerance/r))
+		}
+		n := int(math.Ceil(math.Abs(c.Sweep) / step))
+		for i := 1; i < n; i++ {
+			pts = append(pts, c.PointAt(float64(i)/float64(n)))
+		}
+		return append(pts, c.PointAt(1))
+	}
+	return flattenParameter(c, 0, 1, tolerance, 0, pts)
+}
+
+// flattenParameter flattens any curve from t0 to t1 by halving the
+// interval until its midpoint is within tolerance of the chord.
+func flattenParameter(c Curve, t0, t1, tolerance float64, depth int, pts [][2]float64) [][2]float64 {
+	a, b, m := c.PointAt(t0), c.PointAt(t1), (t0+t1)/2
+	if depth < maxFlattenDepth && (depth < 2 || vlen(vsub(c.PointAt(m), lerp(a, b, 0.5))) > tolerance) {
+		pts = flattenParameter(c, t0, m, tolerance, depth+1, pts)
+		return flattenParameter(c, m, t1, tolerance, depth+1, pts)
+	}
+	return append(pts, b)
+}
+
+// ParsePathGeometry reads path data into exact curves. All commands of
+// the SVG path syntax are supported. On errors the curves before the
+// error are returned along with it.
+func ParsePathGeometry(d string) (*PathGeometry, error) {
+	b := &geometryBuilder{g: &PathGeometry{}}
+	err := parsePathData(d, b)
+	return b.g, err
+}
+
+// geometryBuilder collects the curves of path data into subpaths.
+type geometryBuilder struct {
+	g  *PathGeometry
+	sp *Subpath
+}
+
+func (b *geometryBuilder) moveTo(p [2]float64) {
+	b.sp = nil
+}
+
+func (b *geometryBuilder) curveTo(c Curve) {
+	if b.sp == nil {
+		b.g.Subpaths = append(b.g.Subpaths, Subpath{})
+		b.sp = &b.g.Subpaths[len(b.g.Subpaths)-1]
+	}
+	b.sp.Curves = append(b.sp.Curves, c)
+}
+
+func (b *geometryBuilder) closePath(from, to [2]float64) {
+	if b.sp != nil {
+		if from != to {
+			b.curveTo(LineCurve{from, to})
+		}
+		b.sp.Closed = true
+	}
+	b.sp = nil
+}
+
+// endpointArc converts an arc in the endpoint notation of the path data
+// to centre notation, following the implementation notes of the SVG
+// specification. It returns a line for zero radii and nil when the end
+// points coincide.
+func endpointArc(p0, p1 [2]float64, rx, ry, phi float64, large, sweep bool) Curve {
+	if p0 == p1 {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return LineCurve{p0, p1}
+	}
+	sin, cos := math.Sincos(phi * math.Pi / 180)
+	dx, dy := (p0[0]-p1[0])/2, (p0[1]-p1[1])/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+
+	// Scale radii up that are too small to reach the end point.
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		k = -k
+	}
+	cx1, cy1 := k*rx*y1/ry, -k*ry*x1/rx
+	center := [2]float64{
+		cos*cx1 - sin*cy1 + (p0[0]+p1[0])/2,
+		sin*cx1 + cos*cy1 + (p0[1]+p1[1])/2,
+	}
+
+	start := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	end := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := end - start
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	return ArcCurve{
+		Center: center,
+		U:      [2]float64{rx * cos, rx * sin},
+		V:      [2]float64{-ry * sin, ry * cos},
+		Start:  start,
+		Sweep:  delta,
+	}
+}
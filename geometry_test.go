@@ -0,0 +1,257 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseGeometry(t *testing.T, d string) *PathGeometry {
+	g, err := ParsePathGeometry(d)
+	require.NoError(t, err)
+	return g
+}
+
+func requirePoint(t *testing.T, expected, actual [2]float64, delta float64) {
+	require.InDelta(t, expected[0], actual[0], delta)
+	require.InDelta(t, expected[1], actual[1], delta)
+}
+
+func TestPathGeometryLength(t *testing.T) {
+	// The exact length of the quadratic from (0,0) over (50,100) to
+	// (100,0).
+	quad := 50*math.Sqrt(5) + 25*math.Asinh(2)
+	// Ramanujan's approximation of the perimeter of an ellipse with
+	// radii 10 and 5.
+	ellipse := math.Pi * (45 - math.Sqrt(35*25))
+
+	for _, tc := range []struct {
+		d      string
+		length float64
+	}{
+		{"M0 0 L30 40", 50},
+		{"M0 0 h10 v10 h-10 z", 40},
+		{"M10,10 20,10 20,20", 20},
+		{"M0 0 Q50 100 100 0", quad},
+		{"M0 0 q50 100 100 0 t100 0", 2 * quad},
+		{"M0 0 C0 0 10 10 10 10", math.Sqrt(200)},
+		{"M10 0 A10 10 0 0 1 -10 0 A10 10 0 0 1 10 0Z", 20 * math.Pi},
+		{"M10 0 a10 5 0 1 1 0 .001", ellipse}, // close to the full ellipse
+		{"M0 0 A10 10 0 0 1 100 0", 50 * math.Pi},
+		{"M0 0 A0 10 0 0 1 10 0", 10},
+		{"M1-2L1e1-2", 9},
+	} {
+		g := parseGeometry(t, tc.d)
+		delta := 1e-7
+		if tc.d == "M10 0 a10 5 0 1 1 0 .001" {
+			delta = 0.01
+		}
+		require.InDelta(t, tc.length, g.Length(), delta, tc.d)
+	}
+
+	// The length of a cubic converges to the one of fine flattenings.
+	g := parseGeometry(t, "M0 0 C10 40 90 -40 100 0")
+	pts := FlattenCubic([2]float64{0, 0}, [2]float64{10, 40}, [2]float64{90, -40}, [2]float64{100, 0}, 1e-6, 0)
+	var flat float64
+	for i := 1; i < len(pts); i++ {
+		flat += vlen(vsub(pts[i], pts[i-1]))
+	}
+	require.InDelta(t, flat, g.Length(), 1e-4)
+
+	for _, d := range []string{"L0 0", "M0 0 L1", "M0 0 A1 1 0 2 0 1 1", "M0 0 Z 1 1"} {
+		_, err := ParsePathGeometry(d)
+		require.Error(t, err, d)
+	}
+}
+
+func TestPathGeometryAtLength(t *testing.T) {
+	// A circle of radius 10 around the origin, running towards positive
+	// angles as the sweep flag is 1.
+	g := parseGeometry(t, "M10 0 A10 10 0 0 1 -10 0 A10 10 0 0 1 10 0")
+	total := g.Length()
+	for i := 0; i <= 16; i++ {
+		s := total * float64(i) / 16
+		a := s / 10
+		requirePoint(t, [2]float64{10 * math.Cos(a), 10 * math.Sin(a)}, g.PointAtLength(s), 1e-7)
+		requirePoint(t, [2]float64{-math.Sin(a), math.Cos(a)}, g.TangentAtLength(s), 1e-7)
+		requirePoint(t, [2]float64{-math.Cos(a), -math.Sin(a)}, g.NormalAtLength(s), 1e-7)
+	}
+	requirePoint(t, [2]float64{10, 0}, g.PointAtLength(-5), 0)
+	requirePoint(t, [2]float64{10, 0}, g.PointAtLength(total+5), 1e-9)
+
+	// Points at equal lengths on a cubic are equally far apart along
+	// the curve, not in the parameter.
+	g = parseGeometry(t, "M0 0 C0 0 0 0 100 0")
+	requirePoint(t, [2]float64{25, 0}, g.PointAtLength(25), 1e-7)
+	requirePoint(t, [2]float64{1, 0}, g.TangentAtLength(0), 1e-9)
+	require.Less(t, g.ParameterAtLength(25), 0.9)
+	require.Greater(t, g.ParameterAtLength(25), 0.25)
+
+	// Path parameters count curves, lengths skip the moves between
+	// subpaths.
+	g = parseGeometry(t, "M0 0 H10 M0 10 V30")
+	requirePoint(t, [2]float64{5, 0}, g.PointAt(0.5), 0)
+	requirePoint(t, [2]float64{0, 20}, g.PointAt(1.5), 0)
+	requirePoint(t, [2]float64{0, 15}, g.PointAtLength(15), 1e-9)
+	requirePoint(t, [2]float64{0, 1}, g.TangentAt(1.5), 0)
+	require.InDelta(t, 1.25, g.ParameterAtLength(15), 1e-9)
+}
+
+func TestPathGeometrySplitAtLength(t *testing.T) {
+	g := parseGeometry(t, "M0 0 Q50 100 100 0 A50 50 0 0 1 0 0 Z M200 0 h10")
+	total := g.Length()
+	for _, s := range []float64{0, 30, 100, 200, total - 10, total} {
+		a, b := g.SplitAtLength(s)
+		require.InDelta(t, s, a.Length(), 1e-6)
+		require.InDelta(t, total-s, b.Length(), 1e-6)
+		require.Equal(t, len(g.Subpaths), len(a.Subpaths)+len(b.Subpaths)-1)
+		if len(a.Subpaths) > 0 && len(b.Subpaths) > 0 {
+			last := a.Subpaths[len(a.Subpaths)-1]
+			requirePoint(t, g.PointAtLength(s), last.Curves[len(last.Curves)-1].PointAt(1), 1e-9)
+			requirePoint(t, g.PointAtLength(s), b.Subpaths[0].Curves[0].PointAt(0), 1e-9)
+		}
+	}
+
+	// The closed subpath opens where it is split.
+	a, b := g.SplitAtLength(50)
+	require.Len(t, a.Subpaths, 1)
+	require.False(t, a.Subpaths[0].Closed)
+	require.Len(t, b.Subpaths, 2)
+	require.False(t, b.Subpaths[0].Closed)
+	require.Len(t, b.Subpaths[0].Curves, 2)
+}
+
+func TestPathGeometryTransform(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<g transform="scale(2)">
+			<path d="M0 0 a10 10 0 0 0 20 0" transform="translate(5 0)"/>
+		</g>
+	</svg>`, "geometry", 1)
+	require.NoError(t, err)
+	g, err := s.Groups[0].Elements[0].(*Path).Geometry()
+	require.NoError(t, err)
+	require.InDelta(t, 20*math.Pi, g.Length(), 1e-7)
+	requirePoint(t, [2]float64{10, 0}, g.PointAtLength(0), 1e-9)
+	requirePoint(t, [2]float64{30, 20}, g.PointAtLength(10*math.Pi), 1e-7)
+}
+
+func TestPathQuadraticAndArc(t *testing.T) {
+	// Geometry and drawing instructions read the same path data.
+	s, err := ParseSvg(`<svg viewBox="0 0 200 100">
+		<g transform="translate(10 0)">
+			<path d="M0 0 Q50 100 100 0 A50 50 0 0 1 0 0 Z"/>
+		</g>
+	</svg>`, "parser", 1)
+	require.NoError(t, err)
+	p := s.Groups[0].Elements[0].(*Path)
+
+	g, err := p.Geometry()
+	require.NoError(t, err)
+	instrs, errs := p.ParseDrawingInstructions()
+	var all []*DrawingInstruction
+	for di := range instrs {
+		all = append(all, di)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	var kinds []InstructionType
+	for _, di := range all {
+		kinds = append(kinds, di.Kind)
+	}
+	require.Equal(t, []InstructionType{MoveInstruction, CurveInstruction, CurveInstruction, CurveInstruction,
+		CloseInstruction, PaintInstruction}, kinds)
+	requirePoint(t, [2]float64{110, 0}, [2]float64(*all[1].CurvePoints.T), 1e-12)
+	requirePoint(t, [2]float64{60, 50}, [2]float64(*all[2].CurvePoints.T), 1e-12)
+	require.InEpsilon(t, g.Length(), GeometryFromInstructions(all).Length(), 3e-4)
+
+	segs, errs := p.ParseSegments()
+	box := emptyBox
+	for seg := range segs {
+		for _, pt := range seg.Points {
+			box = box.extend(pt)
+		}
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	b := g.Bounds()
+	require.InDelta(t, b.MinX, box.MinX, DefaultTolerance)
+	require.InDelta(t, b.MaxX, box.MaxX, DefaultTolerance)
+	require.InDelta(t, b.MaxY, box.MaxY, DefaultTolerance)
+}
+
+func TestGeometryFromInstructions(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<path d="M10 10 H30 V20 H10 Z"/>
+		<circle cx="50" cy="50" r="5"/>
+	</svg>`, "instructions", 1)
+	require.NoError(t, err)
+	var geoms []*PathGeometry
+	s.VisitElements(func(e DrawingInstructionParser, _ *Group) {
+		dis, errs := e.ParseDrawingInstructions()
+		var instrs []*DrawingInstruction
+		go func() {
+			for range errs {
+			}
+		}()
+		for di := range dis {
+			instrs = append(instrs, di)
+		}
+		geoms = append(geoms, GeometryFromInstructions(instrs))
+	})
+	require.Len(t, geoms, 2)
+
+	square := geoms[0]
+	require.Len(t, square.Subpaths, 1)
+	require.True(t, square.Subpaths[0].Closed)
+	require.InDelta(t, 60, square.Length(), 1e-9)
+
+	circle := geoms[1]
+	require.Len(t, circle.Subpaths, 1)
+	arc, ok := circle.Subpaths[0].Curves[0].(ArcCurve)
+	require.True(t, ok)
+	require.Equal(t, [2]float64{50, 50}, arc.Center)
+	require.InDelta(t, 2*math.Pi*5, circle.Length(), 1e-6)
+}
+
+func TestShapeInstructions(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<g transform="translate(10 0)">
+			<rect x="0" y="0" width="20" height="10" fill="red"/>
+			<circle cx="0" cy="0" r="5" transform="scale(2)"/>
+			<circle cx="0" cy="0" r="5" transform="scale(2 1)"/>
+		</g>
+	</svg>`, "shapes", 1)
+	require.NoError(t, err)
+	collect := func(e DrawingInstructionParser) []*DrawingInstruction {
+		instrs, errs := e.ParseDrawingInstructions()
+		var all []*DrawingInstruction
+		for di := range instrs {
+			all = append(all, di)
+		}
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		return all
+	}
+	elements := s.Groups[0].Elements
+
+	rect := collect(elements[0])
+	require.Len(t, rect, 7)
+	require.Equal(t, Tuple{10, 0}, *rect[0].M)
+	require.Equal(t, Tuple{30, 10}, *rect[2].M)
+	require.Equal(t, CloseInstruction, rect[5].Kind)
+	require.Equal(t, "red", *rect[6].Fill)
+
+	// A uniform scale keeps the circle a circle.
+	circle := collect(elements[1])
+	require.Len(t, circle, 2)
+	require.Equal(t, CircleInstruction, circle[0].Kind)
+	require.Equal(t, Tuple{10, 0}, *circle[0].M)
+	require.Equal(t, 10.0, *circle[0].Radius)
+
+	ellipse := GeometryFromInstructions(collect(elements[2]))
+	requireBox(t, Box{0, -5, 20, 5}, ellipse.Bounds(), 1e-2)
+}
@@ -0,0 +1,148 @@
+package svg
+
+import (
+	"math"
+	"sort"
+)
+
+// HatchPattern selects the lines Hatch fills shapes with.
+type HatchPattern int
+
+// Patterns of hatch lines.
+const (
+	// Parallel lines run at the hatch angle.
+	Parallel HatchPattern = iota
+	// Crosshatch adds lines at right angles to the parallel lines.
+	Crosshatch
+	// Concentric follows the outline of the shape, shrinking it by the
+	// spacing each time.
+	Concentric
+)
+
+// HatchOptions control the lines Hatch fills shapes with.
+type HatchOptions struct {
+	Pattern HatchPattern
+	// Spacing is the distance between neighbouring lines.
+	Spacing float64
+	// Angle is the direction of parallel lines in degrees,
+	// counter-clockwise from the positive x axis.
+	Angle float64
+	// FillRule decides which parts of the shapes are inside.
+	FillRule FillRule
+}
+
+// Hatch returns lines filling the area of the segments, for pen
+// plotters and lasers that cannot fill. Segments are treated as closed
+// and holes are told apart by the fill rule.
+//
+// Parallel lines lie on a grid through the origin so that the hatching
+// of neighbouring shapes lines up, and run back and forth to keep the
+// travel between them short. Concentric outlines start half the spacing
+// inside the shape, as do the pens drawing them. The result is empty if
+// the spacing is not positive.
+func Hatch(segments []Segment, opts HatchOptions) []Segment {
+	if opts.Spacing <= 0 {
+		return nil
+	}
+	switch opts.Pattern {
+	case Crosshatch:
+		return append(hatchLines(segments, opts.Angle, opts.Spacing, opts.FillRule),
+			hatchLines(segments, opts.Angle+90, opts.Spacing, opts.FillRule)...)
+	case Concentric:
+		var out []Segment
+		for d := opts.Spacing / 2; ; d += opts.Spacing {
+			rings := Offset(segments, -d, OffsetOptions{FillRule: opts.FillRule})
+			if len(rings) == 0 {
+				return out
+			}
+			out = append(out, rings...)
+		}
+	}
+	return hatchLines(segments, opts.Angle, opts.Spacing, opts.FillRule)
+}
+
+// hatchLines returns parallel lines at the given angle in degrees
+// clipped to the area of the segments.
+func hatchLines(segments []Segment, angle, spacing float64, rule FillRule) []Segment {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	// Keep lines along the axes exact, so that they fall on the grid
+	// in the same way as the edges they are parallel to.
+	sin, cos = math.Round(sin*1e12)/1e12, math.Round(cos*1e12)/1e12
+	// Rotate the shapes so that the lines are horizontal.
+	toLine := func(p [2]float64) [2]float64 {
+		return [2]float64{p[0]*cos + p[1]*sin, -p[0]*sin + p[1]*cos}
+	}
+	fromLine := func(p [2]float64) [2]float64 {
+		return [2]float64{p[0]*cos - p[1]*sin, p[0]*sin + p[1]*cos}
+	}
+
+	type edge struct{ a, b [2]float64 }
+	var edges []edge
+	box := emptyBox
+	for _, s := range segments {
+		n := len(s.Points)
+		if n < 3 {
+			continue
+		}
+		for i := range s.Points {
+			a, b := toLine(s.Points[i]), toLine(s.Points[(i+1)%n])
+			if a[1] != b[1] {
+				edges = append(edges, edge{a, b})
+			}
+			box = box.extend(a)
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	type crossing struct {
+		x       float64
+		winding int
+	}
+	var out []Segment
+	var crossings []crossing
+	for k := math.Ceil(box.MinY / spacing); k*spacing <= box.MaxY; k++ {
+		y := k * spacing
+		crossings = crossings[:0]
+		for _, e := range edges {
+			if (e.a[1] > y) == (e.b[1] > y) {
+				continue
+			}
+			x := e.a[0] + (y-e.a[1])/(e.b[1]-e.a[1])*(e.b[0]-e.a[0])
+			w := 1
+			if e.b[1] < e.a[1] {
+				w = -1
+			}
+			crossings = append(crossings, crossing{x, w})
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		var spans [][2]float64
+		var w int
+		for i, c := range crossings {
+			w += c.winding
+			if i+1 < len(crossings) && rule.inside(w) && crossings[i+1].x > c.x {
+				if n := len(spans); n > 0 && spans[n-1][1] == c.x {
+					spans[n-1][1] = crossings[i+1].x
+				} else {
+					spans = append(spans, [2]float64{c.x, crossings[i+1].x})
+				}
+			}
+		}
+		// Every other line runs backwards.
+		backwards := int64(k)%2 != 0
+		for i := range spans {
+			span := spans[i]
+			if backwards {
+				span = spans[len(spans)-1-i]
+				span[0], span[1] = span[1], span[0]
+			}
+			out = append(out, Segment{Points: [][2]float64{
+				fromLine([2]float64{span[0], y}),
+				fromLine([2]float64{span[1], y}),
+			}})
+		}
+	}
+	return out
+}
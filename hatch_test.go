@@ -0,0 +1,76 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hatchLength(segments []Segment) float64 {
+	var l float64
+	for _, s := range segments {
+		for i := 1; i < len(s.Points); i++ {
+			l += vlen(vsub(s.Points[i], s.Points[i-1]))
+		}
+	}
+	return l
+}
+
+func TestHatch(t *testing.T) {
+	outer := square(0, 0, 10)
+	hole := square(4, 4, 2)
+	shape := []Segment{outer, hole}
+
+	lines := Hatch(shape, HatchOptions{Spacing: 1, FillRule: EvenOdd})
+	// Ten lines, the ones through the hole in two pieces.
+	require.Len(t, lines, 12)
+	require.InDelta(t, 96, hatchLength(lines), 1e-9)
+	require.Equal(t, [][2]float64{{0, 0}, {10, 0}}, lines[0].Points)
+	require.Equal(t, [][2]float64{{10, 1}, {0, 1}}, lines[1].Points)
+	require.Equal(t, [][2]float64{{0, 4}, {4, 4}}, lines[4].Points)
+	require.Equal(t, [][2]float64{{6, 4}, {10, 4}}, lines[5].Points)
+
+	// Wound the same way as the outline, the hole is filled by the
+	// nonzero rule.
+	require.Len(t, Hatch(shape, HatchOptions{Spacing: 1}), 10)
+	reversedHole := Segment{Closed: true, Points: reversed(hole.Points)}
+	require.Len(t, Hatch([]Segment{outer, reversedHole}, HatchOptions{Spacing: 1}), 12)
+
+	// Lines at an angle stay inside the shape.
+	diagonal := Hatch(shape, HatchOptions{Spacing: 0.5, Angle: 30, FillRule: EvenOdd})
+	tol := 1e-9
+	for _, s := range diagonal {
+		require.Len(t, s.Points, 2)
+		for _, p := range s.Points {
+			require.LessOrEqual(t, distanceToSegments(shape, p), tol)
+		}
+		mid := lerp(s.Points[0], s.Points[1], 0.5)
+		require.True(t, EvenOdd.inside(windingNumber(shape, mid)))
+		d := vsub(s.Points[1], s.Points[0])
+		require.InDelta(t, 0, math.Abs(vcross(d, [2]float64{math.Cos(math.Pi / 6), math.Sin(math.Pi / 6)})), 1e-9)
+	}
+	// The lines cover the area about once per spacing.
+	require.InDelta(t, 96/0.5, hatchLength(diagonal), 5)
+
+	cross := Hatch(shape, HatchOptions{Pattern: Crosshatch, Spacing: 1, FillRule: EvenOdd})
+	require.Len(t, cross, 24)
+	require.InDelta(t, 192, hatchLength(cross), 1e-9)
+
+	require.Empty(t, Hatch(shape, HatchOptions{}))
+}
+
+func TestHatchConcentric(t *testing.T) {
+	rings := Hatch([]Segment{square(0, 0, 10)}, HatchOptions{Pattern: Concentric, Spacing: 2})
+	require.Len(t, rings, 2)
+	require.Equal(t, Box{1, 1, 9, 9}, pointsBox(rings[0].Points))
+	require.Equal(t, Box{3, 3, 7, 7}, pointsBox(rings[1].Points))
+
+	// Holes grow as the outline shrinks.
+	rings = Hatch([]Segment{square(0, 0, 10), square(4, 4, 2)}, HatchOptions{Pattern: Concentric, Spacing: 1, FillRule: EvenOdd})
+	require.Len(t, rings, 4)
+	for i, b := range []Box{{0.5, 0.5, 9.5, 9.5}, {3.5, 3.5, 6.5, 6.5}, {1.5, 1.5, 8.5, 8.5}, {2.5, 2.5, 7.5, 7.5}} {
+		require.True(t, rings[i].Closed)
+		requireBox(t, b, pointsBox(rings[i].Points), 1e-9)
+	}
+}
@@ -0,0 +1,203 @@
+package svg
+
+import (
+	"math"
+	"strings"
+)
+
+// hitTolerance returns the precision to flatten curves with for hit
+// testing shapes within box.
+func hitTolerance(b Box) float64 {
+	return math.Max(math.Max(b.Width(), b.Height())*1e-6, 1e-9)
+}
+
+// windingNumber returns how often the segments, closed implicitly, wind
+// around p. Counter-clockwise loops count positive.
+func windingNumber(segments []Segment, p [2]float64) int {
+	var w int
+	for _, s := range segments {
+		n := len(s.Points)
+		for i := range s.Points {
+			a, b := s.Points[i], s.Points[(i+1)%n]
+			if (a[1] > p[1]) == (b[1] > p[1]) {
+				continue
+			}
+			side := vcross(vsub(b, a), vsub(p, a))
+			if b[1] > a[1] && side > 0 {
+				w++
+			} else if b[1] <= a[1] && side < 0 {
+				w--
+			}
+		}
+	}
+	return w
+}
+
+// distanceToSegments returns the distance from p to the closest line
+// segment of the segments, including the closing ones of closed
+// segments.
+func distanceToSegments(segments []Segment, p [2]float64) float64 {
+	best := math.Inf(1)
+	for _, s := range segments {
+		n := len(s.Points)
+		for i := range s.Points {
+			j := i + 1
+			if j == n {
+				if !s.Closed && n > 1 {
+					break
+				}
+				j = 0
+			}
+			best = math.Min(best, distanceToSegment(p, s.Points[i], s.Points[j]))
+		}
+	}
+	return best
+}
+
+// filled reports whether a paint instruction has a fill paint. Elements
+// fill black unless the fill is none.
+func filled(paint *DrawingInstruction) bool {
+	return paint.Fill == nil || strings.TrimSpace(*paint.Fill) != "none"
+}
+
+// stroked reports whether a paint instruction has a stroke paint.
+// Elements are not stroked unless a stroke is set.
+func stroked(paint *DrawingInstruction) bool {
+	if paint.Stroke == nil {
+		return false
+	}
+	s := strings.TrimSpace(*paint.Stroke)
+	return s != "" && s != "none"
+}
+
+// scaledStrokeWidth returns the stroke width of the path as given in
+// its drawing instructions.
+func (p *Path) scaledStrokeWidth() float64 {
+	p.parseStyle()
+	if p.group != nil && p.group.Owner != nil {
+		return p.strokeWidth() * p.group.Owner.scale
+	}
+	return p.strokeWidth()
+}
+
+// shapeOutlines returns the outlines of the stroke of the curves drawn
+// with a paint, with curves and round joins accurate to a thousandth of
+// the stroke width.
+func shapeOutlines(g *PathGeometry, paint *DrawingInstruction) []Segment {
+	if g == nil || paint.StrokeWidth == nil || *paint.StrokeWidth <= 0 {
+		return nil
+	}
+	width := *paint.StrokeWidth
+	tolerance := width / 1000
+	var outlines []Segment
+	for _, s := range g.Flatten(tolerance) {
+		s.Width = width
+		if v := paint.StrokeLineCap; v != nil {
+			s.LineCap = strings.TrimSpace(*v)
+		}
+		if v := paint.StrokeLineJoin; v != nil {
+			s.LineJoin = strings.TrimSpace(*v)
+		}
+		if v := paint.StrokeMiterLimit; v != nil {
+			s.MiterLimit = *v
+		}
+		outlines = append(outlines, s.strokeOutline(tolerance)...)
+	}
+	return outlines
+}
+
+// fillContains reports whether the point x, y lies in the area a shape
+// fills, honouring its fill-rule.
+func fillContains(s shape, x, y float64) bool {
+	g, _ := s.Geometry()
+	if g == nil {
+		return false
+	}
+	b := g.Bounds()
+	if b.Empty() || x < b.MinX || x > b.MaxX || y < b.MinY || y > b.MaxY {
+		return false
+	}
+	rule := NonZero
+	if r := s.Paint().FillRule; r != nil {
+		rule = ParseFillRule(strings.TrimSpace(*r))
+	}
+	return rule.inside(windingNumber(g.Flatten(hitTolerance(b)), [2]float64{x, y}))
+}
+
+// strokeContains reports whether the point x, y lies on the stroke of a
+// shape or no further than tolerance from it.
+func strokeContains(s shape, x, y, tolerance float64) bool {
+	g, _ := s.Geometry()
+	outlines := shapeOutlines(g, s.Paint())
+	pt := [2]float64{x, y}
+	return NonZero.inside(windingNumber(outlines, pt)) ||
+		(len(outlines) > 0 && distanceToSegments(outlines, pt) <= tolerance)
+}
+
+// Contains reports whether the point x, y lies in the area the path
+// fills, after its transforms, honouring its fill-rule. Open subpaths
+// are closed as for filling. Whether the path has a fill paint is not
+// taken into account.
+func (p *Path) Contains(x, y float64) bool {
+	return fillContains(p, x, y)
+}
+
+// StrokeContains reports whether the point x, y lies on the stroke of
+// the path, with its width, caps and joins, or no further than
+// tolerance from it. Whether the path has a stroke paint is not taken
+// into account.
+func (p *Path) StrokeContains(x, y, tolerance float64) bool {
+	return strokeContains(p, x, y, tolerance)
+}
+
+// Contains reports whether the point x, y lies inside the circle, after
+// its transforms. See Path.Contains.
+func (c *Circle) Contains(x, y float64) bool {
+	return fillContains(c, x, y)
+}
+
+// StrokeContains reports whether the point x, y lies on the stroke of
+// the circle, after its transforms. See Path.StrokeContains.
+func (c *Circle) StrokeContains(x, y, tolerance float64) bool {
+	return strokeContains(c, x, y, tolerance)
+}
+
+// Contains reports whether the point x, y lies inside the rect, after
+// its transforms. See Path.Contains.
+func (r *Rect) Contains(x, y float64) bool {
+	return fillContains(r, x, y)
+}
+
+// StrokeContains reports whether the point x, y lies on the stroke of
+// the rect, after its transforms. See Path.StrokeContains.
+func (r *Rect) StrokeContains(x, y, tolerance float64) bool {
+	return strokeContains(r, x, y, tolerance)
+}
+
+// hit reports whether an element paints the point x, y: its fill if it
+// has a fill paint or its stroke if it has a stroke paint.
+func hit(e DrawingInstructionParser, x, y float64) bool {
+	s, ok := e.(shape)
+	if !ok {
+		return false
+	}
+	paint := s.Paint()
+	return (filled(paint) && fillContains(s, x, y)) || (stroked(paint) && strokeContains(s, x, y, 0))
+}
+
+// ElementsAt returns the elements painting the point x, y of the
+// document, the topmost first. Elements that ParseDrawingInstructions
+// leaves out, such as hidden ones, are never returned, and groups are
+// descended into rather than returned themselves.
+func (s *Svg) ElementsAt(x, y float64) []DrawingInstructionParser {
+	var hits []DrawingInstructionParser
+	s.VisitElements(func(e DrawingInstructionParser, _ *Group) {
+		if hit(e, x, y) {
+			hits = append(hits, e)
+		}
+	})
+	for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+		hits[i], hits[j] = hits[j], hits[i]
+	}
+	return hits
+}
@@ -0,0 +1,105 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainsFillRule(t *testing.T) {
+	// Two squares wound the same way, one inside the other.
+	d := "M0 0 H30 V30 H0 Z M10 10 H20 V20 H10 Z"
+	nonzero := &Path{D: d}
+	evenodd := &Path{D: d, FillRule: strPtr("evenodd")}
+	styled := &Path{D: d, Style: "fill-rule: evenodd"}
+
+	require.True(t, nonzero.Contains(5, 5))
+	require.True(t, nonzero.Contains(15, 15))
+	require.True(t, evenodd.Contains(5, 5))
+	require.False(t, evenodd.Contains(15, 15))
+	require.False(t, styled.Contains(15, 15))
+	require.False(t, nonzero.Contains(35, 15))
+
+	// Curves are tested exactly rather than on their control polygon,
+	// and open subpaths are closed for filling.
+	arc := &Path{D: "M0 0 A10 10 0 0 0 20 0"}
+	require.True(t, arc.Contains(10, 9.99))
+	require.False(t, arc.Contains(10, 10.01))
+	require.False(t, arc.Contains(10, -0.01))
+	quad := &Path{D: "M0 0 Q10 20 20 0"}
+	require.True(t, quad.Contains(10, 9.99))
+	require.False(t, quad.Contains(10, 10.01))
+}
+
+func TestStrokeContains(t *testing.T) {
+	p := &Path{D: "M0 0 H10", StrokeWidth: 2}
+	require.True(t, p.StrokeContains(5, 0.99, 0))
+	require.False(t, p.StrokeContains(5, 1.01, 0))
+	require.True(t, p.StrokeContains(5, 1.5, 0.5))
+	require.False(t, p.StrokeContains(10.5, 0, 0))
+
+	p.StrokeLineCap = strPtr("round")
+	require.True(t, p.StrokeContains(10.5, 0.5, 0))
+	require.False(t, p.StrokeContains(10.8, 0.8, 0))
+
+	// A closed stroke is a ring, not a disc.
+	ring := &Path{D: "M0 0 H10 V10 H0 Z", StrokeWidth: 2}
+	require.True(t, ring.StrokeContains(5, 10.5, 0))
+	require.False(t, ring.StrokeContains(5, 5, 0))
+
+	// Circles and rects are stroked with their width, after their
+	// transforms.
+	c := &Circle{Cx: 5, Cy: 5, Radius: 5}
+	require.True(t, c.Contains(5, 9))
+	require.False(t, c.StrokeContains(5, 8.9, 0.5))
+	require.True(t, c.StrokeContains(5, 10.2, 0.5))
+	c.Style = "stroke-width:4"
+	c.Transform = "scale(2 1)"
+	require.True(t, c.Contains(18, 5))
+	require.False(t, c.Contains(2, 9))
+	require.True(t, c.StrokeContains(21.9, 5, 0))
+	require.False(t, c.StrokeContains(22.1, 5, 0))
+
+	r := &Rect{X: "0", Y: "0", Width: "10", Height: "10", Transform: "translate(5 0)", Style: "stroke-width:2"}
+	require.True(t, r.Contains(14, 5))
+	require.False(t, r.Contains(4, 5))
+	require.True(t, r.StrokeContains(15.9, 5, 0))
+	require.False(t, r.StrokeContains(10, 5, 0))
+}
+
+func TestElementsAt(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<path id="bottom" d="M0 0 H50 V50 H0 Z"/>
+		<g transform="translate(20 20)">
+			<path id="middle" d="M0 0 H50 V50 H0 Z" fill="none" stroke="red" stroke-width="4"/>
+			<path id="hidden" d="M0 0 H50 V50 H0 Z" visibility="hidden"/>
+		</g>
+		<circle id="top" cx="20" cy="20" r="5"/>
+		<rect id="corner" x="90" y="90" width="10" height="10" transform="translate(-10 0)" fill="none" stroke="blue"/>
+	</svg>`, "hit", 1)
+	require.NoError(t, err)
+
+	ids := func(x, y float64) []string {
+		var out []string
+		for _, e := range s.ElementsAt(x, y) {
+			switch e := e.(type) {
+			case *Path:
+				out = append(out, e.ID)
+			case *Circle:
+				out = append(out, e.ID)
+			case *Rect:
+				out = append(out, e.ID)
+			}
+		}
+		return out
+	}
+
+	require.Equal(t, []string{"top", "middle", "bottom"}, ids(20, 20))
+	require.Equal(t, []string{"top", "bottom"}, ids(23, 23))
+	// The unfilled path is only hit on its stroke.
+	require.Equal(t, []string{"bottom"}, ids(30, 30))
+	require.Equal(t, []string{"middle"}, ids(70, 45))
+	require.Empty(t, ids(95, 95))
+	require.Empty(t, ids(85, 95))
+	require.Equal(t, []string{"corner"}, ids(90, 95))
+}
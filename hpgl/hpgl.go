@@ -0,0 +1,262 @@
+// Package hpgl writes flattened SVG documents as HPGL for HP-compatible
+// pen plotters. Every segment is drawn along its points with a pen
+// chosen by its stroke colour; fills are not produced.
+package hpgl
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+)
+
+// UnitsPerMillimeter is the number of plotter units in a millimetre.
+const UnitsPerMillimeter = 40
+
+// DefaultTolerance is the default maximum distance in millimetres
+// between a curve and the lines it is drawn with, one plotter unit.
+const DefaultTolerance = 1.0 / UnitsPerMillimeter
+
+// Options control the written plot. The zero value draws everything
+// with pen 1 at the speed set on the plotter.
+type Options struct {
+	// Transform maps the points of the segments to millimetres. For
+	// Write it maps the user units of the document, and nil means the
+	// physical size of the document with the y axis up, as plotters
+	// have it. Otherwise nil means the identity.
+	Transform *mt.Transform
+	// Tolerance is the maximum distance in millimetres between a curve
+	// and the lines it is drawn with by Write. Zero means
+	// DefaultTolerance.
+	Tolerance float64
+
+	// Pen is the pen of elements whose stroke is not listed in Pens,
+	// and of segments plotted by WriteSegments without SegmentPen. Zero
+	// means pen 1.
+	Pen int
+	// Pens holds pen numbers by stroke colour, in any form accepted by
+	// svg.ParseColor.
+	Pens map[string]int
+	// SegmentPen returns the pen of a segment plotted by WriteSegments,
+	// as segments carry no colour. A result of zero means Pen.
+	SegmentPen func(svg.Segment) int
+	// Velocity is the speed of the pen down in centimetres per second,
+	// written with VS after selecting a pen. Zero leaves the speed of
+	// the plotter unchanged.
+	Velocity float64
+	// Velocities holds speeds by pen number, overriding Velocity.
+	Velocities map[int]float64
+}
+
+// Write writes a plot of the elements of the document that would be
+// drawn by ParseDrawingInstructions, in paint order. Elements are
+// flattened into segments from their exact geometry and drawn with the
+// pen of their stroke colour. Errors in elements are returned once the
+// rest of the document is written.
+func Write(w io.Writer, s *svg.Svg, opts Options) error {
+	tolerance := opts.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var t mt.Transform
+	if opts.Transform != nil {
+		t = *opts.Transform
+	} else {
+		_, height, _ := s.PhysicalSize()
+		t = mt.Identity()
+		t.Scale(1, -1)
+		t.Translate(0, -height)
+		t = mt.MultiplyTransforms(t, s.MillimeterTransform())
+	}
+	opts.Transform = nil
+	p := newPlotter(w, opts)
+	p.begin()
+
+	var first error
+	s.VisitElements(func(e svg.DrawingInstructionParser, _ *svg.Group) {
+		var geometry *svg.PathGeometry
+		var paint *svg.DrawingInstruction
+		if path, ok := e.(*svg.Path); ok {
+			pg, err := path.Geometry()
+			if err != nil && first == nil {
+				first = err
+			}
+			geometry, paint = pg, path.Paint()
+		} else {
+			instrs, err := svg.CollectInstructions(e)
+			if err != nil && first == nil {
+				first = err
+			}
+			for _, di := range instrs {
+				if di.Kind == svg.PaintInstruction {
+					paint = di
+				}
+			}
+			geometry = svg.GeometryFromInstructions(instrs)
+		}
+		pen := p.penFor(paint)
+		for _, seg := range geometry.Transform(t).Flatten(tolerance) {
+			p.draw(seg, pen)
+		}
+	})
+	if err := p.end(); err != nil {
+		return err
+	}
+	return first
+}
+
+// WriteSegments writes a plot of the segments received from the
+// channel, in the order received, with the pens chosen by
+// opts.SegmentPen. Pens are changed only when the next segment needs
+// another one, so segments ordered by pen plot faster.
+func WriteSegments(w io.Writer, segments chan svg.Segment, opts Options) error {
+	p := newPlotter(w, opts)
+	p.begin()
+	for seg := range segments {
+		pen := 0
+		if opts.SegmentPen != nil {
+			pen = opts.SegmentPen(seg)
+		}
+		if pen <= 0 {
+			pen = p.opts.Pen
+		}
+		p.draw(seg, pen)
+	}
+	return p.end()
+}
+
+// plotter writes HPGL, keeping track of the selected pen and speed to
+// leave out commands that do not change them.
+type plotter struct {
+	w         *bufio.Writer
+	opts      Options
+	transform mt.Transform
+	pens      map[color.NRGBA]int
+	err       error
+
+	pen      int
+	velocity float64
+}
+
+func newPlotter(w io.Writer, opts Options) *plotter {
+	p := &plotter{w: bufio.NewWriter(w), opts: opts, transform: mt.Identity(), velocity: -1}
+	if opts.Transform != nil {
+		p.transform = *opts.Transform
+	}
+	if p.opts.Pen <= 0 {
+		p.opts.Pen = 1
+	}
+	p.pens = make(map[color.NRGBA]int)
+	for k, v := range opts.Pens {
+		if c, ok := svg.ParseColor(k); ok {
+			p.pens[c] = v
+		}
+	}
+	return p
+}
+
+func (p *plotter) begin() {
+	p.println("IN;")
+	p.println("PA;")
+}
+
+// end puts the pen away.
+func (p *plotter) end() error {
+	p.println("PU;")
+	p.println("SP0;")
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+	return p.err
+}
+
+func (p *plotter) println(line string) {
+	if p.err == nil {
+		_, p.err = p.w.WriteString(line + "\n")
+	}
+}
+
+// penFor returns the pen an element with the given paint is drawn
+// with, chosen by its stroke colour.
+func (p *plotter) penFor(paint *svg.DrawingInstruction) int {
+	if paint != nil && paint.Stroke != nil {
+		if c, ok := svg.ParseColor(strings.TrimSpace(*paint.Stroke)); ok {
+			if pen, ok := p.pens[c]; ok {
+				return pen
+			}
+		}
+	}
+	return p.opts.Pen
+}
+
+// selectPen picks up a pen and sets its speed, if either changes.
+func (p *plotter) selectPen(pen int) {
+	if pen != p.pen {
+		p.println("SP" + strconv.Itoa(pen) + ";")
+		p.pen = pen
+	}
+	v, ok := p.opts.Velocities[pen]
+	if !ok {
+		v = p.opts.Velocity
+	}
+	if v > 0 && v != p.velocity {
+		p.println("VS" + strconv.FormatFloat(v, 'f', -1, 64) + ";")
+		p.velocity = v
+	}
+}
+
+// point returns a point of a segment in plotter units.
+func (p *plotter) point(v [2]float64) [2]int {
+	x, y := p.transform.Apply(v[0], v[1])
+	return [2]int{int(math.Round(x * UnitsPerMillimeter)), int(math.Round(y * UnitsPerMillimeter))}
+}
+
+// draw moves to the start of the segment with the pen up and draws it
+// with the given pen down. Points rounding to the same plotter unit are
+// drawn once.
+func (p *plotter) draw(seg svg.Segment, pen int) {
+	if len(seg.Points) == 0 {
+		return
+	}
+	points := seg.Points
+	if seg.Closed && points[len(points)-1] != points[0] {
+		points = append(points[:len(points):len(points)], points[0])
+	}
+	start := p.point(points[0])
+	var path [][2]int
+	last := start
+	for _, v := range points[1:] {
+		if q := p.point(v); q != last {
+			path = append(path, q)
+			last = q
+		}
+	}
+	if len(path) == 0 {
+		// A dot.
+		path = append(path, start)
+	}
+
+	p.selectPen(pen)
+	p.println("PU;")
+	p.println("PA" + coordinates([][2]int{start}) + ";")
+	p.println("PD;")
+	p.println("PA" + coordinates(path) + ";")
+}
+
+func coordinates(points [][2]int) string {
+	var b strings.Builder
+	for i, q := range points {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(q[0]))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(q[1]))
+	}
+	return b.String()
+}
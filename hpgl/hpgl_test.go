@@ -0,0 +1,74 @@
+package hpgl
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSegments(t *testing.T) {
+	segments := make(chan svg.Segment, 4)
+	segments <- svg.Segment{Width: 2, Points: [][2]float64{{0, 0}, {10, 0}, {10.001, 0}, {10, 5}}}
+	segments <- svg.Segment{Width: 2, Closed: true, Points: [][2]float64{{0, 0}, {1, 0}, {1, 1}}}
+	segments <- svg.Segment{Points: [][2]float64{{2, 2}, {2.001, 2}}}
+	segments <- svg.Segment{Width: 3, Points: [][2]float64{{0, 0}, {0, 1}}}
+	close(segments)
+
+	half := mt.Identity()
+	half.Scale(0.5, 0.5)
+	var b strings.Builder
+	require.NoError(t, WriteSegments(&b, segments, Options{
+		Transform:  &half,
+		Pen:        4,
+		SegmentPen: func(s svg.Segment) int { return int(s.Width) },
+		Velocity:   20,
+		Velocities: map[int]float64{3: 20, 4: 5},
+	}))
+	require.Equal(t, strings.Join([]string{
+		"IN;", "PA;",
+		"SP2;", "VS20;",
+		"PU;", "PA0,0;", "PD;", "PA200,0,200,100;",
+		// Closed segments return to their start.
+		"PU;", "PA0,0;", "PD;", "PA20,0,20,20,0,0;",
+		// A segment shorter than a plotter unit is a dot.
+		"SP4;", "VS5;",
+		"PU;", "PA40,40;", "PD;", "PA40,40;",
+		"SP3;", "VS20;",
+		"PU;", "PA0,0;", "PD;", "PA0,20;",
+		"PU;", "SP0;", "",
+	}, "\n"), b.String())
+}
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(`<svg width="100mm" height="50mm" viewBox="0 0 200 100">
+		<g stroke="red">
+			<path d="M0 0 H20 V20 Z"/>
+			<path d="M50 50 A10 10 0 0 1 70 50" stroke="blue"/>
+		</g>
+		<path d="M0 100 L200 0"/>
+	</svg>`, "plot", 1)
+	require.NoError(t, err)
+	var b strings.Builder
+	require.NoError(t, Write(&b, s, Options{Pens: map[string]int{"red": 2, "blue": 3}}))
+	lines := strings.Split(b.String(), "\n")
+	require.Equal(t, []string{"IN;", "PA;", "SP2;", "PU;", "PA0,2000;", "PD;", "PA400,2000,400,1600,0,2000;", "SP3;", "PU;", "PA1000,1000;", "PD;"}, lines[:11])
+
+	// The arc is flattened within a plotter unit and bulges up.
+	arc := strings.Split(strings.TrimSuffix(strings.TrimPrefix(lines[11], "PA"), ";"), ",")
+	require.Greater(t, len(arc), 20)
+	require.Equal(t, []string{"1400", "1000"}, arc[len(arc)-2:])
+	top := 0
+	for i := 1; i < len(arc); i += 2 {
+		y, err := strconv.Atoi(arc[i])
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, y, 1000)
+		top = max(top, y)
+	}
+	require.InDelta(t, 1200, top, 1)
+
+	require.Equal(t, []string{"SP1;", "PU;", "PA0,0;", "PD;", "PA4000,2000;", "PU;", "SP0;", ""}, lines[12:])
+}
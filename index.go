@@ -0,0 +1,344 @@
+package svg
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Bounds of the number of entries of the nodes of an Index.
+const (
+	maxNodeEntries = 16
+	minNodeEntries = 6
+)
+
+// Intersects reports whether the boxes share at least one point.
+func (b Box) Intersects(o Box) bool {
+	return !b.Empty() && !o.Empty() &&
+		b.MinX <= o.MaxX && o.MinX <= b.MaxX && b.MinY <= o.MaxY && o.MinY <= b.MaxY
+}
+
+func (b Box) area() float64 {
+	return b.Width() * b.Height()
+}
+
+// distance returns the distance from p to the closest point of the box.
+func (b Box) distance(p [2]float64) float64 {
+	dx := math.Max(math.Max(b.MinX-p[0], 0), p[0]-b.MaxX)
+	dy := math.Max(math.Max(b.MinY-p[1], 0), p[1]-b.MaxY)
+	return math.Hypot(dx, dy)
+}
+
+// indexEntry is an element in a leaf or a child node in an inner node
+// of an Index.
+type indexEntry struct {
+	box     Box
+	child   *indexNode
+	element DrawingInstructionParser
+}
+
+type indexNode struct {
+	parent  *indexNode
+	leaf    bool
+	entries []indexEntry
+}
+
+func (n *indexNode) box() Box {
+	b := emptyBox
+	for _, e := range n.entries {
+		b = b.Union(e.box)
+	}
+	return b
+}
+
+// Index is an R-tree over the bounding boxes of elements, answering
+// region and nearest element queries without scanning every element.
+// Elements can be added and removed at any time.
+type Index struct {
+	root  *indexNode
+	boxes map[DrawingInstructionParser]Box
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{root: &indexNode{leaf: true}, boxes: make(map[DrawingInstructionParser]Box)}
+}
+
+// Index returns an index of the elements of the document that would be
+// drawn by ParseDrawingInstructions, by their stroke boxes. Groups are
+// not indexed themselves, their elements are.
+func (s *Svg) Index() *Index {
+	ix := NewIndex()
+	s.VisitElements(func(e DrawingInstructionParser, _ *Group) {
+		ix.Add(e)
+	})
+	return ix
+}
+
+// Len returns the number of elements in the index.
+func (ix *Index) Len() int {
+	return len(ix.boxes)
+}
+
+// Add indexes an element by its stroke box, replacing an earlier entry
+// of the element. Elements with an empty box are not indexed.
+func (ix *Index) Add(e DrawingInstructionParser) {
+	ix.Insert(e, elementBox(e, true))
+}
+
+// Insert indexes an element by the given box, replacing an earlier
+// entry of the element. Elements with an empty box are not indexed.
+func (ix *Index) Insert(e DrawingInstructionParser, b Box) {
+	ix.Remove(e)
+	if b.Empty() {
+		return
+	}
+	ix.boxes[e] = b
+	ix.insert(indexEntry{box: b, element: e})
+}
+
+// Remove deletes an element from the index and reports whether it was
+// indexed.
+func (ix *Index) Remove(e DrawingInstructionParser) bool {
+	b, ok := ix.boxes[e]
+	if !ok {
+		return false
+	}
+	delete(ix.boxes, e)
+	leaf := ix.findLeaf(ix.root, e, b)
+	for i, entry := range leaf.entries {
+		if entry.element == e {
+			leaf.entries = append(leaf.entries[:i], leaf.entries[i+1:]...)
+			break
+		}
+	}
+	ix.condense(leaf)
+	return true
+}
+
+// Search returns the elements whose boxes intersect b.
+func (ix *Index) Search(b Box) []DrawingInstructionParser {
+	var found []DrawingInstructionParser
+	stack := []*indexNode{ix.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, entry := range n.entries {
+			if !entry.box.Intersects(b) {
+				continue
+			}
+			if n.leaf {
+				found = append(found, entry.element)
+			} else {
+				stack = append(stack, entry.child)
+			}
+		}
+	}
+	return found
+}
+
+// Nearest returns up to k elements closest to the point x, y, the
+// closest first. Distances are measured to the boxes of the elements,
+// so all elements whose box contains the point are at distance 0.
+func (ix *Index) Nearest(x, y float64, k int) []DrawingInstructionParser {
+	p := [2]float64{x, y}
+	var found []DrawingInstructionParser
+	q := &indexQueue{{dist: ix.root.box().distance(p), node: ix.root}}
+	for q.Len() > 0 && len(found) < k {
+		item := heap.Pop(q).(indexQueueItem)
+		if item.node == nil {
+			found = append(found, item.element)
+			continue
+		}
+		for _, entry := range item.node.entries {
+			next := indexQueueItem{dist: entry.box.distance(p), node: entry.child}
+			if item.node.leaf {
+				next.element = entry.element
+			}
+			heap.Push(q, next)
+		}
+	}
+	return found
+}
+
+// findLeaf returns the leaf below n holding the element e with box b.
+func (ix *Index) findLeaf(n *indexNode, e DrawingInstructionParser, b Box) *indexNode {
+	for _, entry := range n.entries {
+		if n.leaf {
+			if entry.element == e {
+				return n
+			}
+			continue
+		}
+		if entry.box.Intersects(b) {
+			if leaf := ix.findLeaf(entry.child, e, b); leaf != nil {
+				return leaf
+			}
+		}
+	}
+	return nil
+}
+
+// insert adds an element entry to the leaf whose box grows least,
+// splitting nodes that overflow on the way back up.
+func (ix *Index) insert(entry indexEntry) {
+	n := ix.root
+	for !n.leaf {
+		best, bestGrowth, bestArea := 0, math.Inf(1), math.Inf(1)
+		for i, e := range n.entries {
+			area := e.box.area()
+			growth := e.box.Union(entry.box).area() - area
+			if growth < bestGrowth || (growth == bestGrowth && area < bestArea) {
+				best, bestGrowth, bestArea = i, growth, area
+			}
+		}
+		n.entries[best].box = n.entries[best].box.Union(entry.box)
+		n = n.entries[best].child
+	}
+	n.entries = append(n.entries, entry)
+
+	for len(n.entries) > maxNodeEntries {
+		sibling := n.split()
+		parent := n.parent
+		if parent == nil {
+			parent = &indexNode{}
+			ix.root = parent
+			parent.entries = []indexEntry{{child: n}}
+			n.parent = parent
+		}
+		sibling.parent = parent
+		for i := range parent.entries {
+			if parent.entries[i].child == n {
+				parent.entries[i].box = n.box()
+			}
+		}
+		parent.entries = append(parent.entries, indexEntry{box: sibling.box(), child: sibling})
+		n = parent
+	}
+}
+
+// split moves part of the entries of n to a new node with the quadratic
+// algorithm of Guttman: the two entries wasting the most area together
+// seed the nodes, and the rest go where they waste the least.
+func (n *indexNode) split() *indexNode {
+	entries := n.entries
+	s1, s2, worst := 0, 1, math.Inf(-1)
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			waste := entries[i].box.Union(entries[j].box).area() - entries[i].box.area() - entries[j].box.area()
+			if waste > worst {
+				s1, s2, worst = i, j, waste
+			}
+		}
+	}
+
+	a := []indexEntry{entries[s1]}
+	b := []indexEntry{entries[s2]}
+	boxA, boxB := entries[s1].box, entries[s2].box
+	rest := make([]indexEntry, 0, len(entries)-2)
+	for i, e := range entries {
+		if i != s1 && i != s2 {
+			rest = append(rest, e)
+		}
+	}
+	for len(rest) > 0 {
+		// Fill up a node that would otherwise end up underfull.
+		if len(a)+len(rest) == minNodeEntries {
+			a = append(a, rest...)
+			break
+		}
+		if len(b)+len(rest) == minNodeEntries {
+			b = append(b, rest...)
+			break
+		}
+		// Place the entry with the strongest preference first.
+		pick, pickDiff := 0, math.Inf(-1)
+		for i, e := range rest {
+			d := math.Abs((boxA.Union(e.box).area() - boxA.area()) - (boxB.Union(e.box).area() - boxB.area()))
+			if d > pickDiff {
+				pick, pickDiff = i, d
+			}
+		}
+		e := rest[pick]
+		rest = append(rest[:pick], rest[pick+1:]...)
+		growA := boxA.Union(e.box).area() - boxA.area()
+		growB := boxB.Union(e.box).area() - boxB.area()
+		if growA < growB || (growA == growB && len(a) <= len(b)) {
+			a, boxA = append(a, e), boxA.Union(e.box)
+		} else {
+			b, boxB = append(b, e), boxB.Union(e.box)
+		}
+	}
+
+	n.entries = a
+	sibling := &indexNode{leaf: n.leaf, entries: b}
+	if !n.leaf {
+		for _, e := range b {
+			e.child.parent = sibling
+		}
+	}
+	return sibling
+}
+
+// condense removes underfull nodes on the path from n to the root after
+// a removal and inserts their elements again.
+func (ix *Index) condense(n *indexNode) {
+	var orphans []indexEntry
+	for n.parent != nil {
+		parent := n.parent
+		for i := range parent.entries {
+			if parent.entries[i].child != n {
+				continue
+			}
+			if len(n.entries) < minNodeEntries {
+				parent.entries = append(parent.entries[:i], parent.entries[i+1:]...)
+				orphans = n.appendElements(orphans)
+			} else {
+				parent.entries[i].box = n.box()
+			}
+			break
+		}
+		n = parent
+	}
+	for !ix.root.leaf && len(ix.root.entries) == 1 {
+		ix.root = ix.root.entries[0].child
+		ix.root.parent = nil
+	}
+	if !ix.root.leaf && len(ix.root.entries) == 0 {
+		ix.root = &indexNode{leaf: true}
+	}
+	for _, e := range orphans {
+		ix.insert(e)
+	}
+}
+
+// appendElements appends the element entries below n to entries.
+func (n *indexNode) appendElements(entries []indexEntry) []indexEntry {
+	if n.leaf {
+		return append(entries, n.entries...)
+	}
+	for _, e := range n.entries {
+		entries = e.child.appendElements(entries)
+	}
+	return entries
+}
+
+// indexQueue is a priority queue of nodes and elements by distance for
+// nearest element queries.
+type indexQueue []indexQueueItem
+
+type indexQueueItem struct {
+	dist    float64
+	node    *indexNode
+	element DrawingInstructionParser
+}
+
+func (q indexQueue) Len() int            { return len(q) }
+func (q indexQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q indexQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *indexQueue) Push(x interface{}) { *q = append(*q, x.(indexQueueItem)) }
+func (q *indexQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,131 @@
+package svg
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checkIndex verifies the boxes, parents, fill and balance of the tree
+// and returns its number of elements.
+func checkIndex(t *testing.T, ix *Index) int {
+	depth := -1
+	var walk func(n *indexNode, level int) int
+	walk = func(n *indexNode, level int) int {
+		if n != ix.root {
+			require.GreaterOrEqual(t, len(n.entries), minNodeEntries)
+		}
+		require.LessOrEqual(t, len(n.entries), maxNodeEntries)
+		if n.leaf {
+			if depth < 0 {
+				depth = level
+			}
+			require.Equal(t, depth, level)
+			return len(n.entries)
+		}
+		var count int
+		for _, e := range n.entries {
+			require.Same(t, n, e.child.parent)
+			require.Equal(t, e.child.box(), e.box)
+			count += walk(e.child, level+1)
+		}
+		return count
+	}
+	return walk(ix.root, 0)
+}
+
+func TestIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ix := NewIndex()
+	var circles []*Circle
+	add := func() {
+		c := &Circle{Cx: r.Float64() * 1000, Cy: r.Float64() * 1000, Radius: r.Float64() * 10}
+		circles = append(circles, c)
+		ix.Add(c)
+	}
+	for i := 0; i < 2000; i++ {
+		add()
+	}
+	require.Equal(t, 2000, checkIndex(t, ix))
+
+	for round := 0; round < 3; round++ {
+		// Remove a random half and add some back.
+		r.Shuffle(len(circles), func(i, j int) { circles[i], circles[j] = circles[j], circles[i] })
+		for _, c := range circles[len(circles)/2:] {
+			require.True(t, ix.Remove(c))
+			require.False(t, ix.Remove(c))
+		}
+		circles = circles[:len(circles)/2]
+		for i := 0; i < 300; i++ {
+			add()
+		}
+		require.Equal(t, len(circles), ix.Len())
+		require.Equal(t, len(circles), checkIndex(t, ix))
+
+		// Boxes are computed once, as computing them dominates the
+		// brute force checks below.
+		boxes := make(map[*Circle]Box, len(circles))
+		for _, c := range circles {
+			boxes[c] = c.BBox()
+		}
+		for q := 0; q < 50; q++ {
+			x, y := r.Float64()*1000, r.Float64()*1000
+			query := Box{x, y, x + r.Float64()*100, y + r.Float64()*100}
+			var expected []*Circle
+			for _, c := range circles {
+				if boxes[c].Intersects(query) {
+					expected = append(expected, c)
+				}
+			}
+			var found []*Circle
+			for _, e := range ix.Search(query) {
+				found = append(found, e.(*Circle))
+			}
+			require.ElementsMatch(t, expected, found)
+
+			dist := func(c *Circle) float64 { return boxes[c].distance([2]float64{x, y}) }
+			sorted := append([]*Circle{}, circles...)
+			sort.Slice(sorted, func(i, j int) bool { return dist(sorted[i]) < dist(sorted[j]) })
+			nearest := ix.Nearest(x, y, 5)
+			require.Len(t, nearest, 5)
+			for i, e := range nearest {
+				require.Equal(t, dist(sorted[i]), dist(e.(*Circle)))
+			}
+		}
+	}
+
+	for _, c := range circles {
+		require.True(t, ix.Remove(c))
+	}
+	require.Equal(t, 0, ix.Len())
+	require.Empty(t, ix.Search(Box{math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(1)}))
+	require.Empty(t, ix.Nearest(0, 0, 1))
+}
+
+func TestSvgIndex(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<path id="a" d="M0 0 H10 V10 Z"/>
+		<g transform="translate(50 50)">
+			<path id="b" d="M0 0 H10 V10 Z" stroke="black" stroke-width="2"/>
+			<path id="hidden" d="M0 0 H10 V10 Z" display="none"/>
+		</g>
+	</svg>`, "index", 1)
+	require.NoError(t, err)
+	ix := s.Index()
+	require.Equal(t, 2, ix.Len())
+
+	a := s.Elements[0]
+	b := s.Groups[0].Elements[0]
+	require.Equal(t, []DrawingInstructionParser{b}, ix.Search(Box{60.5, 40, 70, 50}))
+	require.Equal(t, []DrawingInstructionParser{a, b}, ix.Nearest(20, 20, 3))
+
+	// Elements moved by an edit are updated by adding them again.
+	b.(*Path).D = "M-50 -50 H-45"
+	ix.Add(b)
+	require.Equal(t, 2, ix.Len())
+	require.Empty(t, ix.Search(Box{60.5, 40, 70, 50}))
+	require.Equal(t, []DrawingInstructionParser{b}, ix.Nearest(0, -10, 1))
+}
@@ -0,0 +1,106 @@
+// Package pathops builds the path operators shared by PDF and
+// PostScript from drawing instructions. Both use m, l, c and h for
+// moves, lines, curves and closes; PostScript defines them in a prolog.
+package pathops
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rustyoz/svg"
+)
+
+// kappa is the distance of the control points from the on-curve points
+// when approximating a quarter circle with a cubic bezier.
+const kappa = 0.5522847498307936
+
+// Builder collects the operators of one path.
+type Builder struct {
+	b       strings.Builder
+	started bool
+}
+
+// Add adds the operators of a drawing instruction to the path. Paint
+// instructions are left to the caller.
+func (p *Builder) Add(di *svg.DrawingInstruction) {
+	switch di.Kind {
+	case svg.MoveInstruction:
+		p.op("m", di.M[0], di.M[1])
+		p.started = true
+	case svg.LineInstruction:
+		p.start()
+		p.op("l", di.M[0], di.M[1])
+	case svg.CurveInstruction:
+		cp := di.CurvePoints
+		p.start()
+		p.op("c", cp.C1[0], cp.C1[1], cp.C2[0], cp.C2[1], cp.T[0], cp.T[1])
+	case svg.CloseInstruction:
+		if p.started {
+			p.op("h")
+		}
+	case svg.CircleInstruction:
+		p.circle(*di.M, *di.Radius)
+	}
+}
+
+// Len returns the length of the operators written so far.
+func (p *Builder) Len() int {
+	return p.b.Len()
+}
+
+// String returns the operators of the path, one per line.
+func (p *Builder) String() string {
+	return p.b.String()
+}
+
+// Reset empties the path.
+func (p *Builder) Reset() {
+	p.b.Reset()
+	p.started = false
+}
+
+// start begins a path at the origin if it is drawn without a move.
+func (p *Builder) start() {
+	if !p.started {
+		p.op("m", 0, 0)
+		p.started = true
+	}
+}
+
+// op adds an operator with its operands to the path.
+func (p *Builder) op(name string, operands ...float64) {
+	for _, v := range operands {
+		p.b.WriteString(Number(v))
+		p.b.WriteByte(' ')
+	}
+	p.b.WriteString(name)
+	p.b.WriteByte('\n')
+}
+
+func (p *Builder) circle(c svg.Tuple, r float64) {
+	p.op("m", c[0]+r, c[1])
+	for q := 0; q < 4; q++ {
+		a0 := float64(q) * math.Pi / 2
+		a1 := a0 + math.Pi/2
+		p0 := [2]float64{c[0] + r*math.Cos(a0), c[1] + r*math.Sin(a0)}
+		p1 := [2]float64{c[0] + r*math.Cos(a1), c[1] + r*math.Sin(a1)}
+		p.op("c",
+			p0[0]-kappa*r*math.Sin(a0), p0[1]+kappa*r*math.Cos(a0),
+			p1[0]+kappa*r*math.Sin(a1), p1[1]-kappa*r*math.Cos(a1),
+			p1[0], p1[1])
+	}
+	p.op("h")
+	p.started = true
+}
+
+// Number formats a number with up to four decimals, as both PDF and
+// PostScript read it.
+func Number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,478 @@
+package svg
+
+import (
+	"math"
+	"sort"
+)
+
+// Crossing is a point where two curves meet, with its parameters on
+// the first and on the second curve. For paths the parameters are path
+// parameters, see PathGeometry.
+type Crossing struct {
+	Point  [2]float64
+	T1, T2 float64
+}
+
+// maxIntersectDepth bounds the subdivision of curves when intersecting
+// them.
+const maxIntersectDepth = 48
+
+// subCurve is the part of a curve from t0 to t1, with c being that part
+// on its own parameterised from 0 to 1.
+type subCurve struct {
+	c      Curve
+	t0, t1 float64
+	box    Box
+}
+
+func newSubCurve(c Curve, t0, t1 float64) subCurve {
+	return subCurve{c: c, t0: t0, t1: t1, box: c.Bounds()}
+}
+
+func (s subCurve) split() (subCurve, subCurve) {
+	a, b := s.c.Split(0.5)
+	m := (s.t0 + s.t1) / 2
+	return newSubCurve(a, s.t0, m), newSubCurve(b, m, s.t1)
+}
+
+// flatness returns a bound on the distance between a curve and its
+// chord.
+func flatness(c Curve) float64 {
+	switch c := c.(type) {
+	case LineCurve:
+		return 0
+	case QuadraticCurve:
+		return distanceToLine(c.C, c.P0, c.P1)
+	case CubicCurve:
+		return math.Max(distanceToLine(c.C1, c.P0, c.P1), distanceToLine(c.C2, c.P0, c.P1))
+	case ArcCurve:
+		if math.Abs(c.Sweep) >= math.Pi {
+			return math.Inf(1)
+		}
+		r := math.Sqrt(vdot(c.U, c.U) + vdot(c.V, c.V))
+		return r * (1 - math.Cos(c.Sweep/2))
+	}
+	b := c.Bounds()
+	return math.Hypot(b.Width(), b.Height())
+}
+
+// distanceToLine returns the distance from p to the line through a and
+// b, or to a when they coincide.
+func distanceToLine(p, a, b [2]float64) float64 {
+	d := vsub(b, a)
+	if l := vlen(d); l > 0 {
+		return math.Abs(vcross(d, vsub(p, a))) / l
+	}
+	return vlen(vsub(p, a))
+}
+
+// Intersect returns the points where the curves a and b cross or touch,
+// ordered by their parameter on a. Of stretches where the curves
+// overlap only the end points are reported.
+func Intersect(a, b Curve) []Crossing {
+	sa, sb := newSubCurve(a, 0, 1), newSubCurve(b, 0, 1)
+	scale := math.Max(math.Max(sa.box.Width(), sa.box.Height()), math.Max(sb.box.Width(), sb.box.Height()))
+	if scale == 0 || math.IsInf(scale, 0) {
+		return nil
+	}
+	x := &intersector{a: a, b: b, eps: scale * 1e-9}
+	x.findOverlaps()
+	x.find(sa, sb, 0)
+	return x.result()
+}
+
+type intersector struct {
+	a, b      Curve
+	eps       float64
+	found     []Crossing
+	stretches []overlap
+}
+
+// overlap is a stretch where the curves coincide, from T1 and T2 of
+// start to those of end.
+type overlap struct {
+	start, end Crossing
+}
+
+// overlapSamples is the number of points checked to lie on both curves
+// between the ends of an overlapping stretch.
+const overlapSamples = 16
+
+// findOverlaps finds the stretches where the curves coincide. Such a
+// stretch ends where one of the curves ends, so its ends are among the
+// end points of each curve lying on the other.
+func (x *intersector) findOverlaps() {
+	tol := x.eps * 1e2
+	var ts []float64
+	for _, t := range []float64{0, 1} {
+		if _, d := closestParameter(x.b, x.a.PointAt(t)); d <= tol {
+			ts = append(ts, t)
+		}
+		if t1, d := closestParameter(x.a, x.b.PointAt(t)); d <= tol {
+			ts = append(ts, t1)
+		}
+	}
+	sort.Float64s(ts)
+	for i := 1; i < len(ts); i++ {
+		if ts[i]-ts[i-1] < 1e-9 {
+			continue
+		}
+		if o, ok := x.overlapBetween(ts[i-1], ts[i]); ok {
+			x.stretches = append(x.stretches, o)
+		}
+	}
+}
+
+// overlapBetween reports whether the curves coincide for t1 from t0 to
+// t1 on the first curve. Points of the first curve are followed on the
+// second from the middle of the stretch outwards, so a closed second
+// curve is followed across its seam.
+func (x *intersector) overlapBetween(t0, t1 float64) (overlap, bool) {
+	tol := x.eps * 1e2
+	mid := (t0 + t1) / 2
+	tm, d := closestParameter(x.b, x.a.PointAt(mid))
+	if d > tol {
+		return overlap{}, false
+	}
+	follow := func(to float64) (Crossing, bool) {
+		t2 := tm
+		for i := 1; i <= overlapSamples/2; i++ {
+			ta := mid + (to-mid)*float64(i)/float64(overlapSamples/2)
+			p := x.a.PointAt(ta)
+			t2 = refineParameter(x.b, p, t2)
+			q := x.b.PointAt(t2)
+			if vlen(vsub(p, q)) > tol {
+				return Crossing{}, false
+			}
+			if i == overlapSamples/2 {
+				return Crossing{Point: lerp(p, q, 0.5), T1: ta, T2: t2}, true
+			}
+		}
+		return Crossing{}, false
+	}
+	start, ok := follow(t0)
+	if !ok {
+		return overlap{}, false
+	}
+	end, ok := follow(t1)
+	if !ok {
+		return overlap{}, false
+	}
+	return overlap{start: start, end: end}, true
+}
+
+// contains reports whether the parameters lie within the stretch, with
+// some slack.
+func (o overlap) contains(t1, t2, slack float64) bool {
+	lo2, hi2 := math.Min(o.start.T2, o.end.T2), math.Max(o.start.T2, o.end.T2)
+	return t1 >= o.start.T1-slack && t1 <= o.end.T1+slack && t2 >= lo2-slack && t2 <= hi2+slack
+}
+
+// closestParameter returns the parameter of the point of the curve
+// closest to p and the distance to it.
+func closestParameter(c Curve, p [2]float64) (float64, float64) {
+	const samples = 64
+	best, bestD := 0.0, math.Inf(1)
+	for i := 0; i <= samples; i++ {
+		t := float64(i) / samples
+		if d := vlen(vsub(c.PointAt(t), p)); d < bestD {
+			best, bestD = t, d
+		}
+	}
+	best = refineParameter(c, p, best)
+	return best, vlen(vsub(c.PointAt(best), p))
+}
+
+// refineParameter moves the parameter t towards the point of the curve
+// closest to p with Newton's method.
+func refineParameter(c Curve, p [2]float64, t float64) float64 {
+	for i := 0; i < 16; i++ {
+		d := c.Derivative(t)
+		dd := vdot(d, d)
+		if dd == 0 {
+			break
+		}
+		step := vdot(vsub(c.PointAt(t), p), d) / dd
+		t = math.Max(0, math.Min(1, t-step))
+		if math.Abs(step) < 1e-15 {
+			break
+		}
+	}
+	return t
+}
+
+func (x *intersector) find(a, b subCurve, depth int) {
+	if !grow(a.box, x.eps).Intersects(b.box) {
+		return
+	}
+	for _, o := range x.stretches {
+		if o.contains(a.t0, b.t0, 1e-9) && o.contains(a.t1, b.t1, 1e-9) {
+			// Inside a stretch where the curves coincide.
+			return
+		}
+	}
+	flatA, flatB := flatness(a.c) <= x.eps*1e3, flatness(b.c) <= x.eps*1e3
+	if (flatA && flatB) || depth >= maxIntersectDepth {
+		x.chords(a, b)
+		return
+	}
+	// Split the larger of the curves that are not flat yet.
+	if !flatA && (flatB || a.box.Width()+a.box.Height() >= b.box.Width()+b.box.Height()) {
+		a1, a2 := a.split()
+		x.find(a1, b, depth+1)
+		x.find(a2, b, depth+1)
+		return
+	}
+	b1, b2 := b.split()
+	x.find(a, b1, depth+1)
+	x.find(a, b2, depth+1)
+}
+
+// chords intersects the chords of two flat parts of the curves and
+// refines the result on the curves themselves.
+func (x *intersector) chords(a, b subCurve) {
+	p0, p1 := a.c.PointAt(0), a.c.PointAt(1)
+	q0, q1 := b.c.PointAt(0), b.c.PointAt(1)
+	d, e := vsub(p1, p0), vsub(q1, q0)
+	den := vcross(d, e)
+	if math.Abs(den) <= 1e-12*vlen(d)*vlen(e) {
+		// Parallel chords only meet where their ends touch.
+		x.touching(a, b)
+		return
+	}
+	w := vsub(q0, p0)
+	u, v := vcross(w, e)/den, vcross(w, d)/den
+	const slack = 0.1
+	if u < -slack || u > 1+slack || v < -slack || v > 1+slack {
+		return
+	}
+	t1 := a.t0 + (a.t1-a.t0)*math.Max(0, math.Min(1, u))
+	t2 := b.t0 + (b.t1-b.t0)*math.Max(0, math.Min(1, v))
+	x.add(t1, t2)
+}
+
+// touching adds the ends of parallel parts that meet.
+func (x *intersector) touching(a, b subCurve) {
+	for _, ta := range []float64{a.t0, a.t1} {
+		for _, tb := range []float64{b.t0, b.t1} {
+			if vlen(vsub(x.a.PointAt(ta), x.b.PointAt(tb))) <= x.eps {
+				x.add(ta, tb)
+			}
+		}
+	}
+}
+
+// add refines a pair of parameters with Newton's method on the
+// difference of the curves and records it when the curves meet there.
+func (x *intersector) add(t1, t2 float64) {
+	for i := 0; i < 16; i++ {
+		f := vsub(x.a.PointAt(t1), x.b.PointAt(t2))
+		if vlen(f) <= x.eps*1e-3 {
+			break
+		}
+		da, db := x.a.Derivative(t1), x.b.Derivative(t2)
+		det := vcross(da, db)
+		if det == 0 {
+			break
+		}
+		// Solve da dt1 - db dt2 = -f.
+		dt1 := vcross(db, f) / det
+		dt2 := vcross(da, f) / det
+		t1 = math.Max(0, math.Min(1, t1+dt1))
+		t2 = math.Max(0, math.Min(1, t2+dt2))
+	}
+	pa, pb := x.a.PointAt(t1), x.b.PointAt(t2)
+	if vlen(vsub(pa, pb)) > x.eps*1e2 {
+		return
+	}
+	x.found = append(x.found, Crossing{Point: lerp(pa, pb, 0.5), T1: t1, T2: t2})
+}
+
+// result returns the intersections found ordered by T1, merging those
+// found more than once. Intersections within overlapping stretches are
+// replaced by the ends of the stretches.
+func (x *intersector) result() []Crossing {
+	found := x.found[:0]
+	for _, in := range x.found {
+		inside := false
+		for _, o := range x.stretches {
+			inside = inside || o.contains(in.T1, in.T2, 1e-7)
+		}
+		if !inside {
+			found = append(found, in)
+		}
+	}
+	for _, o := range x.stretches {
+		found = append(found, o.start, o.end)
+	}
+	x.found = found
+	sort.Slice(x.found, func(i, j int) bool { return x.found[i].T1 < x.found[j].T1 })
+	var out []Crossing
+	for _, in := range x.found {
+		dup := false
+		for _, o := range out {
+			if vlen(vsub(o.Point, in.Point)) <= x.eps*1e3 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// grow returns the box extended by d on all sides.
+func grow(b Box, d float64) Box {
+	return Box{b.MinX - d, b.MinY - d, b.MaxX + d, b.MaxY + d}
+}
+
+// indexedCurve is a curve of a path, or a part of one, with its path
+// parameter range and the index of its subpath.
+type indexedCurve struct {
+	c       Curve
+	t0, t1  float64
+	box     Box
+	subpath int
+}
+
+// Intersections returns the points where the path crosses or touches
+// the other path, with path parameters on both.
+func (g *PathGeometry) Intersections(o *PathGeometry) []Crossing {
+	var out []Crossing
+	for _, a := range g.indexedCurves(false) {
+		for _, b := range o.indexedCurves(false) {
+			if !a.box.Intersects(b.box) {
+				continue
+			}
+			for _, in := range Intersect(a.c, b.c) {
+				out = append(out, Crossing{
+					Point: in.Point,
+					T1:    a.t0 + (a.t1-a.t0)*in.T1,
+					T2:    b.t0 + (b.t1-b.t0)*in.T2,
+				})
+			}
+		}
+	}
+	sortCrossings(out)
+	return out
+}
+
+// SelfIntersections returns the points where the path crosses or
+// touches itself, including loops within a single curve, with both path
+// parameters, the smaller one first. The joints between consecutive
+// curves are not reported.
+func (g *PathGeometry) SelfIntersections() []Crossing {
+	curves := g.indexedCurves(true)
+
+	// Consecutive pieces meet at their common parameter, and the ends
+	// of closed subpaths meet too.
+	var joints [][2]float64
+	for i := 1; i < len(curves); i++ {
+		if curves[i].subpath == curves[i-1].subpath {
+			joints = append(joints, [2]float64{curves[i-1].t1, curves[i].t0})
+		}
+	}
+	for i := 0; i < len(curves); {
+		j := i
+		for j+1 < len(curves) && curves[j+1].subpath == curves[i].subpath {
+			j++
+		}
+		if g.Subpaths[curves[i].subpath].Closed {
+			joints = append(joints, [2]float64{curves[i].t0, curves[j].t1})
+		}
+		i = j + 1
+	}
+	isJoint := func(t1, t2 float64) bool {
+		const tol = 1e-7
+		for _, j := range joints {
+			if math.Abs(j[0]-t1) < tol && math.Abs(j[1]-t2) < tol {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []Crossing
+	for i, a := range curves {
+		for _, b := range curves[i+1:] {
+			if !grow(a.box, 1e-9).Intersects(b.box) {
+				continue
+			}
+			for _, in := range Intersect(a.c, b.c) {
+				t1 := a.t0 + (a.t1-a.t0)*in.T1
+				t2 := b.t0 + (b.t1-b.t0)*in.T2
+				if isJoint(t1, t2) {
+					continue
+				}
+				out = append(out, Crossing{Point: in.Point, T1: t1, T2: t2})
+			}
+		}
+	}
+	sortCrossings(out)
+	return out
+}
+
+// indexedCurves returns the curves of the path with their path
+// parameter ranges. With monotone set cubic curves are split where they
+// turn horizontally or vertically, so no piece intersects itself.
+func (g *PathGeometry) indexedCurves(monotone bool) []indexedCurve {
+	var out []indexedCurve
+	var i int
+	for k, sp := range g.Subpaths {
+		for _, c := range sp.Curves {
+			ts := []float64{0, 1}
+			if cb, ok := c.(CubicCurve); ok && monotone {
+				ts = append(ts, cubicExtrema(cb)...)
+				sort.Float64s(ts)
+			}
+			rest, done := c, 0.0
+			for j := 1; j < len(ts); j++ {
+				if ts[j]-ts[j-1] < 1e-9 {
+					continue
+				}
+				piece := rest
+				if ts[j] < 1 {
+					piece, rest = rest.Split((ts[j] - done) / (1 - done))
+				}
+				out = append(out, indexedCurve{
+					c:       piece,
+					t0:      float64(i) + done,
+					t1:      float64(i) + ts[j],
+					box:     piece.Bounds(),
+					subpath: k,
+				})
+				done = ts[j]
+			}
+			i++
+		}
+	}
+	return out
+}
+
+// cubicExtrema returns the parameters strictly between 0 and 1 at which
+// the curve turns horizontally or vertically.
+func cubicExtrema(c CubicCurve) []float64 {
+	var ts []float64
+	for k := 0; k < 2; k++ {
+		qa := -c.P0[k] + 3*c.C1[k] - 3*c.C2[k] + c.P1[k]
+		qb := 2 * (c.P0[k] - 2*c.C1[k] + c.C2[k])
+		qc := c.C1[k] - c.P0[k]
+		for _, t := range quadraticRoots(qa, qb, qc) {
+			if t > 1e-9 && t < 1-1e-9 {
+				ts = append(ts, t)
+			}
+		}
+	}
+	return ts
+}
+
+func sortCrossings(in []Crossing) {
+	sort.Slice(in, func(i, j int) bool {
+		if in[i].T1 != in[j].T1 {
+			return in[i].T1 < in[j].T1
+		}
+		return in[i].T2 < in[j].T2
+	})
+}
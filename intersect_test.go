@@ -0,0 +1,115 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireCrossings checks that the crossings lie on both curves and
+// returns their points.
+func requireCrossings(t *testing.T, a, b Curve, crossings []Crossing) [][2]float64 {
+	var pts [][2]float64
+	for _, c := range crossings {
+		requirePoint(t, c.Point, a.PointAt(c.T1), 1e-7)
+		requirePoint(t, c.Point, b.PointAt(c.T2), 1e-7)
+		pts = append(pts, c.Point)
+	}
+	return pts
+}
+
+func TestIntersect(t *testing.T) {
+	circle := parseGeometry(t, "M10 0 A10 10 0 0 1 -10 0 A10 10 0 0 1 10 0").curves()
+	lower, upper := circle[0], circle[1]
+
+	for _, tc := range []struct {
+		name     string
+		a, b     Curve
+		expected [][2]float64
+	}{
+		{"lines", LineCurve{[2]float64{0, 0}, [2]float64{10, 10}}, LineCurve{[2]float64{0, 10}, [2]float64{10, 0}}, [][2]float64{{5, 5}}},
+		{"parallel", LineCurve{[2]float64{0, 0}, [2]float64{10, 0}}, LineCurve{[2]float64{0, 1}, [2]float64{10, 1}}, nil},
+		{"touching ends", LineCurve{[2]float64{0, 0}, [2]float64{10, 0}}, LineCurve{[2]float64{10, 0}, [2]float64{10, 10}}, [][2]float64{{10, 0}}},
+		{"line and arc", LineCurve{[2]float64{-20, 5}, [2]float64{20, 5}}, lower, [][2]float64{{-math.Sqrt(75), 5}, {math.Sqrt(75), 5}}},
+		{"tangent", LineCurve{[2]float64{-20, -10}, [2]float64{20, -10}}, upper, [][2]float64{{0, -10}}},
+		{"miss", LineCurve{[2]float64{-20, 11}, [2]float64{20, 11}}, lower, nil},
+		{"line and cubic", LineCurve{[2]float64{0, 0}, [2]float64{100, 0}}, CubicCurve{[2]float64{0, 0}, [2]float64{30, 60}, [2]float64{70, -60}, [2]float64{100, 0}}, [][2]float64{{0, 0}, {50, 0}, {100, 0}}},
+		{"cubic and quadratic",
+			CubicCurve{[2]float64{0, 0}, [2]float64{30, 60}, [2]float64{70, -60}, [2]float64{100, 0}},
+			QuadraticCurve{[2]float64{0, 10}, [2]float64{50, -30}, [2]float64{100, 10}},
+			nil},
+	} {
+		crossings := Intersect(tc.a, tc.b)
+		pts := requireCrossings(t, tc.a, tc.b, crossings)
+		if tc.name == "cubic and quadratic" {
+			// The quadratic dips through the wave twice.
+			require.Len(t, pts, 2, tc.name)
+			continue
+		}
+		require.Len(t, pts, len(tc.expected), tc.name)
+		for i := range pts {
+			requirePoint(t, tc.expected[i], pts[i], 1e-7)
+		}
+	}
+}
+
+func TestPathIntersections(t *testing.T) {
+	square := parseGeometry(t, "M0 0 H10 V10 H0 Z")
+	line := parseGeometry(t, "M-5 5 H15")
+	crossings := square.Intersections(line)
+	require.Len(t, crossings, 2)
+	require.InDelta(t, 1.5, crossings[0].T1, 1e-9)
+	require.InDelta(t, 0.75, crossings[0].T2, 1e-9)
+	require.InDelta(t, 3.5, crossings[1].T1, 1e-9)
+	require.InDelta(t, 0.25, crossings[1].T2, 1e-9)
+
+	require.Empty(t, square.SelfIntersections())
+
+	eight := parseGeometry(t, "M0 0 L10 10 L10 0 L0 10 Z")
+	crossings = eight.SelfIntersections()
+	require.Len(t, crossings, 1)
+	requirePoint(t, [2]float64{5, 5}, crossings[0].Point, 1e-9)
+	require.InDelta(t, 0.5, crossings[0].T1, 1e-9)
+	require.InDelta(t, 2.5, crossings[0].T2, 1e-9)
+
+	// A single cubic curve with a loop.
+	loop := parseGeometry(t, "M0 0 C150 100 -50 100 100 0")
+	crossings = loop.SelfIntersections()
+	require.Len(t, crossings, 1)
+	c := crossings[0]
+	require.Less(t, c.T1, c.T2)
+	requirePoint(t, c.Point, loop.PointAt(c.T1), 1e-7)
+	requirePoint(t, c.Point, loop.PointAt(c.T2), 1e-7)
+	require.InDelta(t, 50, c.Point[0], 1e-7)
+}
+
+func TestIntersectOverlapping(t *testing.T) {
+	cubic := CubicCurve{[2]float64{0, 0}, [2]float64{30, 60}, [2]float64{70, -60}, [2]float64{100, 0}}
+	circle := parseGeometry(t, "M10 0 A10 10 0 1 1 10 -0.001").curves()[0]
+	full := ArcCurve{U: [2]float64{10, 0}, V: [2]float64{0, 10}, Sweep: 2 * math.Pi}
+	halfway := ArcCurve{U: [2]float64{10, 0}, V: [2]float64{0, 10}, Start: math.Pi, Sweep: 2 * math.Pi}
+	head, tail := cubic.Split(0.75)
+	_, middle := head.Split(0.5)
+
+	for _, tc := range []struct {
+		name     string
+		a, b     Curve
+		expected [][2]float64
+	}{
+		{"identical cubics", cubic, cubic, [][2]float64{cubic.P0, cubic.P1}},
+		{"identical arcs", circle, circle, [][2]float64{circle.PointAt(0), circle.PointAt(1)}},
+		{"identical full circles", full, full, [][2]float64{{10, 0}}},
+		{"full circles started apart", full, halfway, [][2]float64{{10, 0}, {-10, 0}}},
+		{"collinear lines", LineCurve{[2]float64{0, 0}, [2]float64{10, 0}}, LineCurve{[2]float64{15, 0}, [2]float64{5, 0}}, [][2]float64{{5, 0}, {10, 0}}},
+		{"cubic and its part", cubic, middle, [][2]float64{cubic.PointAt(0.375), cubic.PointAt(0.75)}},
+		{"cubic parts meeting", head, tail, [][2]float64{cubic.PointAt(0.75)}},
+	} {
+		crossings := Intersect(tc.a, tc.b)
+		pts := requireCrossings(t, tc.a, tc.b, crossings)
+		require.Len(t, pts, len(tc.expected), tc.name)
+		for i := range pts {
+			requirePoint(t, tc.expected[i], pts[i], 1e-7)
+		}
+	}
+}
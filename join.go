@@ -0,0 +1,135 @@
+package svg
+
+import "math"
+
+// JoinStats reports what JoinSegments did.
+type JoinStats struct {
+	// Joins is the number of times two segments were joined into one.
+	Joins int
+	// Closed is the number of joined segments found to form loops.
+	Closed int
+	// Reversed is the number of segments joined in reverse.
+	Reversed int
+}
+
+// segmentEnd is the first or the last point of a segment.
+type segmentEnd struct {
+	segment int
+	last    bool
+}
+
+// endGrid finds the ends of open segments near a point.
+type endGrid struct {
+	cell  float64
+	cells map[[2]int][]segmentEnd
+}
+
+func (g *endGrid) key(p [2]float64) [2]int {
+	return [2]int{int(math.Floor(p[0] / g.cell)), int(math.Floor(p[1] / g.cell))}
+}
+
+func (g *endGrid) add(p [2]float64, e segmentEnd) {
+	k := g.key(p)
+	g.cells[k] = append(g.cells[k], e)
+}
+
+// JoinSegments joins open segments whose ends lie within tolerance of
+// each other into longer segments, reversing segments where needed.
+// Joined segments whose ends meet are closed. Only segments with the
+// same stroke properties are joined; closed segments are passed
+// through. The joined segments take the place of their first part.
+func JoinSegments(segments []Segment, tolerance float64) ([]Segment, JoinStats) {
+	var stats JoinStats
+	grid := endGrid{cell: tolerance, cells: make(map[[2]int][]segmentEnd)}
+	if grid.cell <= 0 {
+		grid.cell = 1
+	}
+	for i, s := range segments {
+		if !s.Closed && len(s.Points) > 0 {
+			grid.add(s.Points[0], segmentEnd{i, false})
+			grid.add(s.Points[len(s.Points)-1], segmentEnd{i, true})
+		}
+	}
+
+	used := make([]bool, len(segments))
+	// find returns the closest unused end near p of a segment with the
+	// stroke of s.
+	find := func(p [2]float64, s Segment) (segmentEnd, bool) {
+		best, bestDist := segmentEnd{}, math.Inf(1)
+		k := grid.key(p)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, e := range grid.cells[[2]int{k[0] + dx, k[1] + dy}] {
+					o := segments[e.segment]
+					if used[e.segment] || !sameStroke(o, s) {
+						continue
+					}
+					q := o.Points[0]
+					if e.last {
+						q = o.Points[len(o.Points)-1]
+					}
+					d := vlen(vsub(p, q))
+					if d <= tolerance && (d < bestDist || d == bestDist && e.segment < best.segment) {
+						best, bestDist = e, d
+					}
+				}
+			}
+		}
+		return best, !math.IsInf(bestDist, 1)
+	}
+
+	var out []Segment
+	for i, s := range segments {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if s.Closed || len(s.Points) == 0 {
+			out = append(out, s)
+			continue
+		}
+		chain := append([][2]float64{}, s.Points...)
+		// Extend the end of the chain, then its start.
+		for forward := true; ; {
+			end := chain[len(chain)-1]
+			if !forward {
+				end = chain[0]
+			}
+			e, ok := find(end, s)
+			if !ok {
+				if !forward {
+					break
+				}
+				forward = false
+				continue
+			}
+			used[e.segment] = true
+			pts := segments[e.segment].Points
+			// Forward the points must start at the joint, backward
+			// they must end there.
+			if e.last == forward {
+				pts = reversed(pts)
+				stats.Reversed++
+			}
+			if forward {
+				chain = append(chain, pts[1:]...)
+			} else {
+				chain = append(append([][2]float64{}, pts[:len(pts)-1]...), chain...)
+			}
+			stats.Joins++
+		}
+		s.Points = chain
+		if len(chain) > 2 && vlen(vsub(chain[0], chain[len(chain)-1])) <= tolerance {
+			chain[len(chain)-1] = chain[0]
+			s.Closed = true
+			stats.Closed++
+		}
+		out = append(out, s)
+	}
+	return out, stats
+}
+
+// sameStroke reports whether the segments are stroked alike.
+func sameStroke(a, b Segment) bool {
+	return a.Width == b.Width && a.LineCap == b.LineCap && a.LineJoin == b.LineJoin && a.MiterLimit == b.MiterLimit
+}
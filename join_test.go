@@ -0,0 +1,48 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSegments(t *testing.T) {
+	// The sides of a square, out of order, some reversed and with small
+	// gaps.
+	segments := []Segment{
+		{Points: [][2]float64{{0, 0}, {10, 0}}},
+		{Points: [][2]float64{{0, 10}, {10, 10.001}}},
+		{Points: [][2]float64{{10, 0}, {10, 5}, {10, 10}}},
+		{Points: [][2]float64{{0, 0.001}, {0, 10}}},
+	}
+	joined, stats := JoinSegments(segments, 0.01)
+	require.Len(t, joined, 1)
+	require.Equal(t, JoinStats{Joins: 3, Closed: 1, Reversed: 2}, stats)
+	require.True(t, joined[0].Closed)
+	require.Equal(t, [][2]float64{{0, 0}, {10, 0}, {10, 5}, {10, 10}, {0, 10}, {0, 0}}, joined[0].Points)
+
+	// Only the exactly touching sides are joined.
+	joined, stats = JoinSegments(segments, 0.0001)
+	require.Len(t, joined, 2)
+	require.Equal(t, 2, stats.Joins)
+	require.Zero(t, stats.Closed)
+}
+
+func TestJoinSegmentsBackward(t *testing.T) {
+	closed := square(50, 50, 5)
+	segments := []Segment{
+		{Points: [][2]float64{{10, 0}, {20, 0}}},
+		closed,
+		{Points: [][2]float64{{0, 0}, {10, 0}}},
+		{Points: [][2]float64{{30, 0}, {20, 0}}},
+		{Width: 2, Points: [][2]float64{{30, 0}, {40, 0}}},
+	}
+	joined, stats := JoinSegments(segments, 0)
+	require.Equal(t, JoinStats{Joins: 2, Reversed: 1}, stats)
+	require.Len(t, joined, 3)
+	require.Equal(t, [][2]float64{{0, 0}, {10, 0}, {20, 0}, {30, 0}}, joined[0].Points)
+	require.False(t, joined[0].Closed)
+	require.Equal(t, closed, joined[1])
+	// Segments stroked differently are kept apart.
+	require.Equal(t, segments[4], joined[2])
+}
@@ -0,0 +1,240 @@
+// Package kicad turns SVG documents into graphic items of KiCad boards
+// and footprints, such as logos on the silkscreen, board outlines on
+// Edge.Cuts and shapes in copper. Items are written as the s-expressions
+// of KiCad 7 and later, in millimetres.
+package kicad
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+)
+
+// DefaultTolerance is the default maximum distance in millimetres
+// between a curve and the lines it is written as.
+const DefaultTolerance = 0.01
+
+// DefaultWidth is the default width in millimetres of lines and arcs of
+// elements without a stroke width.
+const DefaultWidth = 0.1
+
+// Layer is the name of a KiCad layer.
+type Layer string
+
+// Common layers for graphic items.
+const (
+	FrontSilkscreen Layer = "F.SilkS"
+	BackSilkscreen  Layer = "B.SilkS"
+	EdgeCuts        Layer = "Edge.Cuts"
+	FrontCopper     Layer = "F.Cu"
+	BackCopper      Layer = "B.Cu"
+)
+
+// Options control the written items.
+type Options struct {
+	// Layer is the layer of the items. Zero means FrontSilkscreen.
+	Layer Layer
+	// Footprint writes fp_ items for a footprint instead of gr_ items
+	// for a board.
+	Footprint bool
+	// Transform maps the user units of the document to millimetres.
+	// Nil means the physical size of the document. KiCad, like SVG, has
+	// its y axis pointing down.
+	Transform *mt.Transform
+	// Tolerance is the maximum distance in millimetres between a curve
+	// and the lines it is written as. Zero means DefaultTolerance.
+	Tolerance float64
+	// Width is the width in millimetres of lines and arcs. Zero means
+	// the stroke width of the element, or DefaultWidth.
+	Width float64
+}
+
+// Write writes the elements of the document that would be drawn by
+// ParseDrawingInstructions as KiCad items, one per line, to be placed in
+// a board or footprint file. Filled elements become filled circles or
+// polygons, with holes joined to their boundary, outlined by the stroke
+// if they have one. Stroked elements become lines, circles and arcs; curves other
+// than circular arcs are written as lines. On Edge.Cuts every element
+// is written as an outline. Errors in elements are returned once the
+// rest of the document is written.
+func Write(w io.Writer, s *svg.Svg, opts Options) error {
+	if opts.Layer == "" {
+		opts.Layer = FrontSilkscreen
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DefaultTolerance
+	}
+	t := s.MillimeterTransform()
+	if opts.Transform != nil {
+		t = *opts.Transform
+	}
+	scale := math.Sqrt(math.Abs(t[0][0]*t[1][1] - t[0][1]*t[1][0]))
+	wr := &writer{w: bufio.NewWriter(w), opts: opts}
+	if opts.Footprint {
+		wr.prefix = "fp_"
+	} else {
+		wr.prefix = "gr_"
+	}
+
+	var first error
+	s.VisitElements(func(e svg.DrawingInstructionParser, _ *svg.Group) {
+		var geometry *svg.PathGeometry
+		var paint *svg.DrawingInstruction
+		if p, ok := e.(*svg.Path); ok {
+			pg, err := p.Geometry()
+			if err != nil && first == nil {
+				first = err
+			}
+			geometry, paint = pg, p.Paint()
+		} else {
+			instrs, err := svg.CollectInstructions(e)
+			if err != nil && first == nil {
+				first = err
+			}
+			for _, di := range instrs {
+				if di.Kind == svg.PaintInstruction {
+					paint = di
+				}
+			}
+			geometry = svg.GeometryFromInstructions(instrs)
+		}
+		if paint == nil {
+			paint = &svg.DrawingInstruction{Kind: svg.PaintInstruction}
+		}
+		fill := "black"
+		if paint.Fill != nil && strings.TrimSpace(*paint.Fill) != "" {
+			fill = *paint.Fill
+		}
+		_, filled := svg.ParseColor(fill)
+		var width float64
+		if paint.Stroke != nil && paint.StrokeWidth != nil {
+			if _, ok := svg.ParseColor(*paint.Stroke); ok {
+				width = *paint.StrokeWidth * scale
+			}
+		}
+		geometry = geometry.Transform(t)
+
+		switch {
+		case filled && opts.Layer != EdgeCuts:
+			if arc, ok := fullCircle(geometry); ok {
+				wr.circle(arc, width, "solid")
+				return
+			}
+			rule := svg.NonZero
+			if paint.FillRule != nil {
+				rule = svg.ParseFillRule(strings.TrimSpace(*paint.FillRule))
+			}
+			for _, p := range Polygons(geometry.Flatten(opts.Tolerance), rule) {
+				wr.poly(p.Points[:len(p.Points)-1], width)
+			}
+		case filled || width > 0:
+			if opts.Width > 0 {
+				width = opts.Width
+			} else if width <= 0 {
+				width = DefaultWidth
+			}
+			wr.outline(geometry, width)
+		}
+	})
+	if wr.err == nil {
+		wr.err = wr.w.Flush()
+	}
+	if wr.err != nil {
+		return wr.err
+	}
+	return first
+}
+
+// writer writes items and keeps the first error.
+type writer struct {
+	w      *bufio.Writer
+	opts   Options
+	prefix string
+	err    error
+}
+
+func (wr *writer) item(kind string, body ...string) {
+	if wr.err != nil {
+		return
+	}
+	_, wr.err = fmt.Fprintf(wr.w, "(%s%s %s (layer %q))\n", wr.prefix, kind, strings.Join(body, " "), string(wr.opts.Layer))
+}
+
+func stroke(width float64) string {
+	return "(stroke (width " + number(width) + ") (type solid))"
+}
+
+func point(name string, p [2]float64) string {
+	return "(" + name + " " + number(p[0]) + " " + number(p[1]) + ")"
+}
+
+// poly writes a filled polygon.
+func (wr *writer) poly(points [][2]float64, width float64) {
+	var pts strings.Builder
+	pts.WriteString("(pts")
+	for _, p := range points {
+		pts.WriteString(" " + point("xy", p))
+	}
+	pts.WriteString(")")
+	wr.item("poly", pts.String(), stroke(width), "(fill solid)")
+}
+
+// outline writes the curves of the geometry as lines, circles and arcs.
+func (wr *writer) outline(g *svg.PathGeometry, width float64) {
+	for _, sp := range g.Subpaths {
+		for _, c := range sp.Curves {
+			if arc, ok := c.(svg.ArcCurve); ok && circular(arc) {
+				if math.Abs(arc.Sweep) >= 2*math.Pi-1e-9 {
+					wr.circle(arc, width, "none")
+				} else {
+					wr.item("arc", point("start", arc.PointAt(0)), point("mid", arc.PointAt(0.5)), point("end", arc.PointAt(1)), stroke(width))
+				}
+				continue
+			}
+			pts := svg.FlattenCurve(c, wr.opts.Tolerance)
+			for i := 1; i < len(pts); i++ {
+				if pts[i] != pts[i-1] {
+					wr.item("line", point("start", pts[i-1]), point("end", pts[i]), stroke(width))
+				}
+			}
+		}
+	}
+}
+
+func (wr *writer) circle(c svg.ArcCurve, width float64, fill string) {
+	wr.item("circle", point("center", c.Center), point("end", c.PointAt(0)), stroke(width), "(fill "+fill+")")
+}
+
+// fullCircle returns the circle a geometry consists of, if it is one.
+func fullCircle(g *svg.PathGeometry) (svg.ArcCurve, bool) {
+	if len(g.Subpaths) != 1 || len(g.Subpaths[0].Curves) != 1 {
+		return svg.ArcCurve{}, false
+	}
+	arc, ok := g.Subpaths[0].Curves[0].(svg.ArcCurve)
+	return arc, ok && circular(arc) && math.Abs(arc.Sweep) >= 2*math.Pi-1e-9
+}
+
+// circular reports whether an arc is part of a circle.
+func circular(c svg.ArcCurve) bool {
+	u := math.Hypot(c.U[0], c.U[1])
+	v := math.Hypot(c.V[0], c.V[1])
+	dot := c.U[0]*c.V[0] + c.U[1]*c.V[1]
+	return math.Abs(u-v) <= 1e-9*math.Max(u, 1) && math.Abs(dot) <= 1e-9*math.Max(u*v, 1)
+}
+
+// number formats a length in millimetres to the nanometre resolution of
+// KiCad.
+func number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
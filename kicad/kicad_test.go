@@ -0,0 +1,123 @@
+package kicad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+func square(x, y, size float64) svg.Segment {
+	return svg.Segment{Closed: true, Points: [][2]float64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}}
+}
+
+func TestPolygons(t *testing.T) {
+	// Two holes, one with an island in it.
+	shape := []svg.Segment{square(0, 0, 20), square(2, 2, 8), square(4, 4, 2), square(12, 12, 4)}
+	polys := Polygons(shape, svg.EvenOdd)
+	require.Len(t, polys, 2)
+	outer, island := polys[0].Points, polys[1].Points
+	if len(outer) < len(island) {
+		outer, island = island, outer
+	}
+	require.True(t, polys[0].Closed)
+	require.Equal(t, outer[0], outer[len(outer)-1])
+
+	// The cuts add no area.
+	ring := outer[:len(outer)-1]
+	require.InDelta(t, 400-64-16, signedArea(ring), 1e-9)
+	require.InDelta(t, 4, signedArea(island[:len(island)-1]), 1e-9)
+	for _, p := range [][2]float64{{1, 1}, {11, 11}, {19, 19}, {11, 3}} {
+		require.True(t, inside(ring, p), "%v", p)
+	}
+	for _, p := range [][2]float64{{3, 3}, {5, 3}, {5, 5}, {14, 14}, {21, 1}} {
+		require.False(t, inside(ring, p), "%v", p)
+	}
+
+	// Every edge of the holes is kept, and no cut crosses another edge.
+	edges := map[[2][2]float64]bool{}
+	for i := 1; i < len(outer); i++ {
+		edges[[2][2]float64{outer[i-1], outer[i]}] = true
+	}
+	for _, e := range [][2][2]float64{{{2, 10}, {10, 10}}, {{12, 16}, {16, 16}}} {
+		require.True(t, edges[e] || edges[[2][2]float64{e[1], e[0]}], "%v", e)
+	}
+	for i := 1; i < len(outer); i++ {
+		for j := i + 2; j < len(outer); j++ {
+			a, b, c, d := outer[i-1], outer[i], outer[j-1], outer[j]
+			if a == c || a == d || b == c || b == d {
+				continue
+			}
+			require.False(t, crosses(a, b, c, d), "%v %v and %v %v", a, b, c, d)
+		}
+	}
+
+	// With the nonzero rule an inner square wound the same way is
+	// filled.
+	require.Len(t, Polygons([]svg.Segment{square(0, 0, 20), square(2, 2, 8)}, svg.NonZero), 1)
+	require.Len(t, Polygons([]svg.Segment{square(0, 0, 20), square(2, 2, 8)}, svg.NonZero)[0].Points, 5)
+}
+
+const drawingSvg = `<svg width="100mm" height="50mm" viewBox="0 0 200 100">
+	<path d="M10 10 H50 V50 H10 Z M20 20 V40 H40 V20 Z" fill-rule="evenodd"/>
+	<path d="M110 10 H190 V90" fill="none" stroke="black" stroke-width="0.4"/>
+	<path d="M120 50 A10 10 0 0 1 140 50" fill="none" stroke="black"/>
+	<path d="M0 90 C10 80 20 80 30 90" fill="none" stroke="black"/>
+	<circle cx="150" cy="50" r="10" fill="green"/>
+	<path d="M0 0 H10" fill="none"/>
+</svg>`
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(drawingSvg, "kicad", 1)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, Write(&b, s, Options{}))
+	items := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	require.Equal(t, "(gr_poly (pts (xy 5 5) (xy 25 5) (xy 25 25) (xy 20 20) (xy 20 10) (xy 10 10) (xy 10 20) (xy 20 20) (xy 25 25) (xy 5 25)) "+
+		"(stroke (width 0) (type solid)) (fill solid) (layer \"F.SilkS\"))", items[0])
+	require.Equal(t, "(gr_line (start 55 5) (end 95 5) (stroke (width 0.2) (type solid)) (layer \"F.SilkS\"))", items[1])
+	require.Equal(t, "(gr_line (start 95 5) (end 95 45) (stroke (width 0.2) (type solid)) (layer \"F.SilkS\"))", items[2])
+	// The arc bulges up, as drawn.
+	require.Equal(t, "(gr_arc (start 60 25) (mid 65 20) (end 70 25) (stroke (width 0.5) (type solid)) (layer \"F.SilkS\"))", items[3])
+	// The bezier curve is written as lines.
+	var curve []string
+	for _, item := range items[4:] {
+		if strings.HasPrefix(item, "(gr_line") {
+			curve = append(curve, item)
+		}
+	}
+	require.Greater(t, len(curve), 10)
+	require.True(t, strings.HasPrefix(curve[0], "(gr_line (start 0 45) "))
+	require.True(t, strings.HasPrefix(curve[len(curve)-1], "(gr_line (start "))
+	require.True(t, strings.HasSuffix(curve[len(curve)-1], "(end 15 45) (stroke (width 0.5) (type solid)) (layer \"F.SilkS\"))"))
+	require.Equal(t, "(gr_circle (center 75 25) (end 80 25) (stroke (width 0) (type solid)) (fill solid) (layer \"F.SilkS\"))", items[len(items)-1])
+	require.Len(t, items, 5+len(curve))
+
+	b.Reset()
+	require.NoError(t, Write(&b, s, Options{Layer: EdgeCuts, Footprint: true, Width: 0.05}))
+	out := b.String()
+	require.NotContains(t, out, "fp_poly")
+	require.NotContains(t, out, "gr_")
+	require.Contains(t, out, "(fp_line (start 10 10) (end 10 20) (stroke (width 0.05) (type solid)) (layer \"Edge.Cuts\"))\n")
+	require.Contains(t, out, "(fp_circle (center 75 25) (end 80 25) (stroke (width 0.05) (type solid)) (fill none) (layer \"Edge.Cuts\"))\n")
+
+	// A transform in place of the physical size.
+	b.Reset()
+	m := mt.Identity()
+	m.Scale(2, 2)
+	require.NoError(t, Write(&b, s, Options{Layer: FrontCopper, Transform: &m}))
+	require.Contains(t, b.String(), "(gr_arc (start 240 100) (mid 260 80) (end 280 100) (stroke (width 2) (type solid)) (layer \"F.Cu\"))\n")
+}
+
+func TestCircular(t *testing.T) {
+	require.True(t, circular(svg.ArcCurve{U: [2]float64{2, 0}, V: [2]float64{0, -2}, Sweep: 1}))
+	require.False(t, circular(svg.ArcCurve{U: [2]float64{2, 0}, V: [2]float64{0, 1}, Sweep: 1}))
+	_, ok := fullCircle(&svg.PathGeometry{Subpaths: []svg.Subpath{{Curves: []svg.Curve{
+		svg.ArcCurve{U: [2]float64{1, 0}, V: [2]float64{0, 1}, Sweep: -2 * math.Pi},
+	}}}})
+	require.True(t, ok)
+}
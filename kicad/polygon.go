@@ -0,0 +1,169 @@
+package kicad
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rustyoz/svg"
+)
+
+// Polygons returns the area of the segments, filled with the fill rule,
+// as closed segments without holes, since KiCad polygons cannot have
+// any. Every segment is treated as closed. Each hole is joined to the
+// boundary around it by a cut of zero width, running from its rightmost
+// point to the nearest point of the boundary it can see.
+func Polygons(segments []svg.Segment, rule svg.FillRule) []svg.Segment {
+	var outers, holes [][][2]float64
+	for _, ring := range svg.Clip(svg.Union, segments, rule, nil, svg.NonZero) {
+		pts := ring.Points
+		if len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+			pts = pts[:len(pts)-1]
+		}
+		if len(pts) < 3 {
+			continue
+		}
+		if signedArea(pts) > 0 {
+			outers = append(outers, pts)
+		} else {
+			holes = append(holes, pts)
+		}
+	}
+
+	// Each hole belongs to the smallest boundary around it.
+	owned := make([][][][2]float64, len(outers))
+	for _, h := range holes {
+		probe := lerp(h[0], h[1], 0.5)
+		best, bestArea := -1, math.Inf(1)
+		for i, o := range outers {
+			if a := signedArea(o); a < bestArea && inside(o, probe) {
+				best, bestArea = i, a
+			}
+		}
+		if best >= 0 {
+			owned[best] = append(owned[best], h)
+		}
+	}
+
+	out := make([]svg.Segment, 0, len(outers))
+	for i, o := range outers {
+		pts := bridge(o, owned[i])
+		pts = append(pts, pts[0])
+		out = append(out, svg.Segment{Closed: true, Points: pts})
+	}
+	return out
+}
+
+// bridge joins the holes to the boundary, rightmost hole first, so each
+// cut crosses neither the boundary nor the holes still to be joined.
+func bridge(outer [][2]float64, holes [][][2]float64) [][2]float64 {
+	rightmost := func(ring [][2]float64) int {
+		k := 0
+		for i, p := range ring {
+			if p[0] > ring[k][0] {
+				k = i
+			}
+		}
+		return k
+	}
+	holes = append([][][2]float64(nil), holes...)
+	sort.SliceStable(holes, func(i, j int) bool {
+		return holes[i][rightmost(holes[i])][0] > holes[j][rightmost(holes[j])][0]
+	})
+
+	poly := append([][2]float64(nil), outer...)
+	for n, hole := range holes {
+		k := rightmost(hole)
+		h := hole[k]
+		best, bestDist := -1, math.Inf(1)
+		fallback, fallbackDist := 0, math.Inf(1)
+		for i, v := range poly {
+			d := math.Hypot(v[0]-h[0], v[1]-h[1])
+			if d < fallbackDist {
+				fallback, fallbackDist = i, d
+			}
+			if d >= bestDist || !visible(h, v, poly, holes[n:]) {
+				continue
+			}
+			best, bestDist = i, d
+		}
+		if best < 0 {
+			best = fallback
+		}
+
+		// Go round the hole from h back to h, then return to the
+		// boundary along the cut.
+		joined := make([][2]float64, 0, len(poly)+len(hole)+2)
+		joined = append(joined, poly[:best+1]...)
+		joined = append(joined, hole[k:]...)
+		joined = append(joined, hole[:k+1]...)
+		joined = append(joined, poly[best:]...)
+		poly = joined
+	}
+	return poly
+}
+
+// visible reports whether the segment from a to b crosses no edge of
+// the rings, other than at its ends.
+func visible(a, b [2]float64, poly [][2]float64, rings [][][2]float64) bool {
+	for _, ring := range append([][][2]float64{poly}, rings...) {
+		for i, p := range ring {
+			q := ring[(i+1)%len(ring)]
+			if p == a || p == b || q == a || q == b {
+				continue
+			}
+			if crosses(a, b, p, q) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// crosses reports whether the segments ab and cd intersect.
+func crosses(a, b, c, d [2]float64) bool {
+	d1, d2 := orient(c, d, a), orient(c, d, b)
+	d3, d4 := orient(a, b, c), orient(a, b, d)
+	if (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0) && d1 != 0 && d2 != 0 && d3 != 0 && d4 != 0 {
+		return true
+	}
+	// Touching counts as crossing, except when collinear end to end.
+	return d1 == 0 && onSegment(c, d, a) || d2 == 0 && onSegment(c, d, b) ||
+		d3 == 0 && onSegment(a, b, c) || d4 == 0 && onSegment(a, b, d)
+}
+
+func orient(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment reports whether p, collinear with ab, lies on it.
+func onSegment(a, b, p [2]float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
+// signedArea returns the area of a ring, positive for the boundaries
+// returned by svg.Clip and negative for their holes.
+func signedArea(ring [][2]float64) float64 {
+	var a float64
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		a += p[0]*q[1] - q[0]*p[1]
+	}
+	return a / 2
+}
+
+// inside reports whether p lies inside the ring.
+func inside(ring [][2]float64, p [2]float64) bool {
+	in := false
+	for i, a := range ring {
+		b := ring[(i+1)%len(ring)]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < a[0]+(p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			in = !in
+		}
+	}
+	return in
+}
+
+func lerp(a, b [2]float64, t float64) [2]float64 {
+	return [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+}
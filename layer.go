@@ -0,0 +1,74 @@
+package svg
+
+import "encoding/xml"
+
+const (
+	inkscapeNamespace = "http://www.inkscape.org/namespaces/inkscape"
+	sodipodiNamespace = "http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+)
+
+// Layer describes an Inkscape layer, which is a group with the
+// inkscape:groupmode="layer" attribute.
+type Layer struct {
+	ID     string
+	Label  string
+	Hidden bool
+	Locked bool
+	Group  *Group
+}
+
+// inNamespace reports whether an XML name belongs to the namespace
+// given by its URI, or by its prefix if the document did not declare it.
+func inNamespace(name xml.Name, prefix, uri string) bool {
+	return name.Space == uri || name.Space == prefix
+}
+
+// OnlyLayers restricts ParseDrawingInstructions and ParseSegments to the
+// content of the layers with the given labels or IDs. Sublayers of a
+// selected layer are included as well.
+func OnlyLayers(names ...string) ParseOption {
+	return func(s *Svg) {
+		if s.layers == nil {
+			s.layers = make(map[string]bool)
+		}
+		for _, n := range names {
+			s.layers[n] = true
+		}
+	}
+}
+
+// Layers returns all layers of the document in document order,
+// including sublayers.
+func (s *Svg) Layers() []*Layer {
+	var layers []*Layer
+	for i := range s.Groups {
+		layers = s.Groups[i].appendLayers(layers)
+	}
+	return layers
+}
+
+func (g *Group) appendLayers(layers []*Layer) []*Layer {
+	if g.Layer != nil {
+		layers = append(layers, g.Layer)
+	}
+	for _, e := range g.Elements {
+		if sub, ok := e.(*Group); ok {
+			layers = sub.appendLayers(layers)
+		}
+	}
+	return layers
+}
+
+// inSelectedLayer reports whether the direct children of the group pass
+// the layer filter set with OnlyLayers.
+func (g *Group) inSelectedLayer() bool {
+	if g.Owner == nil || g.Owner.layers == nil {
+		return true
+	}
+	for l := g; l != nil; l = l.Parent {
+		if l.Layer != nil && (g.Owner.layers[l.Layer.Label] || g.Owner.layers[l.Layer.ID]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const layeredSvg = `<svg xmlns="http://www.w3.org/2000/svg"
+	xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+	xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+	viewBox="0 0 100 100">
+<path d="M0 0 L10 0"/>
+<g id="layer1" inkscape:groupmode="layer" inkscape:label="Cut">
+	<path d="M0 1 L10 1"/>
+	<g id="layer2" inkscape:groupmode="layer" inkscape:label="Holes" sodipodi:insensitive="true">
+		<path d="M0 2 L10 2"/>
+	</g>
+</g>
+<g id="layer3" inkscape:groupmode="layer" inkscape:label="Engrave" style="display:none">
+	<path d="M0 3 L10 3"/>
+</g>
+<g id="layer4" inkscape:groupmode="layer" inkscape:label="Score">
+	<path d="M0 4 L10 4"/>
+</g>
+</svg>`
+
+func TestLayers(t *testing.T) {
+	s, err := ParseSvg(layeredSvg, "layers", 0)
+	require.NoError(t, err)
+
+	layers := s.Layers()
+	require.Len(t, layers, 4)
+	var labels []string
+	for _, l := range layers {
+		labels = append(labels, l.Label)
+	}
+	require.Equal(t, []string{"Cut", "Holes", "Engrave", "Score"}, labels)
+	require.Equal(t, "layer2", layers[1].ID)
+	require.True(t, layers[1].Locked)
+	require.False(t, layers[0].Locked)
+	require.True(t, layers[2].Hidden)
+	require.Same(t, &s.Groups[0], layers[0].Group)
+}
+
+func TestOnlyLayers(t *testing.T) {
+	s, err := ParseSvg(layeredSvg, "layers", 0, OnlyLayers("Cut", "layer4"))
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 2, 4}, movedToY(t, s))
+
+	s, err = ParseSvg(layeredSvg, "layers", 0, OnlyLayers("Engrave"), IncludeHidden())
+	require.NoError(t, err)
+	require.Equal(t, []float64{3}, movedToY(t, s))
+
+	s, err = ParseSvg(layeredSvg, "layers", 1, OnlyLayers("Holes"))
+	require.NoError(t, err)
+	segs, errs := s.ParseSegments()
+	var got []Segment
+	for seg := range segs {
+		got = append(got, seg)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, []Segment{{Width: 1, Points: [][2]float64{{0, 2}, {10, 2}}}}, got)
+}
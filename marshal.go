@@ -0,0 +1,573 @@
+package svg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+// xmlSource remembers how an element was written in the parsed document
+// so it can be written back with as few changes as possible.
+type xmlSource struct {
+	name   xml.Name
+	attrs  []xml.Attr
+	parsed map[xml.Name]string
+}
+
+// rawElement holds the tokens of an element the parser does not
+// interpret, from its start to its end element.
+type rawElement []xml.Token
+
+// element is implemented by all elements which can be written back.
+type element interface {
+	xmlName() xml.Name
+	fieldAttrs() []xml.Attr
+	attributes() Attributes
+	xmlSource() **xmlSource
+}
+
+// newXMLSource records the start element of e, which must have been
+// decoded from it.
+func newXMLSource(e element, start xml.StartElement) *xmlSource {
+	src := &xmlSource{name: start.Name, parsed: make(map[xml.Name]string)}
+	src.attrs = append(src.attrs, start.Attr...)
+	*e.xmlSource() = src
+	for _, a := range e.fieldAttrs() {
+		src.parsed[a.Name] = a.Value
+	}
+	return src
+}
+
+// setSource records the start element an element was decoded from.
+func setSource(e interface{}, start xml.StartElement) {
+	if el, ok := e.(element); ok {
+		newXMLSource(el, start)
+	}
+}
+
+// has reports whether the element was written with the attribute.
+func (src *xmlSource) has(name string) bool {
+	if src == nil {
+		return false
+	}
+	for _, a := range src.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+func readRawElement(decoder *xml.Decoder, start xml.StartElement) (rawElement, error) {
+	raw := rawElement{start.Copy()}
+	for depth := 1; depth > 0; {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		raw = append(raw, xml.CopyToken(token))
+	}
+	return raw, nil
+}
+
+// formatFloat formats a float field whose zero value means the
+// attribute is not set. Zero is written for an attribute the element
+// was parsed with, so setting it to 0 is not lost.
+func (src *xmlSource) formatFloat(name string, f float64) string {
+	if f == 0 && !src.has(name) {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatFloatPtr(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+func formatStringPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// startElement builds the start element of an element. Attributes are
+// written in their original order keeping their original text unless
+// they were changed. New attributes follow in a stable order.
+func startElement(e element) xml.StartElement {
+	src := *e.xmlSource()
+	start := xml.StartElement{Name: e.xmlName()}
+	if src != nil {
+		start.Name = src.name
+	}
+
+	fields := make(map[xml.Name]string)
+	var fieldOrder []xml.Name
+	for _, a := range e.fieldAttrs() {
+		fields[a.Name] = a.Value
+		fieldOrder = append(fieldOrder, a.Name)
+	}
+	attrs := e.attributes()
+	written := make(map[xml.Name]bool)
+
+	if src != nil {
+		for _, a := range src.attrs {
+			if v, ok := fields[a.Name]; ok {
+				switch {
+				case v == src.parsed[a.Name]:
+					start.Attr = append(start.Attr, a)
+				case v != "":
+					start.Attr = append(start.Attr, xml.Attr{Name: a.Name, Value: v})
+				}
+			} else if v, ok := attrs[a.Name]; ok {
+				start.Attr = append(start.Attr, xml.Attr{Name: a.Name, Value: v})
+			}
+			written[a.Name] = true
+		}
+	}
+
+	for _, n := range fieldOrder {
+		v := fields[n]
+		if written[n] || v == "" || (src != nil && v == src.parsed[n]) {
+			continue
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: n, Value: v})
+	}
+
+	var names []xml.Name
+	for n := range attrs {
+		if !written[n] {
+			names = append(names, n)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Space != names[j].Space {
+			return names[i].Space < names[j].Space
+		}
+		return names[i].Local < names[j].Local
+	})
+	for _, n := range names {
+		start.Attr = append(start.Attr, xml.Attr{Name: n, Value: attrs[n]})
+	}
+	return start
+}
+
+func (s *Svg) xmlName() xml.Name         { return xml.Name{Local: "svg"} }
+func (s *Svg) attributes() Attributes    { return s.Attrs }
+func (s *Svg) xmlSource() **xmlSource    { return &s.source }
+func (g *Group) xmlName() xml.Name       { return xml.Name{Local: "g"} }
+func (g *Group) attributes() Attributes  { return g.Attrs }
+func (g *Group) xmlSource() **xmlSource  { return &g.source }
+func (p *Path) xmlName() xml.Name        { return xml.Name{Local: "path"} }
+func (p *Path) attributes() Attributes   { return p.Attrs }
+func (p *Path) xmlSource() **xmlSource   { return &p.source }
+func (c *Circle) xmlName() xml.Name      { return xml.Name{Local: "circle"} }
+func (c *Circle) attributes() Attributes { return c.Attrs }
+func (c *Circle) xmlSource() **xmlSource { return &c.source }
+func (r *Rect) xmlName() xml.Name        { return xml.Name{Local: "rect"} }
+func (r *Rect) attributes() Attributes   { return r.Attrs }
+func (r *Rect) xmlSource() **xmlSource   { return &r.source }
+
+func (s *Svg) fieldAttrs() []xml.Attr {
+	return []xml.Attr{
+		attr("width", s.Width),
+		attr("height", s.Height),
+		attr("viewBox", s.ViewBox),
+	}
+}
+
+func (g *Group) fieldAttrs() []xml.Attr {
+	return []xml.Attr{
+		attr("id", g.ID),
+		attr("stroke", g.Stroke),
+		attr("stroke-width", g.source.formatFloat("stroke-width", g.StrokeWidth)),
+		attr("fill", g.Fill),
+		attr("opacity", g.source.formatFloat("opacity", g.Opacity)),
+		attr("fill-rule", g.FillRule),
+		attr("style", g.Style),
+		attr("display", g.Display),
+		attr("visibility", g.Visibility),
+		attr("transform", g.TransformString),
+	}
+}
+
+func (p *Path) fieldAttrs() []xml.Attr {
+	return []xml.Attr{
+		attr("id", p.ID),
+		attr("d", p.D),
+		attr("style", p.Style),
+		attr("transform", p.TransformString),
+		attr("stroke-width", p.source.formatFloat("stroke-width", p.StrokeWidth)),
+		attr("fill", formatStringPtr(p.Fill)),
+		attr("opacity", formatFloatPtr(p.Opacity)),
+		attr("stroke", formatStringPtr(p.Stroke)),
+		attr("stroke-linecap", formatStringPtr(p.StrokeLineCap)),
+		attr("stroke-linejoin", formatStringPtr(p.StrokeLineJoin)),
+		attr("stroke-miterlimit", formatFloatPtr(p.StrokeMiterLimit)),
+		attr("fill-rule", formatStringPtr(p.FillRule)),
+		attr("display", p.Display),
+		attr("visibility", p.Visibility),
+	}
+}
+
+func (c *Circle) fieldAttrs() []xml.Attr {
+	return []xml.Attr{
+		attr("id", c.ID),
+		attr("transform", c.Transform),
+		attr("style", c.Style),
+		attr("cx", strconv.FormatFloat(c.Cx, 'f', -1, 64)),
+		attr("cy", strconv.FormatFloat(c.Cy, 'f', -1, 64)),
+		attr("r", strconv.FormatFloat(c.Radius, 'f', -1, 64)),
+		attr("fill", c.Fill),
+		attr("display", c.Display),
+		attr("visibility", c.Visibility),
+	}
+}
+
+func (r *Rect) fieldAttrs() []xml.Attr {
+	return []xml.Attr{
+		attr("id", r.ID),
+		attr("x", r.X),
+		attr("y", r.Y),
+		attr("width", r.Width),
+		attr("height", r.Height),
+		attr("transform", r.Transform),
+		attr("style", r.Style),
+		attr("rx", r.Rx),
+		attr("ry", r.Ry),
+		attr("display", r.Display),
+		attr("visibility", r.Visibility),
+	}
+}
+
+// tokenWriter receives the tokens of a serialised document. It is
+// implemented by xml.Encoder and textWriter.
+type tokenWriter interface {
+	EncodeToken(t xml.Token) error
+}
+
+// serializer turns namespace URIs back into the prefixes declared in the
+// document before passing tokens on to a tokenWriter.
+type serializer struct {
+	w      tokenWriter
+	scopes []map[string]string
+}
+
+func newSerializer(w tokenWriter) *serializer {
+	return &serializer{w: w}
+}
+
+func (s *serializer) prefix(uri string, allowDefault bool) (string, bool) {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if p, ok := s.scopes[i][uri]; ok && (p != "" || allowDefault) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func (s *serializer) qualify(n xml.Name, isAttr bool) xml.Name {
+	switch {
+	case n.Space == "":
+		return n
+	case isAttr && n.Space == "xmlns":
+		return xml.Name{Local: "xmlns:" + n.Local}
+	case n.Space == xmlNamespace:
+		return xml.Name{Local: "xml:" + n.Local}
+	}
+	if p, ok := s.prefix(n.Space, !isAttr); ok {
+		if p == "" {
+			return xml.Name{Local: n.Local}
+		}
+		return xml.Name{Local: p + ":" + n.Local}
+	}
+	if n.Space == svgNamespace && !isAttr {
+		return xml.Name{Local: n.Local}
+	}
+	for p, uri := range knownNamespaces {
+		if uri == n.Space {
+			return xml.Name{Local: p + ":" + n.Local}
+		}
+	}
+	return xml.Name{Local: n.Space + ":" + n.Local}
+}
+
+func (s *serializer) token(t xml.Token) error {
+	switch tok := t.(type) {
+	case xml.StartElement:
+		scope := make(map[string]string)
+		for _, a := range tok.Attr {
+			switch {
+			case a.Name.Space == "xmlns":
+				scope[a.Value] = a.Name.Local
+			case a.Name.Space == "" && a.Name.Local == "xmlns":
+				scope[a.Value] = ""
+			}
+		}
+		s.scopes = append(s.scopes, scope)
+		out := xml.StartElement{Name: s.qualify(tok.Name, false)}
+		for _, a := range tok.Attr {
+			out.Attr = append(out.Attr, xml.Attr{Name: s.qualify(a.Name, true), Value: a.Value})
+		}
+		return s.w.EncodeToken(out)
+	case xml.EndElement:
+		name := s.qualify(tok.Name, false)
+		if len(s.scopes) > 0 {
+			s.scopes = s.scopes[:len(s.scopes)-1]
+		}
+		return s.w.EncodeToken(xml.EndElement{Name: name})
+	}
+	return s.w.EncodeToken(t)
+}
+
+// writeLeaf writes an element without children.
+func (s *serializer) writeLeaf(e element) error {
+	start := startElement(e)
+	if err := s.token(start); err != nil {
+		return err
+	}
+	return s.token(start.End())
+}
+
+// writeChild writes an entry of the content of an Svg or Group.
+func (s *serializer) writeChild(c interface{}) error {
+	switch c := c.(type) {
+	case *Svg:
+		return c.writeXML(s)
+	case *Group:
+		return c.writeXML(s)
+	case element:
+		return s.writeLeaf(c)
+	case rawElement:
+		for _, t := range c {
+			if err := s.token(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	case xml.Token:
+		return s.token(c)
+	}
+	return fmt.Errorf("cannot write %T", c)
+}
+
+// writeElements writes the content of an Svg or Group. Elements which
+// were removed from elements are left out and elements which were added
+// are written at the end.
+func (s *serializer) writeElements(content []interface{}, elements []DrawingInstructionParser) error {
+	current := make(map[DrawingInstructionParser]bool)
+	for _, e := range elements {
+		current[e] = true
+	}
+	written := make(map[DrawingInstructionParser]bool)
+	for _, c := range content {
+		if e, ok := c.(DrawingInstructionParser); ok {
+			if !current[e] {
+				continue
+			}
+			written[e] = true
+		}
+		if err := s.writeChild(c); err != nil {
+			return err
+		}
+	}
+	for _, e := range elements {
+		if !written[e] {
+			if err := s.writeChild(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Group) writeXML(s *serializer) error {
+	start := startElement(g)
+	if err := s.token(start); err != nil {
+		return err
+	}
+	if err := s.writeElements(g.content, g.Elements); err != nil {
+		return err
+	}
+	return s.token(start.End())
+}
+
+func (svg *Svg) writeXML(s *serializer) error {
+	start := startElement(svg)
+	if svg.source == nil {
+		if _, ok := svg.Attrs[xml.Name{Local: "xmlns"}]; !ok {
+			start.Attr = append([]xml.Attr{attr("xmlns", svgNamespace)}, start.Attr...)
+		}
+	}
+	if err := s.token(start); err != nil {
+		return err
+	}
+
+	current := svg.currentGroups()
+	content := make([]interface{}, len(svg.content))
+	for i, c := range svg.content {
+		if g, ok := c.(*Group); ok {
+			c = current(g)
+		}
+		content[i] = c
+	}
+	if err := s.writeElements(content, svg.children()); err != nil {
+		return err
+	}
+	return s.token(start.End())
+}
+
+// MarshalXML implements the encoding.xml.Marshaler interface
+func (s *Svg) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return s.writeXML(newSerializer(e))
+}
+
+// MarshalXML implements the encoding.xml.Marshaler interface
+func (g *Group) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return g.writeXML(newSerializer(e))
+}
+
+// MarshalXML implements the encoding.xml.Marshaler interface
+func (p *Path) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return newSerializer(e).writeLeaf(p)
+}
+
+// MarshalXML implements the encoding.xml.Marshaler interface
+func (c *Circle) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return newSerializer(e).writeLeaf(c)
+}
+
+// MarshalXML implements the encoding.xml.Marshaler interface
+func (r *Rect) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return newSerializer(e).writeLeaf(r)
+}
+
+// WriteTo writes the document as SVG including the XML declaration,
+// doctype and comments surrounding the root element. It implements the
+// io.WriterTo interface.
+func (s *Svg) WriteTo(w io.Writer) (int64, error) {
+	tw := newTextWriter(w)
+	ser := newSerializer(tw)
+	for _, t := range s.prolog {
+		if err := ser.token(t); err != nil {
+			return tw.n, err
+		}
+	}
+	if err := s.writeXML(ser); err != nil {
+		return tw.n, err
+	}
+	for _, t := range s.epilog {
+		if err := ser.token(t); err != nil {
+			return tw.n, err
+		}
+	}
+	err := tw.Flush()
+	return tw.n, err
+}
+
+// textWriter writes tokens as XML text. Unlike xml.Encoder it writes
+// elements without content as empty-element tags and leaves white space
+// in attribute values alone, which keeps the output close to the way
+// SVG files are usually written.
+type textWriter struct {
+	w       *bufio.Writer
+	n       int64
+	pending *xml.StartElement
+}
+
+func newTextWriter(w io.Writer) *textWriter {
+	tw := &textWriter{}
+	tw.w = bufio.NewWriter(writerFunc(func(p []byte) (int, error) {
+		n, err := w.Write(p)
+		tw.n += int64(n)
+		return n, err
+	}))
+	return tw
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func (tw *textWriter) writeStart(close string) {
+	tw.w.WriteString("<" + tw.pending.Name.Local)
+	for _, a := range tw.pending.Attr {
+		tw.w.WriteString(" " + a.Name.Local + `="` + attrEscaper.Replace(a.Value) + `"`)
+	}
+	tw.w.WriteString(close)
+	tw.pending = nil
+}
+
+func (tw *textWriter) flushPending() {
+	if tw.pending != nil {
+		tw.writeStart(">")
+	}
+}
+
+// EncodeToken writes a token whose names are already qualified.
+func (tw *textWriter) EncodeToken(t xml.Token) error {
+	switch tok := t.(type) {
+	case xml.StartElement:
+		tw.flushPending()
+		start := tok.Copy()
+		tw.pending = &start
+	case xml.EndElement:
+		if tw.pending != nil && tw.pending.Name == tok.Name {
+			tw.writeStart("/>")
+			break
+		}
+		tw.flushPending()
+		tw.w.WriteString("</" + tok.Name.Local + ">")
+	case xml.CharData:
+		tw.flushPending()
+		tw.w.WriteString(textEscaper.Replace(string(tok)))
+	case xml.Comment:
+		tw.flushPending()
+		tw.w.WriteString("<!--" + string(tok) + "-->")
+	case xml.ProcInst:
+		tw.flushPending()
+		if len(tok.Inst) == 0 {
+			tw.w.WriteString("<?" + tok.Target + "?>")
+		} else {
+			tw.w.WriteString("<?" + tok.Target + " " + string(tok.Inst) + "?>")
+		}
+	case xml.Directive:
+		tw.flushPending()
+		tw.w.WriteString("<!" + string(tok) + ">")
+	default:
+		return fmt.Errorf("cannot write token %T", t)
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (tw *textWriter) Flush() error {
+	tw.flushPending()
+	return tw.w.Flush()
+}
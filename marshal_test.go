@@ -0,0 +1,108 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const roundTripSvg = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!-- Created with Inkscape -->
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" xmlns:cad="http://example.com/cad" width="100mm" height="50mm" viewBox="0 0 100 50" version="1.1">
+  <title>Round &amp; trip</title>
+  <defs id="defs2"><linearGradient id="lg"><stop offset="0"/></linearGradient></defs>
+  <path d="M 0,0 L 10,10" id="top" stroke-width="2.50"/>
+  <!-- cut layer -->
+  <g inkscape:label="Cut" inkscape:groupmode="layer" id="layer1" transform="translate(1,2)">
+    <rect width="10" height="5" x="1" y="1" data-power="80"/>
+    <a href="#top"><circle cx="5" cy="5" r="2" cad:tool="t1"/></a>
+    <text x="0" y="0">Label &lt;1&gt;</text>
+    <g id="inner" style="display:inline"><path d="m 1,1 h 5" style="stroke-width:0.3"/></g>
+  </g>
+  <circle cx="1" cy="2" r="3"/>
+</svg>
+`
+
+func TestWriteToRoundTrip(t *testing.T) {
+	s, err := ParseSvg(roundTripSvg, "roundtrip", 1)
+	require.NoError(t, err)
+
+	// parsing drawing instructions must not change the written document
+	dis, _ := s.ParseDrawingInstructions()
+	for range dis {
+	}
+
+	var buf bytes.Buffer
+	n, err := s.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+	require.Equal(t, roundTripSvg, buf.String())
+}
+
+func TestWriteToEdited(t *testing.T) {
+	s, err := ParseSvg(roundTripSvg, "edited", 1)
+	require.NoError(t, err)
+
+	top := s.Elements[0].(*Path)
+	top.D = "M 0,0 L 20,20"
+	top.Attrs.Set(xml.Name{Local: "data-speed"}, "1200")
+	s.Elements = s.Elements[:1]
+
+	g := &s.Groups[0]
+	g.Attrs.Set(xml.Name{Space: inkscapeNamespace, Local: "label"}, "Engrave")
+	fill := "red"
+	g.Elements = append(g.Elements, &Path{D: "M0 0", Fill: &fill})
+
+	var buf bytes.Buffer
+	_, err = s.WriteTo(&buf)
+	require.NoError(t, err)
+	out := buf.String()
+
+	require.Contains(t, out, `<path d="M 0,0 L 20,20" id="top" stroke-width="2.50" data-speed="1200"/>`)
+	require.Contains(t, out, `<g inkscape:label="Engrave" inkscape:groupmode="layer"`)
+	require.Contains(t, out, `<path d="M0 0" fill="red"/></g>`)
+	require.NotContains(t, out, `<circle cx="1" cy="2" r="3"/>`)
+
+	reparsed, err := ParseSvg(out, "reparsed", 1)
+	require.NoError(t, err)
+	require.Equal(t, "Engrave", reparsed.Layers()[0].Label)
+}
+
+func TestMarshalXML(t *testing.T) {
+	s, err := ParseSvg(roundTripSvg, "marshal", 1)
+	require.NoError(t, err)
+
+	out, err := xml.Marshal(s)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(out), `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape=`))
+	require.Contains(t, string(out), `<circle cx="5" cy="5" r="2" cad:tool="t1"></circle>`)
+
+	out, err = xml.Marshal(s.Groups[0].Elements[0])
+	require.NoError(t, err)
+	require.Equal(t, `<rect width="10" height="5" x="1" y="1" data-power="80"></rect>`, string(out))
+
+	fill := "none"
+	out, err = xml.Marshal(&Svg{Elements: []DrawingInstructionParser{&Path{D: "M0 0 L1 1", Fill: &fill}}})
+	require.NoError(t, err)
+	require.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0 L1 1" fill="none"></path></svg>`, string(out))
+}
+
+func TestWriteToExplicitZero(t *testing.T) {
+	s, err := ParseSvg(`<svg><g opacity="0.5" stroke-width="2"><path d="M0 0 H1" stroke-width="3"/></g><g><path d="M0 0"/></g></svg>`, "zero", 1)
+	require.NoError(t, err)
+	s.Groups[0].Opacity = 0
+	s.Groups[0].StrokeWidth = 0
+	s.Groups[0].Elements[0].(*Path).StrokeWidth = 0
+
+	var buf bytes.Buffer
+	_, err = s.WriteTo(&buf)
+	require.NoError(t, err)
+	out := buf.String()
+	require.Contains(t, out, `<g opacity="0" stroke-width="0"><path d="M0 0 H1" stroke-width="0"/></g>`)
+	// Fields that were never set stay unwritten.
+	require.Contains(t, out, `<g><path d="M0 0"/></g>`)
+}
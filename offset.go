@@ -0,0 +1,60 @@
+package svg
+
+// positive is the fill rule used to clean up raw offsets: only areas
+// wound counter-clockwise more often than clockwise are inside.
+const positive FillRule = -1
+
+// OffsetOptions control the shape of the corners of an offset.
+type OffsetOptions struct {
+	// Join is the shape of convex corners: "miter" (the default),
+	// "round", "bevel" or "square".
+	Join string
+	// MiterLimit bevels miter joins longer than MiterLimit times the
+	// distance. Zero means 4.
+	MiterLimit float64
+	// Tolerance is the maximum distance between a round join and its
+	// flattened outline. Zero picks one relative to the distance.
+	Tolerance float64
+	// FillRule decides which parts of the input are inside.
+	FillRule FillRule
+}
+
+// Offset returns the outlines of the area of segments grown by distance,
+// or shrunk when distance is negative, as needed for kerf and tool
+// radius compensation. Segments are treated as closed; holes are told
+// apart by the fill rule, so they shrink when the area grows and the
+// other way round. Like Clip, the result has outer boundaries with a
+// positive signed area and holes with a negative one.
+func Offset(segments []Segment, distance float64, opts OffsetOptions) []Segment {
+	region := Clip(Union, segments, opts.FillRule, nil, NonZero)
+	if distance == 0 || len(region) == 0 {
+		return region
+	}
+
+	o := &outliner{hw: distance, join: opts.Join, miterLimit: opts.MiterLimit, tolerance: opts.Tolerance, pivot: true}
+	if distance < 0 {
+		o.hw = -distance
+	}
+	if o.miterLimit < 1 {
+		o.miterLimit = defaultMiterLimit
+	}
+	if o.tolerance <= 0 {
+		o.tolerance = o.hw / 100
+	}
+
+	// The boundaries of the region have the area on their left, so the
+	// offset to the left shrinks it and the one to the right grows it.
+	// Where the offset overshoots, the raw outline winds the other way
+	// and is dropped by the positive fill rule.
+	raw := make([]Segment, 0, len(region))
+	for _, s := range region {
+		var pts [][2]float64
+		if distance < 0 {
+			pts = o.loop(s.Points)
+		} else {
+			pts = reversed(o.loop(reversed(s.Points)))
+		}
+		raw = append(raw, Segment{Closed: true, Points: pts})
+	}
+	return Clip(Union, raw, positive, nil, NonZero)
+}
@@ -0,0 +1,89 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetJoins(t *testing.T) {
+	square := []Segment{rect(0, 0, 10, 10)}
+	for _, tc := range []struct {
+		join     string
+		distance float64
+		area     float64
+	}{
+		{"miter", 1, 144},
+		{"bevel", 1, 142},
+		{"square", 1, 144 - 4*(math.Sqrt2-1)*(math.Sqrt2-1)},
+		{"round", 1, 140 + math.Pi},
+		{"miter", -1, 64},
+		{"round", -1, 64},
+		{"miter", -6, 0},
+		{"miter", 0, 100},
+	} {
+		result := Offset(square, tc.distance, OffsetOptions{Join: tc.join, Tolerance: 1e-6})
+		require.InDelta(t, tc.area, signedArea(result), 1e-4, "%s %v", tc.join, tc.distance)
+	}
+
+	// With a miter limit of 1 every corner is beveled.
+	result := Offset(square, 1, OffsetOptions{MiterLimit: 1})
+	require.InDelta(t, 142, signedArea(result), 1e-9)
+}
+
+func TestOffsetConcave(t *testing.T) {
+	l := []Segment{{Closed: true, Points: [][2]float64{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}}}
+
+	grown := Offset(l, 0.5, OffsetOptions{})
+	require.Len(t, grown, 1)
+	require.ElementsMatch(t, [][2]float64{{-0.5, -0.5}, {2.5, -0.5}, {2.5, 1.5}, {1.5, 1.5}, {1.5, 2.5}, {-0.5, 2.5}}, grown[0].Points)
+
+	shrunk := Offset(l, -0.25, OffsetOptions{})
+	require.InDelta(t, 1.25, signedArea(shrunk), 1e-9)
+
+	// Five convex quarter circles, and the concave corner where the
+	// offsets of both edges overlap.
+	round := Offset(l, 0.5, OffsetOptions{Join: "round", Tolerance: 1e-6})
+	require.InDelta(t, 3+8*0.5+5*math.Pi/4*0.25-0.25, signedArea(round), 1e-4)
+
+	// Shrinking by more than half the width of the arms splits nothing
+	// off but removes everything.
+	require.Empty(t, Offset(l, -0.6, OffsetOptions{}))
+}
+
+func TestOffsetHoles(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		segments []Segment
+		rule     FillRule
+	}{
+		{"evenodd", []Segment{rect(0, 0, 10, 10), rect(3, 3, 7, 7)}, EvenOdd},
+		{"winding", []Segment{rect(0, 0, 10, 10), reverse(rect(3, 3, 7, 7))}, NonZero},
+	} {
+		grown := Offset(tc.segments, 1, OffsetOptions{FillRule: tc.rule})
+		require.InDelta(t, 144-4, signedArea(grown), 1e-9, tc.name)
+		require.Len(t, grown, 2, tc.name)
+
+		// The hole closes once it has shrunk away.
+		require.InDelta(t, 225, signedArea(Offset(tc.segments, 2.5, OffsetOptions{FillRule: tc.rule})), 1e-9, tc.name)
+		require.Len(t, Offset(tc.segments, 2.5, OffsetOptions{FillRule: tc.rule}), 1, tc.name)
+
+		shrunk := Offset(tc.segments, -1, OffsetOptions{FillRule: tc.rule})
+		require.InDelta(t, 64-36, signedArea(shrunk), 1e-9, tc.name)
+	}
+}
+
+func TestOffsetRoundTrip(t *testing.T) {
+	// Growing and then shrinking by the same distance with round joins
+	// gives back a convex shape up to the arc tolerance.
+	var pts [][2]float64
+	for i := 0; i < 7; i++ {
+		a := float64(i) * 2 * math.Pi / 7
+		pts = append(pts, [2]float64{5 * math.Cos(a), 5 * math.Sin(a)})
+	}
+	heptagon := []Segment{{Closed: true, Points: pts}}
+	opts := OffsetOptions{Join: "round", Tolerance: 1e-4}
+	back := Offset(Offset(heptagon, 2, opts), -2, opts)
+	require.InDelta(t, signedArea(heptagon), signedArea(back), 1e-2)
+}
@@ -0,0 +1,18 @@
+package svg
+
+// ParseOption changes how a parsed Svg behaves. Options are passed to
+// ParseSvg and ParseSvgFromReader.
+type ParseOption func(*Svg)
+
+// IncludeHidden makes ParseDrawingInstructions emit elements which are
+// hidden with display="none" or visibility="hidden". By default these
+// elements are skipped.
+func IncludeHidden() ParseOption {
+	return func(s *Svg) {
+		s.includeHidden = true
+	}
+}
+
+func (s *Svg) showsHidden() bool {
+	return s != nil && s.includeHidden
+}
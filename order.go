@@ -0,0 +1,318 @@
+package svg
+
+import "math"
+
+// OrderOptions control how OrderSegments orders segments.
+type OrderOptions struct {
+	// Start is the position of the tool before the first segment.
+	Start [2]float64
+	// Reverse allows open segments to be drawn from their last point.
+	Reverse bool
+	// ChooseStart allows closed segments to be drawn from any of their
+	// points rather than from their first one.
+	ChooseStart bool
+	// InsideFirst draws segments lying inside a closed segment before
+	// that segment, so inner cuts are made while the part is still held
+	// by the material around it.
+	InsideFirst bool
+	// TwoOpt improves the nearest neighbour order by reversing runs of
+	// segments while that shortens the travel. Each round takes time
+	// quadratic in the number of segments.
+	TwoOpt bool
+}
+
+// orderItem is a segment being ordered, with the point it is entered
+// and left at for the way it is currently drawn.
+type orderItem struct {
+	seg         Segment
+	box         Box
+	entry, exit [2]float64
+	vertex      int
+	reversed    bool
+	reversible  bool
+	containers  []int
+	contained   []int
+	blockers    int
+}
+
+// OrderSegments returns the segments in an order that keeps the travel
+// between them short, each drawn in one piece. Open segments may be
+// reversed and closed segments started at another point as allowed by
+// the options; closed segments keep their direction. Segments without
+// points are moved to the end.
+func OrderSegments(segments []Segment, opts OrderOptions) []Segment {
+	var items []*orderItem
+	var empty []Segment
+	for _, s := range segments {
+		if len(s.Points) == 0 {
+			empty = append(empty, s)
+			continue
+		}
+		it := &orderItem{seg: s, box: pointsBox(s.Points), reversible: s.Closed || opts.Reverse}
+		it.entry, it.exit = s.Points[0], s.Points[len(s.Points)-1]
+		if s.Closed {
+			it.exit = it.entry
+		}
+		items = append(items, it)
+	}
+	if opts.InsideFirst {
+		constrainInsideFirst(items)
+	}
+
+	order := nearestNeighbourOrder(items, opts)
+	if opts.TwoOpt {
+		twoOpt(items, order, opts.Start)
+	}
+	if opts.ChooseStart {
+		pos := opts.Start
+		for k, i := range order {
+			it := items[i]
+			if it.seg.Closed {
+				next := it.entry
+				if k+1 < len(order) {
+					next = items[order[k+1]].entry
+				}
+				it.setVertex(it.bestVertex(pos, next))
+			}
+			pos = it.exit
+		}
+	}
+
+	out := make([]Segment, 0, len(segments))
+	for _, i := range order {
+		out = append(out, items[i].segment())
+	}
+	return append(out, empty...)
+}
+
+// TravelLength returns the distance travelled between drawing the
+// segments in order, starting at start. Closed segments are left where
+// they started.
+func TravelLength(segments []Segment, start [2]float64) float64 {
+	var d float64
+	pos := start
+	for _, s := range segments {
+		if len(s.Points) == 0 {
+			continue
+		}
+		d += vlen(vsub(s.Points[0], pos))
+		pos = s.Points[len(s.Points)-1]
+		if s.Closed {
+			pos = s.Points[0]
+		}
+	}
+	return d
+}
+
+func pointsBox(pts [][2]float64) Box {
+	b := emptyBox
+	for _, p := range pts {
+		b = b.extend(p)
+	}
+	return b
+}
+
+// constrainInsideFirst records which segments lie inside which closed
+// segments. Of two segments with the same box the earlier one counts as
+// inside, so the constraints never form a cycle.
+func constrainInsideFirst(items []*orderItem) {
+	for i, outer := range items {
+		if !outer.seg.Closed || len(outer.seg.Points) < 3 {
+			continue
+		}
+		tol := hitTolerance(outer.box)
+		loop := []Segment{outer.seg}
+		for j, inner := range items {
+			if i == j || !within(inner.box, outer.box) {
+				continue
+			}
+			if inner.box == outer.box && j > i {
+				continue
+			}
+			if !inside(inner.seg.Points, loop, tol) {
+				continue
+			}
+			inner.containers = append(inner.containers, i)
+			outer.contained = append(outer.contained, j)
+			outer.blockers++
+		}
+	}
+}
+
+// within reports whether the box a lies within the box b.
+func within(a, b Box) bool {
+	return a.MinX >= b.MinX && a.MaxX <= b.MaxX && a.MinY >= b.MinY && a.MaxY <= b.MaxY
+}
+
+// inside reports whether no point lies outside the loop and at least
+// one lies inside it, points within tol of the loop counting as on it.
+func inside(pts [][2]float64, loop []Segment, tol float64) bool {
+	var in bool
+	for _, p := range pts {
+		if windingNumber(loop, p) != 0 {
+			in = true
+		} else if distanceToSegments(loop, p) > tol {
+			return false
+		}
+	}
+	return in
+}
+
+// nearestNeighbourOrder returns the indices of the items, each next one
+// being the one closest to where the previous one was left among those
+// whose contained items have all been drawn. The items are set up to be
+// drawn from their closest end or point.
+func nearestNeighbourOrder(items []*orderItem, opts OrderOptions) []int {
+	done := make([]bool, len(items))
+	order := make([]int, 0, len(items))
+	pos := opts.Start
+	for len(order) < len(items) {
+		best, bestDist, bestVertex, bestReversed := -1, math.Inf(1), 0, false
+		for i, it := range items {
+			if done[i] || it.blockers > 0 || it.box.distance(pos) >= bestDist {
+				continue
+			}
+			vertex, reversed := 0, false
+			var d float64
+			switch {
+			case it.seg.Closed && opts.ChooseStart:
+				vertex = it.bestVertex(pos, pos)
+				d = vlen(vsub(it.seg.Points[vertex], pos))
+			case it.seg.Closed:
+				d = vlen(vsub(it.seg.Points[0], pos))
+			default:
+				d = vlen(vsub(it.seg.Points[0], pos))
+				if opts.Reverse {
+					if dr := vlen(vsub(it.seg.Points[len(it.seg.Points)-1], pos)); dr < d {
+						d, reversed = dr, true
+					}
+				}
+			}
+			if d < bestDist {
+				best, bestDist, bestVertex, bestReversed = i, d, vertex, reversed
+			}
+		}
+		it := items[best]
+		if it.seg.Closed {
+			it.setVertex(bestVertex)
+		} else if bestReversed {
+			it.flip()
+		}
+		done[best] = true
+		for _, c := range it.containers {
+			items[c].blockers--
+		}
+		order = append(order, best)
+		pos = it.exit
+	}
+	return order
+}
+
+// twoOpt reverses runs of the order, drawing each item of a run the
+// other way round, as long as that shortens the travel. Runs holding an
+// item that cannot be reversed or an item together with one containing
+// it are left alone.
+func twoOpt(items []*orderItem, order []int, start [2]float64) {
+	n := len(order)
+	position := make([]int, len(items))
+	for k, i := range order {
+		position[i] = k
+	}
+	dist := func(a, b [2]float64) float64 { return vlen(vsub(a, b)) }
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n; i++ {
+			prev := start
+			if i > 0 {
+				prev = items[order[i-1]].exit
+			}
+			first := items[order[i]]
+		runs:
+			for j := i; j < n; j++ {
+				last := items[order[j]]
+				if !last.reversible {
+					break
+				}
+				for _, related := range [][]int{last.containers, last.contained} {
+					for _, c := range related {
+						if position[c] >= i && position[c] < j {
+							break runs
+						}
+					}
+				}
+				delta := dist(prev, last.exit) - dist(prev, first.entry)
+				if j+1 < n {
+					next := items[order[j+1]].entry
+					delta += dist(first.entry, next) - dist(last.exit, next)
+				}
+				if delta >= -1e-9*(1+dist(prev, first.entry)) {
+					continue
+				}
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					order[a], order[b] = order[b], order[a]
+				}
+				for k := i; k <= j; k++ {
+					items[order[k]].flip()
+					position[order[k]] = k
+				}
+				improved = true
+				break
+			}
+		}
+	}
+}
+
+// bestVertex returns the point of a closed segment to start drawing it
+// at when coming from prev and going on to next.
+func (it *orderItem) bestVertex(prev, next [2]float64) int {
+	pts := it.seg.Points
+	n := len(pts)
+	if n > 1 && pts[n-1] == pts[0] {
+		n--
+	}
+	best, bestDist := 0, math.Inf(1)
+	for k := 0; k < n; k++ {
+		d := vlen(vsub(pts[k], prev)) + vlen(vsub(pts[k], next))
+		if d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func (it *orderItem) setVertex(k int) {
+	it.vertex = k
+	it.entry = it.seg.Points[k]
+	it.exit = it.entry
+}
+
+// flip swaps the ends of an open segment. Closed segments are entered
+// and left at the same point and are not changed.
+func (it *orderItem) flip() {
+	if it.seg.Closed {
+		return
+	}
+	it.reversed = !it.reversed
+	it.entry, it.exit = it.exit, it.entry
+}
+
+// segment returns the segment of the item as it is to be drawn.
+func (it *orderItem) segment() Segment {
+	s := it.seg
+	switch {
+	case it.reversed:
+		s.Points = reversed(s.Points)
+	case it.vertex > 0:
+		pts := s.Points
+		n := len(pts)
+		repeated := pts[n-1] == pts[0]
+		if repeated {
+			n--
+		}
+		s.Points = append(append(make([][2]float64, 0, len(pts)), pts[it.vertex:n]...), pts[:it.vertex]...)
+		if repeated {
+			s.Points = append(s.Points, s.Points[0])
+		}
+	}
+	return s
+}
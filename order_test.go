@@ -0,0 +1,99 @@
+package svg
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func square(x, y, size float64) Segment {
+	return Segment{Closed: true, Points: [][2]float64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}}
+}
+
+func TestOrderSegments(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var segments []Segment
+	for i := 0; i < 300; i++ {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		segments = append(segments, Segment{Points: [][2]float64{{x, y}, {x + r.Float64()*20, y + r.Float64()*20}}})
+	}
+	before := TravelLength(segments, [2]float64{})
+
+	nn := OrderSegments(segments, OrderOptions{Reverse: true})
+	require.Len(t, nn, len(segments))
+	optimized := OrderSegments(segments, OrderOptions{Reverse: true, TwoOpt: true})
+	require.Len(t, optimized, len(segments))
+	require.Less(t, TravelLength(nn, [2]float64{}), before/3)
+	require.LessOrEqual(t, TravelLength(optimized, [2]float64{}), TravelLength(nn, [2]float64{}))
+
+	// Every segment is drawn once, possibly reversed.
+	seen := make(map[[2][2]float64]int)
+	for _, s := range segments {
+		seen[[2][2]float64{s.Points[0], s.Points[1]}]++
+	}
+	for _, s := range optimized {
+		key := [2][2]float64{s.Points[0], s.Points[1]}
+		if seen[key] == 0 {
+			key = [2][2]float64{s.Points[1], s.Points[0]}
+		}
+		seen[key]--
+		require.GreaterOrEqual(t, seen[key], 0)
+	}
+
+	// Without Reverse open segments keep their direction.
+	directed := make(map[[2][2]float64]bool)
+	for _, s := range segments {
+		directed[[2][2]float64{s.Points[0], s.Points[1]}] = true
+	}
+	for _, s := range OrderSegments(segments, OrderOptions{TwoOpt: true}) {
+		require.True(t, directed[[2][2]float64{s.Points[0], s.Points[1]}])
+	}
+}
+
+func TestOrderSegmentsReverse(t *testing.T) {
+	segments := []Segment{
+		{Points: [][2]float64{{10, 0}, {20, 0}}},
+		{Points: [][2]float64{{10, 1}, {0, 1}}},
+	}
+	ordered := OrderSegments(segments, OrderOptions{Reverse: true})
+	require.Equal(t, [][2]float64{{0, 1}, {10, 1}}, ordered[0].Points)
+	require.Equal(t, [][2]float64{{10, 0}, {20, 0}}, ordered[1].Points)
+	require.InDelta(t, 2, TravelLength(ordered, [2]float64{}), 1e-9)
+
+	ordered = OrderSegments(segments, OrderOptions{})
+	require.Equal(t, segments[1], ordered[1])
+}
+
+func TestOrderSegmentsChooseStart(t *testing.T) {
+	segments := []Segment{square(0, 0, 10), square(20, 0, 10)}
+	ordered := OrderSegments(segments, OrderOptions{Start: [2]float64{12, 12}, ChooseStart: true})
+	require.Equal(t, [][2]float64{{10, 10}, {0, 10}, {0, 0}, {10, 0}, {10, 10}}, ordered[0].Points)
+	require.Equal(t, [][2]float64{{20, 10}, {20, 0}, {30, 0}, {30, 10}, {20, 10}}, ordered[1].Points)
+	require.True(t, ordered[0].Closed)
+	require.InDelta(t, 2*1.4142135623730951+10, TravelLength(ordered, [2]float64{12, 12}), 1e-9)
+}
+
+func TestOrderSegmentsInsideFirst(t *testing.T) {
+	outer := square(0, 0, 100)
+	hole := square(40, 40, 20)
+	slot := Segment{Points: [][2]float64{{10, 80}, {10, 90}}}
+	other := square(200, 0, 10)
+	segments := []Segment{hole, outer, other, slot}
+
+	ordered := OrderSegments(segments, OrderOptions{Reverse: true, TwoOpt: true})
+	require.Equal(t, outer, ordered[0])
+
+	ordered = OrderSegments(segments, OrderOptions{Reverse: true, ChooseStart: true, InsideFirst: true, TwoOpt: true})
+	index := func(s Segment) int {
+		for i, o := range ordered {
+			if pointsBox(o.Points) == pointsBox(s.Points) {
+				return i
+			}
+		}
+		return -1
+	}
+	require.Less(t, index(hole), index(outer))
+	require.Less(t, index(slot), index(outer))
+	require.Len(t, ordered, 4)
+}
@@ -53,6 +53,22 @@ func parseTuple(l *gl.Lexer) (Tuple, error) {
 	return t, nil
 }
 
+// elementTransform returns the transform attribute of an element
+// combined with the transform of its group, which may be nil. Invalid
+// transform attributes are ignored.
+func elementTransform(g *Group, transform string) mt.Transform {
+	m := mt.Identity()
+	if g != nil && g.Transform != nil {
+		m = mt.MultiplyTransforms(m, *g.Transform)
+	}
+	if transform != "" {
+		if t, err := parseTransform(transform); err == nil {
+			m = mt.MultiplyTransforms(m, t)
+		}
+	}
+	return m
+}
+
 func parseTransform(tstring string) (mt.Transform, error) {
 	var x *mt.Transform
 	lexer, _ := gl.Lex("tlexer", tstring)
@@ -219,3 +219,80 @@ func TestParsedPathString(t *testing.T) {
 	t.Logf("parsed shape in %v", f1)
 	t.Log("Please check consistency of above files, with web browser or eog")
 }
+
+func TestDocumentOrder(t *testing.T) {
+	content := `<svg viewBox="0 0 100 100">
+	<path d="M0 0 L10 0"/>
+	<g><path d="M0 1 L10 1"/></g>
+	<path d="M0 2 L10 2"/>
+	<g><path d="M0 3 L10 3"/></g>
+	</svg>`
+	s, err := ParseSvg(content, "order", 1)
+	if err != nil {
+		t.Fatalf("cannot parse svg %v", content)
+	}
+	if len(s.Children) != 4 || len(s.Elements) != 2 || len(s.Groups) != 2 {
+		t.Fatalf("expected 4 children, 2 elements and 2 groups, got %d, %d and %d",
+			len(s.Children), len(s.Elements), len(s.Groups))
+	}
+	if s.Children[1] != &s.Groups[0] {
+		t.Fatalf("expected second child to be the first group")
+	}
+
+	got := movedToY(t, s)
+	if fmt.Sprint(got) != "[0 1 2 3]" {
+		t.Fatalf("expected paths in document order, got %v", got)
+	}
+
+	s.Elements = append(s.Elements[:1], &Path{D: "M0 4 L10 4"})
+	s.Groups = s.Groups[:1]
+	got = movedToY(t, s)
+	if fmt.Sprint(got) != "[0 1 4]" {
+		t.Fatalf("expected edited views to be honoured, got %v", got)
+	}
+}
+
+func TestDocumentOrderAfterAppendingGroup(t *testing.T) {
+	content := `<svg><path d="M0 0 L10 0"/><g><path d="M0 1 L10 1"/></g><path d="M0 2 L10 2"/></svg>`
+	s, err := ParseSvg(content, "append", 1)
+	if err != nil {
+		t.Fatalf("cannot parse svg %v", content)
+	}
+	// Appending to a full slice reallocates it.
+	s.Groups = append(s.Groups[:1:1], Group{Elements: []DrawingInstructionParser{&Path{D: "M0 3 L10 3"}}})
+
+	got := movedToY(t, s)
+	if fmt.Sprint(got) != "[0 1 2 3]" {
+		t.Fatalf("expected parsed groups to keep their place, got %v", got)
+	}
+
+	var b strings.Builder
+	if _, err := s.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !(strings.Index(out, "M0 1") < strings.Index(out, "M0 2") && strings.Index(out, "M0 2") < strings.Index(out, "M0 3")) {
+		t.Fatalf("expected groups written in document order, got %s", out)
+	}
+}
+
+func TestGroupPaintNotShared(t *testing.T) {
+	content := `<svg viewBox="0 0 100 100">
+	<g stroke="blue">
+		<path d="M0 0 L10 0" stroke="red"/>
+		<path d="M0 1 L10 1"/>
+	</g>
+	</svg>`
+	s, err := ParseSvg(content, "paint", 1)
+	if err != nil {
+		t.Fatalf("cannot parse svg %v", content)
+	}
+	g := &s.Groups[0]
+	if g.Stroke != "blue" {
+		t.Fatalf("expected the group stroke to stay blue, got %q", g.Stroke)
+	}
+	second := g.Elements[1].(*Path)
+	if *second.Stroke != "blue" {
+		t.Fatalf("expected the second path to inherit blue, got %q", *second.Stroke)
+	}
+}
@@ -3,888 +3,483 @@ package svg
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	mt "github.com/rustyoz/Mtransform"
-	gl "github.com/rustyoz/genericlexer"
 )
 
 // Path is an SVG XML path element
 type Path struct {
-	ID              string `xml:"id,attr"`
-	D               string `xml:"d,attr"`
-	Style           string `xml:"style,attr"`
-	TransformString string `xml:"transform,attr"`
-	properties      map[string]string
-	StrokeWidth     float64 `xml:"stroke-width,attr"`
-	Fill            *string `xml:"fill,attr"`
-	Opacity			*float64 `xml:"opacity,attr"`
-	Stroke          *string `xml:"stroke,attr"`
-	StrokeLineCap   *string `xml:"stroke-linecap,attr"`
-	StrokeLineJoin  *string `xml:"stroke-linejoin,attr"`
-	Segments        chan Segment
-	instructions    chan *DrawingInstruction
-	errors          chan error
-	group           *Group
+	ID               string `xml:"id,attr"`
+	D                string `xml:"d,attr"`
+	Style            string `xml:"style,attr"`
+	TransformString  string `xml:"transform,attr"`
+	properties       map[string]string
+	StrokeWidth      float64    `xml:"stroke-width,attr"`
+	Fill             *string    `xml:"fill,attr"`
+	Opacity          *float64   `xml:"opacity,attr"`
+	Stroke           *string    `xml:"stroke,attr"`
+	StrokeLineCap    *string    `xml:"stroke-linecap,attr"`
+	StrokeLineJoin   *string    `xml:"stroke-linejoin,attr"`
+	StrokeMiterLimit *float64   `xml:"stroke-miterlimit,attr"`
+	FillRule         *string    `xml:"fill-rule,attr"`
+	Display          string     `xml:"display,attr"`
+	Visibility       string     `xml:"visibility,attr"`
+	Attrs            Attributes `xml:",any,attr"`
+	Segments         chan Segment
+	instructions     chan *DrawingInstruction
+	errors           chan error
+	group            *Group
+	source           *xmlSource
 }
 
 // A Segment of a path that contains a list of connected points, its
 // stroke Width and if the segment forms a closed loop.  Points are
 // defined in world space after any matrix transformation is applied.
+// LineCap, LineJoin and MiterLimit hold the stroke-linecap,
+// stroke-linejoin and stroke-miterlimit of the element, empty or zero
+// when not set.
 type Segment struct {
-	Width  float64
-	Closed bool
-	Points [][2]float64
-}
-
-func (p Path) newSegment(start [2]float64) *Segment {
-	var s Segment
-	s.Width = p.StrokeWidth * p.group.Owner.scale
-	s.Points = append(s.Points, start)
-	return &s
+	Width      float64
+	LineCap    string
+	LineJoin   string
+	MiterLimit float64
+	Closed     bool
+	Points     [][2]float64
 }
 
 func (s *Segment) addPoint(p [2]float64) {
 	s.Points = append(s.Points, p)
 }
 
-type pathDescriptionParser struct {
-	p              *Path
-	lex            *gl.Lexer
-	x, y           float64
-	currentcommand int
-	tokbuf         [4]gl.Item
-	peekcount      int
-	lasttuple      Tuple
-	transform      mt.Transform
-	svg            *Svg
-	currentsegment *Segment
+// Parse interprets path description, transform and style atttributes to
+// create a channel of segments. It is the same as ParseSegments: errors
+// in the path data are sent on the error channel.
+func (p *Path) Parse() (chan Segment, chan error) {
+	return p.ParseSegments()
 }
 
-func newPathDParse() *pathDescriptionParser {
-	pdp := &pathDescriptionParser{}
-	pdp.transform = mt.Identity()
-	return pdp
+// ParseSegments flattens the path into segments. Errors in the path data
+// are sent on the error channel, after the segments before them.
+func (p *Path) ParseSegments() (chan Segment, chan error) {
+	return p.ParseSegmentsWith(FlattenOptions{})
 }
 
-// Parse interprets path description, transform and style atttributes to
-// create a channel of segments.
-func (p *Path) Parse() chan Segment {
-	p.parseStyle()
-	pdp := newPathDParse()
-	pdp.p = p
-	if p.group == nil {
-		p.group = new(Group)
-		temp := mt.Identity()
-		p.group.Transform = &temp
-	}
-	pdp.svg = p.group.Owner
-	pathTransform := mt.Identity()
-	if p.TransformString != "" {
-		pt, err := parseTransform(p.TransformString)
-		if err == nil {
-			pathTransform = pt
-		}
-	}
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, *p.group.Transform)
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, pathTransform)
-	p.Segments = make(chan Segment)
-	l, _ := gl.Lex(fmt.Sprint(p.ID), p.D)
-	pdp.lex = l
-	go func() {
-		defer close(p.Segments)
-		for {
-			i := pdp.lex.NextItem()
-			switch {
-			case i.Type == gl.ItemError:
-				return
-			case i.Type == gl.ItemEOS:
-				if pdp.currentsegment != nil {
-					p.Segments <- *pdp.currentsegment
-				}
-				return
-			case i.Type == gl.ItemLetter:
-				pdp.parseCommand(l, i)
-			default:
-			}
-		}
-	}()
-	return p.Segments
+// ParseSegmentsWith is like ParseSegments with control over the
+// transform and the precision of flattening.
+func (p *Path) ParseSegmentsWith(opts FlattenOptions) (chan Segment, chan error) {
+	instrs, errs := p.ParseDrawingInstructions()
+	p.Segments, errs = segmentsFromInstructions(instrs, errs, opts)
+	return p.Segments, errs
 }
 
 // ParseDrawingInstructions returns two channels. One is a channel of
 // Segments identical to the one returned by Parse() and the other one
 // is a channel of DrawingInstruction. The latter should be used to pass
-// to a path drawing library (like Cairo or something comparable)
+// to a path drawing library (like Cairo or something comparable).
+// Quadratic curves and arcs are drawn as cubic curves.
 func (p *Path) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
 	p.parseStyle()
-	pdp := newPathDParse()
-	pdp.p = p
 	if p.group == nil {
 		p.group = new(Group)
 		temp := mt.Identity()
 		p.group.Transform = &temp
 	}
-	pdp.svg = p.group.Owner
-	pathTransform := mt.Identity()
-	if p.TransformString != "" {
-		pt, err := parseTransform(p.TransformString)
-		if err == nil {
-			pathTransform = pt
-		}
+	if p.group.Owner == nil {
+		p.group.Owner = &Svg{scale: 1}
 	}
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, *p.group.Transform)
-	pdp.transform = mt.MultiplyTransforms(pdp.transform, pathTransform)
+	w := &instructionWriter{transform: p.transform()}
 
 	p.instructions = make(chan *DrawingInstruction, 100)
 	p.errors = make(chan error, 100)
-	l, _ := gl.Lex(fmt.Sprint(p.ID), p.D)
-
-	pdp.lex = l
+	w.instructions = p.instructions
 	go func() {
 		defer close(p.instructions)
 		defer close(p.errors)
-		var count int
-		for {
-			i := pdp.lex.NextItem()
-			count++
-			switch {
-			case i.Type == gl.ItemError:
-				return
-			case i.Type == gl.ItemEOS:
-				scaledStrokeWidth := p.StrokeWidth * pdp.p.group.Owner.scale
-				opacity := p.Opacity
-
-				pdp.p.instructions <- &DrawingInstruction{
-					Kind:           PaintInstruction,
-					StrokeWidth:    &scaledStrokeWidth,
-					Stroke:         p.Stroke,
-					StrokeLineCap:  p.StrokeLineCap,
-					StrokeLineJoin: p.StrokeLineJoin,
-					Fill:           p.Fill,
-					Opacity:		opacity,
-				}
-				return
-			case i.Type == gl.ItemLetter:
-				err := pdp.parseCommandDrawingInstructions(l, i)
-				if err != nil {
-					p.errors <- fmt.Errorf("error when parsing instruction number %d: %s", count, err)
-					return
-				}
-
-			default:
-				fmt.Printf("Default invoked: %d item %v\n", count, i)
-			}
+		if err := parsePathData(p.D, w); err != nil {
+			p.errors <- err
+			return
 		}
+		p.instructions <- p.Paint()
 	}()
 
 	return p.instructions, p.errors
 }
 
-func (pdp *pathDescriptionParser) parseCommand(l *gl.Lexer, i gl.Item) error {
-
-	switch i.Value {
-	case "M":
-		return pdp.parseMoveToAbs()
-	case "m":
-		return pdp.parseMoveToRel()
-	case "c":
-		return pdp.parseCurveToRel()
-	case "C":
-		return pdp.parseCurveToAbs()
-	case "L":
-		return pdp.parseLineToAbs()
-	case "l":
-		return pdp.parseLineToRel()
-	case "H":
-		return pdp.parseHLineToAbs()
-	case "h":
-		return pdp.parseHLineToRel()
-	case "V":
-		return pdp.parseVLineToAbs()
-	case "v":
-		return pdp.parseVLineToRel()
-	case "z", "Z":
-		return pdp.parseClose()
-	}
-
-	return fmt.Errorf("unknown command found in SVG: %s", i.Value)
+// transform returns the transform of the path combined with the
+// transform of its group.
+func (p *Path) transform() mt.Transform {
+	return elementTransform(p.group, p.TransformString)
 }
 
-func (pdp *pathDescriptionParser) parseCommandDrawingInstructions(l *gl.Lexer, i gl.Item) error {
-
-	switch i.Value {
-	case "M":
-		return pdp.parseMoveToAbsDI()
-	case "m":
-		return pdp.parseMoveToRelDI()
-	case "c":
-		return pdp.parseCurveToRelDI()
-	case "C":
-		return pdp.parseCurveToAbsDI()
-	case "l":
-		return pdp.parseLineToRelDI()
-	case "L":
-		return pdp.parseLineToAbsDI()
-	case "H":
-		fallthrough
-	case "h":
-		return pdp.parseHLineToDI(i.Value == "H")
-	case "V":
-		fallthrough
-	case "v":
-		return pdp.parseVLineToDI(i.Value == "V")
-	case "z", "Z":
-		return pdp.parseCloseDI()
-	}
-
-	return fmt.Errorf("unknown command found in SVG: %s", i.Value)
+// pathHandler receives the moves, curves and closes of path data read
+// by parsePathData, in absolute coordinates.
+type pathHandler interface {
+	moveTo(p [2]float64)
+	curveTo(c Curve)
+	// closePath ends the subpath with a line from the current point to
+	// the start of the subpath.
+	closePath(from, to [2]float64)
 }
 
-func (pdp *pathDescriptionParser) parseMoveToAbsDI() error {
-	var tuples []Tuple
-
-	t, err := parseTuple(pdp.lex)
-	if err != nil {
-		return fmt.Errorf("error parsing MoveToAbs. Expected tuple: %s", err)
-	}
-
-	pdp.x = t[0]
-	pdp.y = t[1]
-
-	if pdp.p.group.Owner == nil {
-		pdp.p.group.Owner = &Svg{scale: 1}
-	}
-	if pdp.p.StrokeWidth == 0 {
-		pdp.p.StrokeWidth = 1
-	}
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToAbs\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-
-	for _, nt := range tuples {
-		pdp.x = nt[0]
-		pdp.y = nt[1]
-		x, y = pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-	}
-
-	return nil
+// instructionWriter sends the drawing instructions of path data with a
+// transform applied.
+type instructionWriter struct {
+	instructions chan *DrawingInstruction
+	transform    mt.Transform
 }
 
-func (pdp *pathDescriptionParser) parseMoveToAbs() error {
-	var tuples []Tuple
-
-	t, err := parseTuple(pdp.lex)
-	if err != nil {
-		return fmt.Errorf("Error Passing MoveToAbs Expected Tuple\n%s", err)
-	}
-
-	pdp.x = t[0]
-	pdp.y = t[1]
-
-	if pdp.p.group.Owner == nil {
-		pdp.p.group.Owner = &Svg{scale: 1}
-	}
-	if pdp.p.StrokeWidth == 0 {
-		pdp.p.StrokeWidth = 1
-	}
-
-	scaledStroke := pdp.p.StrokeWidth * pdp.p.group.Owner.scale
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToAbs\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-
-	if pdp.currentsegment != nil {
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
-	} else {
-		var s Segment
-		s.Width = scaledStroke
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s.addPoint([2]float64{x, y})
-		pdp.currentsegment = &s
-		//fmt.Printf("orig x %f y %f, applied x %f y %f\n", pdp.x, pdp.y, x, y)
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	}
-
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s := pdp.p.newSegment([2]float64{x, y})
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		for _, nt := range tuples {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			s.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		}
-		pdp.currentsegment = s
-	}
-
-	return nil
+func (w *instructionWriter) moveTo(p [2]float64) {
+	t := Tuple(applyPoint(w.transform, p))
+	w.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &t}
 }
 
-func (pdp *pathDescriptionParser) parseLineToAbsDI() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing LineToAbs\n%s", err)
+func (w *instructionWriter) curveTo(c Curve) {
+	switch c := c.Transform(w.transform).(type) {
+	case LineCurve:
+		t := Tuple(c.P1)
+		w.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &t}
+	case QuadraticCurve:
+		w.cubic(c.cubic())
+	case CubicCurve:
+		w.cubic(c)
+	case ArcCurve:
+		for _, cc := range c.cubics() {
+			w.cubic(cc)
 		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
 	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-
-		for _, nt := range tuples {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-		}
-	}
-
-	return nil
 }
 
-func (pdp *pathDescriptionParser) parseLineToAbs() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing LineToAbs\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
+func (w *instructionWriter) cubic(c CubicCurve) {
+	c1, c2, t := Tuple(c.C1), Tuple(c.C2), Tuple(c.P1)
+	w.instructions <- &DrawingInstruction{
+		Kind:        CurveInstruction,
+		CurvePoints: &CurvePoints{C1: &c1, C2: &c2, T: &t},
 	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-
-		for _, nt := range tuples {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-		}
-	}
-
-	return nil
 }
 
-func (pdp *pathDescriptionParser) parseMoveToRelDI() error {
-	pdp.lex.ConsumeWhiteSpace()
-	t, err := parseTuple(pdp.lex)
-	if err != nil {
-		return fmt.Errorf("Error Passing MoveToRel Expected First Tuple %s", err)
-	}
-
-	pdp.x += t[0]
-	pdp.y += t[1]
-
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-
-	for _, nt := range tuples {
-		pdp.x += nt[0]
-		pdp.y += nt[1]
-		x, y = pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-	}
-
-	return nil
+func (w *instructionWriter) closePath(from, to [2]float64) {
+	w.instructions <- &DrawingInstruction{Kind: CloseInstruction}
 }
 
-func (pdp *pathDescriptionParser) parseMoveToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	t, err := parseTuple(pdp.lex)
-	if err != nil {
-		return fmt.Errorf("Error Passing MoveToRel Expected First Tuple\n%s", err)
-	}
-
-	pdp.x += t[0]
-	pdp.y += t[1]
-
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing MoveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if pdp.currentsegment != nil {
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
-	} else {
-		var s Segment
-		scaledStroke := pdp.p.StrokeWidth * pdp.svg.scale
-		s.Width = scaledStroke
-
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		s.addPoint([2]float64{x, y})
-		pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-		pdp.currentsegment = &s
-	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-		for _, nt := range tuples {
-			pdp.x += nt[0]
-			pdp.y += nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
+// parsePathData reads path data and hands its pieces to h. All commands
+// of the SVG path syntax are supported; relative coordinates and the
+// reflected control points of smooth curves are resolved, and arcs are
+// converted to centre notation. It stops at the first error.
+func parsePathData(d string, h pathHandler) error {
+	sc := &pathScanner{s: d}
+	var cur, start, ctrl [2]float64
+	var prev byte
+
+	cmd := byte(0)
+	for {
+		sc.skip()
+		if sc.done() {
+			break
+		}
+		if c := sc.s[sc.pos]; isPathCommand(c) {
+			if cmd == 0 && c != 'M' && c != 'm' {
+				return fmt.Errorf("path data does not start with a move: %q", d)
+			}
+			cmd = c
+			sc.pos++
+		} else if cmd == 0 || cmd == 'Z' || cmd == 'z' {
+			return fmt.Errorf("expected command at offset %d of %q", sc.pos, d)
 		}
-	}
-
-	return nil
-}
 
-func (pdp *pathDescriptionParser) parseHLineToDI(abs bool) error {
-	coords := []float64{}
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		item := pdp.lex.NextItem()
-		c, err := strconv.ParseFloat(item.Value, 64)
-		if err != nil {
-			return fmt.Errorf("parsing %q: %s", item.Value, err)
+		rel := cmd >= 'a'
+		offset := func(p [2]float64) [2]float64 {
+			if rel {
+				return vadd(p, cur)
+			}
+			return p
 		}
-		coords = append(coords, c)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if len(coords) > 0 {
-		for _, c := range coords {
-			if abs {
-				pdp.x = c
+
+		var err error
+		switch cmd {
+		case 'M', 'm':
+			var p [2]float64
+			if p, err = sc.point(); err != nil {
+				break
+			}
+			cur = offset(p)
+			start = cur
+			h.moveTo(cur)
+			// Further pairs are implicit lines.
+			if cmd == 'M' {
+				cmd = 'L'
+			} else {
+				cmd = 'l'
+			}
+		case 'L', 'l':
+			var p [2]float64
+			if p, err = sc.point(); err != nil {
+				break
+			}
+			p = offset(p)
+			h.curveTo(LineCurve{cur, p})
+			cur = p
+		case 'H', 'h', 'V', 'v':
+			var n float64
+			if n, err = sc.number(); err != nil {
+				break
+			}
+			p := cur
+			axis := 0
+			if cmd == 'V' || cmd == 'v' {
+				axis = 1
+			}
+			if rel {
+				p[axis] += n
 			} else {
-				pdp.x += c
+				p[axis] = n
+			}
+			h.curveTo(LineCurve{cur, p})
+			cur = p
+		case 'C', 'c', 'S', 's':
+			var pts [][2]float64
+			n := 3
+			if cmd == 'S' || cmd == 's' {
+				n = 2
+			}
+			if pts, err = sc.points(n); err != nil {
+				break
+			}
+			c1 := cur
+			if cmd == 'C' || cmd == 'c' {
+				c1, pts = offset(pts[0]), pts[1:]
+			} else if prev == 'C' || prev == 'S' {
+				c1 = vsub(vscale(cur, 2), ctrl)
+			}
+			c2, p := offset(pts[0]), offset(pts[1])
+			h.curveTo(CubicCurve{cur, c1, c2, p})
+			ctrl, cur = c2, p
+		case 'Q', 'q', 'T', 't':
+			var pts [][2]float64
+			n := 2
+			if cmd == 'T' || cmd == 't' {
+				n = 1
+			}
+			if pts, err = sc.points(n); err != nil {
+				break
+			}
+			c := cur
+			if cmd == 'Q' || cmd == 'q' {
+				c, pts = offset(pts[0]), pts[1:]
+			} else if prev == 'Q' || prev == 'T' {
+				c = vsub(vscale(cur, 2), ctrl)
 			}
-			x, y := pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
+			p := offset(pts[0])
+			h.curveTo(QuadraticCurve{cur, c, p})
+			ctrl, cur = c, p
+		case 'A', 'a':
+			var rx, ry, phi float64
+			var large, sweep bool
+			var p [2]float64
+			if rx, err = sc.number(); err != nil {
+				break
+			}
+			if ry, err = sc.number(); err != nil {
+				break
+			}
+			if phi, err = sc.number(); err != nil {
+				break
+			}
+			if large, err = sc.flag(); err != nil {
+				break
+			}
+			if sweep, err = sc.flag(); err != nil {
+				break
+			}
+			if p, err = sc.point(); err != nil {
+				break
+			}
+			p = offset(p)
+			if c := endpointArc(cur, p, rx, ry, phi, large, sweep); c != nil {
+				h.curveTo(c)
+			}
+			cur = p
+		case 'Z', 'z':
+			h.closePath(cur, start)
+			cur = start
 		}
-	}
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseLineToRelDI() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
 		if err != nil {
-			return fmt.Errorf("Error Passing LineToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-
-		for _, nt := range tuples {
-			pdp.x += nt[0]
-			pdp.y += nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
+			return fmt.Errorf("parsing %q command: %s", string(cmd), err)
 		}
+		prev = cmd &^ 0x20 // upper case
 	}
-
 	return nil
 }
 
-func (pdp *pathDescriptionParser) parseLineToRel() error {
-
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing LineToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	if len(tuples) > 0 {
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.currentsegment.addPoint([2]float64{x, y})
-
-		for _, nt := range tuples {
-			pdp.x += nt[0]
-			pdp.y += nt[1]
-			x, y = pdp.transform.Apply(pdp.x, pdp.y)
-			pdp.currentsegment.addPoint([2]float64{x, y})
-			pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-		}
+func isPathCommand(c byte) bool {
+	switch c | 0x20 {
+	case 'm', 'l', 'h', 'v', 'c', 's', 'q', 't', 'a', 'z':
+		return true
 	}
-
-	return nil
+	return false
 }
 
-func (pdp *pathDescriptionParser) parseHLineToAbs() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing HLineToAbs\n%s", err)
-		}
-	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	pdp.x = n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
+// pathScanner reads the numbers and flags of path data. It splits
+// numbers that are not separated, such as "1-2" and ".5.5", and single
+// digit arc flags.
+type pathScanner struct {
+	s   string
+	pos int
+}
 
-	return nil
+func (sc *pathScanner) done() bool {
+	return sc.pos >= len(sc.s)
 }
 
-func (pdp *pathDescriptionParser) parseHLineToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing HLineToRel\n%s", err)
+// skip advances over white space and at most one comma.
+func (sc *pathScanner) skip() {
+	comma := false
+	for !sc.done() {
+		switch c := sc.s[sc.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+		case c == ',' && !comma:
+			comma = true
+		default:
+			return
 		}
+		sc.pos++
 	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.p.instructions <- &DrawingInstruction{Kind: MoveInstruction, M: &Tuple{x, y}}
-	pdp.x += n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
 }
 
-func (pdp *pathDescriptionParser) parseVLineToDI(abs bool) error {
-	pdp.lex.ConsumeWhiteSpace()
-	coords := []float64{}
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		n, err := parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing VLineToRel\n%s", err)
+func (sc *pathScanner) number() (float64, error) {
+	sc.skip()
+	start := sc.pos
+	digits := func() bool {
+		from := sc.pos
+		for !sc.done() && sc.s[sc.pos] >= '0' && sc.s[sc.pos] <= '9' {
+			sc.pos++
 		}
-		coords = append(coords, n)
-		pdp.lex.ConsumeWhiteSpace()
+		return sc.pos > from
 	}
-
-	for _, n := range coords {
-		if abs {
-			pdp.y = n
-		} else {
-			pdp.y += n
+	sign := func() {
+		if !sc.done() && (sc.s[sc.pos] == '+' || sc.s[sc.pos] == '-') {
+			sc.pos++
 		}
-		x, y := pdp.transform.Apply(pdp.x, pdp.y)
-		pdp.p.instructions <- &DrawingInstruction{Kind: LineInstruction, M: &Tuple{x, y}}
 	}
-
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseVLineToAbs() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing VLineToAbs\n%s", err)
+	sign()
+	ok := digits()
+	if !sc.done() && sc.s[sc.pos] == '.' {
+		sc.pos++
+		ok = digits() || ok
+	}
+	if !ok {
+		sc.pos = start
+		return 0, fmt.Errorf("expected number at offset %d", start)
+	}
+	if !sc.done() && (sc.s[sc.pos] == 'e' || sc.s[sc.pos] == 'E') {
+		mark := sc.pos
+		sc.pos++
+		sign()
+		if !digits() {
+			sc.pos = mark
 		}
 	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.y = n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
+	return strconv.ParseFloat(sc.s[start:sc.pos], 64)
 }
 
-func (pdp *pathDescriptionParser) parseCloseDI() error {
-	pdp.lex.ConsumeWhiteSpace()
-
-	pdp.p.instructions <- &DrawingInstruction{Kind: CloseInstruction}
-
-	return nil
-}
-
-func (pdp *pathDescriptionParser) parseClose() error {
-	pdp.lex.ConsumeWhiteSpace()
-
-	if pdp.currentsegment != nil {
-		pdp.currentsegment.addPoint(pdp.currentsegment.Points[0])
-		pdp.currentsegment.Closed = true
-		pdp.p.Segments <- *pdp.currentsegment
-		pdp.currentsegment = nil
+func (sc *pathScanner) point() ([2]float64, error) {
+	x, err := sc.number()
+	if err != nil {
+		return [2]float64{}, err
 	}
-
-	pdp.p.instructions <- &DrawingInstruction{Kind: CloseInstruction}
-	return nil
+	y, err := sc.number()
+	return [2]float64{x, y}, err
 }
 
-func (pdp *pathDescriptionParser) parseVLineToRel() error {
-	pdp.lex.ConsumeWhiteSpace()
-	var n float64
-	var err error
-	if pdp.lex.PeekItem().Type != gl.ItemNumber {
-		n, err = parseNumber(pdp.lex.NextItem())
-		if err != nil {
-			return fmt.Errorf("Error Passing VLineToRel\n%s", err)
+func (sc *pathScanner) points(n int) ([][2]float64, error) {
+	pts := make([][2]float64, n)
+	for i := range pts {
+		var err error
+		if pts[i], err = sc.point(); err != nil {
+			return nil, err
 		}
 	}
-
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-	pdp.y += n
-	x, y = pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	return nil
-
+	return pts, nil
 }
 
-func (pdp *pathDescriptionParser) parseCurveToRelDI() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	pdp.lex.ConsumeComma()
-
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			panic(err)
-			return fmt.Errorf("Error Passing CurveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-		pdp.lex.ConsumeComma()
-	}
-	for j := 0; j < len(tuples)/3; j++ { // convert to absolute
-		j3 := j * 3
-		for i := 0; i < 3; i++ {
-			tuples[j3+i][0] += pdp.x
-			tuples[j3+i][1] += pdp.y
-		}
-		t := tuples[j3+2]
-		pdp.x, pdp.y = t[0], t[1]
-	}
-	for j := 0; j < len(tuples)/3; j++ {
-		c1x, c1y := pdp.transform.Apply(tuples[j*3][0], tuples[j*3][1])
-		c2x, c2y := pdp.transform.Apply(tuples[j*3+1][0], tuples[j*3+1][1])
-		tx, ty := pdp.transform.Apply(tuples[j*3+2][0], tuples[j*3+2][1])
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{C1: &Tuple{c1x, c1y},
-				C2: &Tuple{c2x, c2y},
-				T:  &Tuple{tx, ty},
-			},
+func (sc *pathScanner) flag() (bool, error) {
+	sc.skip()
+	if !sc.done() {
+		switch sc.s[sc.pos] {
+		case '0', '1':
+			sc.pos++
+			return sc.s[sc.pos-1] == '1', nil
 		}
 	}
-
-	return nil
+	return false, fmt.Errorf("expected flag at offset %d", sc.pos)
 }
 
-func (pdp *pathDescriptionParser) parseCurveToRel() error {
-	var tuples []Tuple
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error Passing CurveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-	}
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	for j := 0; j < len(tuples)/3; j++ {
-		var cb cubicBezier
-		cb.controlpoints[0][0] = pdp.x
-		cb.controlpoints[0][1] = pdp.y
-
-		cb.controlpoints[1][0] = pdp.x + tuples[j*3][0]
-		cb.controlpoints[1][1] = pdp.y + tuples[j*3][1]
-
-		cb.controlpoints[2][0] = pdp.x + tuples[j*3+1][0]
-		cb.controlpoints[2][1] = pdp.y + tuples[j*3+1][1]
-
-		pdp.x += tuples[j*3+2][0]
-		pdp.y += tuples[j*3+2][1]
-
-		cb.controlpoints[3][0] = pdp.x
-		cb.controlpoints[3][1] = pdp.y
-
-		c1x, c1y := pdp.transform.Apply(x+tuples[j*3][0], y+tuples[j*3][1])
-		c2x, c2y := pdp.transform.Apply(x+tuples[j*3+1][0], y+tuples[j*3+1][1])
-		tx, ty := pdp.transform.Apply(x+tuples[j*3+2][0], y+tuples[j*3+2][1])
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{
-				C1: &Tuple{c1x, c1y},
-				C2: &Tuple{c2x, c2y},
-				T:  &Tuple{tx, ty},
-			},
-		}
+func (p *Path) parseStyle() {
+	p.properties = splitStyle(p.Style)
+}
 
-		vertices := cb.recursiveInterpolate(10, 0)
-		for _, v := range vertices {
-			x, y = pdp.transform.Apply(v[0], v[1])
-			pdp.currentsegment.addPoint([2]float64{x, y})
-		}
+// Paint returns the paint instruction ending the drawing instructions
+// of the path, with its stroke and fill properties resolved from its
+// attributes and style.
+func (p *Path) Paint() *DrawingInstruction {
+	scaledStrokeWidth := p.scaledStrokeWidth()
+	return &DrawingInstruction{
+		Kind:             PaintInstruction,
+		StrokeWidth:      &scaledStrokeWidth,
+		Stroke:           p.property("stroke", p.Stroke),
+		StrokeLineCap:    p.property("stroke-linecap", p.StrokeLineCap),
+		StrokeLineJoin:   p.property("stroke-linejoin", p.StrokeLineJoin),
+		StrokeMiterLimit: p.miterLimit(),
+		Fill:             p.property("fill", p.Fill),
+		FillRule:         p.property("fill-rule", p.FillRule),
+		Opacity:          p.opacity(),
 	}
-
-	return nil
 }
 
-func (pdp *pathDescriptionParser) parseCurveToAbsDI() error {
-	var tuples []Tuple
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error parsing CurveToRel\n%s", err)
+// strokeWidth returns the stroke width of the path. A stroke-width in
+// the style attribute takes precedence over the attribute and paths
+// without a stroke width default to 1.
+func (p *Path) strokeWidth() float64 {
+	sw := p.StrokeWidth
+	if val, ok := p.properties["stroke-width"]; ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			sw = f
 		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-		pdp.lex.ConsumeComma()
 	}
-
-	for j := 0; j < len(tuples)/3; j++ {
-		instrTuples := []Tuple{}
-		for _, nt := range tuples[j*3 : (j+1)*3] {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-
-			tx, ty := pdp.transform.Apply(pdp.x, pdp.y)
-			instrTuples = append(instrTuples, Tuple{tx, ty})
-		}
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{
-				C1: &instrTuples[0],
-				C2: &instrTuples[1],
-				T:  &instrTuples[2],
-			},
-		}
+	if sw == 0 {
+		sw = 1
 	}
-
-	return nil
+	return sw
 }
 
-func (pdp *pathDescriptionParser) parseCurveToAbs() error {
-	var tuples []Tuple
-
-	pdp.lex.ConsumeWhiteSpace()
-	for pdp.lex.PeekItem().Type == gl.ItemNumber {
-		t, err := parseTuple(pdp.lex)
-		if err != nil {
-			return fmt.Errorf("Error parsing CurveToRel\n%s", err)
-		}
-		tuples = append(tuples, t)
-		pdp.lex.ConsumeWhiteSpace()
-		pdp.lex.ConsumeComma()
+// property returns the value of a paint property, giving the style
+// attribute precedence over the attribute.
+func (p *Path) property(key string, attr *string) *string {
+	if val, ok := p.properties[key]; ok {
+		val = strings.TrimSpace(val)
+		return &val
 	}
+	return attr
+}
 
-	x, y := pdp.transform.Apply(pdp.x, pdp.y)
-	pdp.currentsegment.addPoint([2]float64{x, y})
-
-	for j := 0; j < len(tuples)/3; j++ {
-		instrTuples := []Tuple{}
-		var cb cubicBezier
-		cb.controlpoints[0][0] = pdp.x
-		cb.controlpoints[0][1] = pdp.y
-
-		for i, nt := range tuples[j*3 : (j+1)*3] {
-			pdp.x = nt[0]
-			pdp.y = nt[1]
-			cb.controlpoints[i+1][0] = pdp.x
-			cb.controlpoints[i+1][1] = pdp.y
-
-			tx, ty := pdp.transform.Apply(pdp.x, pdp.y)
-			instrTuples = append(instrTuples, Tuple{tx, ty})
-		}
-
-		pdp.p.instructions <- &DrawingInstruction{
-			Kind: CurveInstruction,
-			CurvePoints: &CurvePoints{
-				C1: &instrTuples[0],
-				C2: &instrTuples[1],
-				T:  &instrTuples[2],
-			},
-		}
-
-		vertices := cb.recursiveInterpolate(10, 0)
-		for _, v := range vertices {
-			x, y = pdp.transform.Apply(v[0], v[1])
-			pdp.currentsegment.addPoint([2]float64{x, y})
+// miterLimit returns the stroke miter limit of the path, giving the style
+// attribute precedence over the attribute.
+func (p *Path) miterLimit() *float64 {
+	if val, ok := p.properties["stroke-miterlimit"]; ok {
+		if m, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			return &m
 		}
 	}
-
-	return nil
+	return p.StrokeMiterLimit
 }
 
-func (p *Path) parseStyle() {
-	p.properties = splitStyle(p.Style)
-	for key, val := range p.properties {
-		switch key {
-		case "stroke-width":
-			sw, ok := strconv.ParseFloat(val, 64)
-			if ok == nil {
-				p.StrokeWidth = sw
-			}
-		case "opacity":
-			o, ok := strconv.ParseFloat(val, 64)
-			if ok == nil {
-				p.Opacity = &o
-			}
+// opacity returns the opacity of the path, giving an opacity in the style
+// attribute precedence over the attribute.
+func (p *Path) opacity() *float64 {
+	if val, ok := p.properties["opacity"]; ok {
+		if o, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			return &o
 		}
 	}
+	return p.Opacity
 }
@@ -102,3 +102,49 @@ func TestParsePathList(t *testing.T) {
 		}
 	}
 }
+
+func TestPathSegments(t *testing.T) {
+	s, err := ParseSvg(`<svg><g><path d="M0 0 L10 0 L10 10 Z M20 20 l5 0"/></g></svg>`, "segments", 1)
+	require.NoError(t, err)
+	p := s.Groups[0].Elements[0].(*Path)
+
+	var segs []Segment
+	segments, errs := p.Parse()
+	for seg := range segments {
+		segs = append(segs, seg)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, []Segment{
+		{Width: 1, Closed: true, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 0}}},
+		{Width: 1, Points: [][2]float64{{20, 20}, {25, 20}}},
+	}, segs)
+}
+
+func TestPathParseSegmentsError(t *testing.T) {
+	p := &Path{D: "M0 0 L10 0 X5 5"}
+	segs, errs := p.Parse()
+	var all []Segment
+	for seg := range segs {
+		all = append(all, seg)
+	}
+	var errors []error
+	for err := range errs {
+		errors = append(errors, err)
+	}
+	require.Len(t, errors, 1)
+	require.Contains(t, errors[0].Error(), "expected number")
+	require.Len(t, all, 1)
+	require.Equal(t, [][2]float64{{0, 0}, {10, 0}}, all[0].Points)
+}
+
+func TestCollectInstructions(t *testing.T) {
+	instrs, err := CollectInstructions(&Path{D: "M0 0 L10 0"})
+	require.NoError(t, err)
+	require.Len(t, instrs, 3)
+	require.Equal(t, PaintInstruction, instrs[2].Kind)
+
+	_, err = CollectInstructions(&Path{D: "M0 0 L10 0 X5 5"})
+	require.Error(t, err)
+}
@@ -0,0 +1,257 @@
+// Package pdf writes SVG documents as single page vector PDF files. It
+// consumes the drawing instructions of a parsed document and is written
+// in pure Go.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+	"github.com/rustyoz/svg/internal/pathops"
+)
+
+// PointsPerMillimeter is the number of PDF points in a millimetre.
+const PointsPerMillimeter = 72 / 25.4
+
+// Page draws drawing instructions onto a PDF page. Instructions are
+// collected into a path until a paint instruction fills and strokes it.
+type Page struct {
+	// Width and Height are the size of the page in points.
+	Width, Height float64
+	// Transform maps the coordinates of the drawing instructions to
+	// points with the origin in the bottom left corner of the page.
+	Transform mt.Transform
+
+	path    pathops.Builder
+	content bytes.Buffer
+	states  []string
+}
+
+// NewPage returns a page of the given size in points. Its transform
+// puts the origin of the drawing instructions in the top left corner
+// with the y axis pointing down, as in SVG.
+func NewPage(width, height float64) *Page {
+	t := mt.Identity()
+	t.Translate(0, height)
+	t.Scale(1, -1)
+	return &Page{Width: width, Height: height, Transform: t}
+}
+
+// Write writes the document as a PDF page the physical size of the
+// document. Documents without a size are taken to be 300 by 150
+// pixels, the default size of SVG images.
+func Write(w io.Writer, s *svg.Svg) error {
+	width, height, ok := s.PhysicalSize()
+	m := s.MillimeterTransform()
+	if !ok {
+		px := 25.4 / 96
+		width, height = 300*px, 150*px
+	}
+	p := NewPage(width*PointsPerMillimeter, height*PointsPerMillimeter)
+	p.Transform.Scale(PointsPerMillimeter, PointsPerMillimeter)
+	p.Transform = mt.MultiplyTransforms(p.Transform, m)
+
+	instrs, first := svg.CollectInstructions(s)
+	for _, di := range instrs {
+		p.Draw(di)
+	}
+	if _, err := p.WriteTo(w); err != nil {
+		return err
+	}
+	return first
+}
+
+// DrawInstructions draws all instructions received from the channel.
+func (p *Page) DrawInstructions(instrs chan *svg.DrawingInstruction) {
+	for di := range instrs {
+		p.Draw(di)
+	}
+}
+
+// Draw processes a single drawing instruction.
+func (p *Page) Draw(di *svg.DrawingInstruction) {
+	if di.Kind == svg.PaintInstruction {
+		p.paint(di)
+		p.path.Reset()
+		return
+	}
+	p.path.Add(di)
+}
+
+// paint fills and strokes the current path. The path is written in the
+// coordinates of the drawing instructions under the page transform, so
+// stroke widths are transformed along with it.
+func (p *Page) paint(di *svg.DrawingInstruction) {
+	if p.path.Len() == 0 {
+		return
+	}
+	opacity := 1.0
+	if di.Opacity != nil {
+		opacity = math.Max(0, math.Min(1, *di.Opacity))
+	}
+
+	fill := "black"
+	if di.Fill != nil && strings.TrimSpace(*di.Fill) != "" {
+		fill = *di.Fill
+	}
+	fc, filled := svg.ParseColor(fill)
+	var sc color.NRGBA
+	stroked := false
+	if di.Stroke != nil && di.StrokeWidth != nil && *di.StrokeWidth > 0 {
+		sc, stroked = svg.ParseColor(*di.Stroke)
+	}
+	if !filled && !stroked {
+		return
+	}
+
+	var b strings.Builder
+	t := p.Transform
+	b.WriteString("q\n")
+	for _, v := range []float64{t[0][0], t[1][0], t[0][1], t[1][1], t[0][2], t[1][2]} {
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64) + " ")
+	}
+	b.WriteString("cm\n")
+
+	fillAlpha, strokeAlpha := 1.0, 1.0
+	if filled {
+		fillAlpha = float64(fc.A) / 255 * opacity
+		fmt.Fprintf(&b, "%s %s %s rg\n", channel(fc.R), channel(fc.G), channel(fc.B))
+	}
+	if stroked {
+		strokeAlpha = float64(sc.A) / 255 * opacity
+		fmt.Fprintf(&b, "%s %s %s RG\n", channel(sc.R), channel(sc.G), channel(sc.B))
+		b.WriteString(pathops.Number(*di.StrokeWidth) + " w\n")
+		if di.StrokeLineCap != nil {
+			switch strings.TrimSpace(*di.StrokeLineCap) {
+			case "round":
+				b.WriteString("1 J\n")
+			case "square":
+				b.WriteString("2 J\n")
+			}
+		}
+		if di.StrokeLineJoin != nil {
+			switch strings.TrimSpace(*di.StrokeLineJoin) {
+			case "round":
+				b.WriteString("1 j\n")
+			case "bevel":
+				b.WriteString("2 j\n")
+			}
+		}
+		// SVG and PDF differ in the default miter limit.
+		limit := 4.0
+		if di.StrokeMiterLimit != nil && *di.StrokeMiterLimit >= 1 {
+			limit = *di.StrokeMiterLimit
+		}
+		b.WriteString(pathops.Number(limit) + " M\n")
+	}
+	if fillAlpha < 1 || strokeAlpha < 1 {
+		b.WriteString("/" + p.state(fillAlpha, strokeAlpha) + " gs\n")
+	}
+
+	b.WriteString(p.path.String())
+	evenOdd := di.FillRule != nil && svg.ParseFillRule(strings.TrimSpace(*di.FillRule)) == svg.EvenOdd
+	switch {
+	case filled && stroked && evenOdd:
+		b.WriteString("B*\n")
+	case filled && stroked:
+		b.WriteString("B\n")
+	case filled && evenOdd:
+		b.WriteString("f*\n")
+	case filled:
+		b.WriteString("f\n")
+	default:
+		b.WriteString("S\n")
+	}
+	b.WriteString("Q\n")
+	p.content.WriteString(b.String())
+}
+
+// state returns the name of the graphics state setting the opacities
+// of fills and strokes, adding it to the resources of the page.
+func (p *Page) state(fill, stroke float64) string {
+	dict := fmt.Sprintf("<< /Type /ExtGState /ca %s /CA %s >>", pathops.Number(fill), pathops.Number(stroke))
+	for i, s := range p.states {
+		if s == dict {
+			return "GS" + strconv.Itoa(i+1)
+		}
+	}
+	p.states = append(p.states, dict)
+	return "GS" + strconv.Itoa(len(p.states))
+}
+
+// WriteTo writes the page as a PDF file.
+func (p *Page) WriteTo(w io.Writer) (int64, error) {
+	var stream bytes.Buffer
+	z := zlib.NewWriter(&stream)
+	z.Write(p.content.Bytes())
+	z.Close()
+
+	var resources strings.Builder
+	if len(p.states) > 0 {
+		resources.WriteString(" /ExtGState <<")
+		for i, s := range p.states {
+			fmt.Fprintf(&resources, " /GS%d %s", i+1, s)
+		}
+		resources.WriteString(" >>")
+	}
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int64
+	object := func(body string) {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	cw.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources <<%s >> /Contents 4 0 R >>",
+		pathops.Number(p.Width), pathops.Number(p.Height), resources.String()))
+	object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// countingWriter counts the bytes written for the cross-reference
+// table and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (c *countingWriter) WriteString(s string) {
+	c.Write([]byte(s))
+}
+
+// channel formats a colour channel as a PDF colour component.
+func channel(v uint8) string {
+	return pathops.Number(float64(v) / 255)
+}
@@ -0,0 +1,89 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+// readPDF checks the cross-reference table of a PDF file and returns
+// its page dictionary and its inflated content stream.
+func readPDF(t *testing.T, file []byte) (string, string) {
+	require.True(t, bytes.HasPrefix(file, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(file, []byte("%%EOF\n")))
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(file)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(file[xref:], []byte("xref\n0 5\n")))
+	for i, o := range regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(file, -1) {
+		offset, err := strconv.Atoi(string(o[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(file[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")))
+	}
+
+	page := regexp.MustCompile(`(?s)3 0 obj\n(.*?)\nendobj`).FindSubmatch(file)
+	require.NotNil(t, page)
+	stream := regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n(.*)\nendstream`).FindSubmatch(file)
+	require.NotNil(t, stream)
+	require.Equal(t, string(stream[1]), strconv.Itoa(len(stream[2])))
+	z, err := zlib.NewReader(bytes.NewReader(stream[2]))
+	require.NoError(t, err)
+	content, err := io.ReadAll(z)
+	require.NoError(t, err)
+	return string(page[1]), string(content)
+}
+
+func TestWrite(t *testing.T) {
+	s, err := svg.ParseSvg(`<svg width="100mm" height="50mm" viewBox="0 0 200 100">
+		<path d="M10 10 H90 V90 H10 Z M30 30 V70 H70 V30 Z" fill="#3366cc" fill-rule="evenodd"
+			stroke="black" stroke-width="2" stroke-linejoin="round" opacity="0.5"/>
+		<path d="M110 10 C150 10 150 90 190 90" fill="none" stroke="red" stroke-width="6" stroke-linecap="round"/>
+		<path d="M0 0 H10" fill="none"/>
+		<circle cx="150" cy="50" r="10" fill="green"/>
+	</svg>`, "pdf", 1)
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, Write(&b, s))
+	page, content := readPDF(t, b.Bytes())
+
+	require.Contains(t, page, "/MediaBox [0 0 283.4646 141.7323]")
+	require.Contains(t, page, "/ExtGState << /GS1 << /Type /ExtGState /ca 0.5 /CA 0.5 >> >>")
+
+	elements := strings.Split(strings.TrimSuffix(content, "Q\n"), "Q\nq\n")
+	require.Len(t, elements, 3)
+	scale := strconv.FormatFloat(0.5*PointsPerMillimeter, 'f', -1, 64)
+	for _, e := range elements {
+		require.Contains(t, e, scale+" 0 0 -"+scale+" 0 141.73228346456693 cm\n")
+	}
+
+	require.Contains(t, elements[0], "0.2 0.4 0.8 rg\n0 0 0 RG\n2 w\n1 j\n4 M\n/GS1 gs\n10 10 m\n90 10 l\n")
+	require.True(t, strings.HasSuffix(elements[0], "70 30 l\nh\nB*\n"))
+
+	require.Contains(t, elements[1], "1 0 0 RG\n6 w\n1 J\n4 M\n110 10 m\n150 10 150 90 190 90 c\nS\n")
+	require.NotContains(t, elements[1], " rg\n")
+
+	require.Contains(t, elements[2], "0 0.502 0 rg\n160 50 m\n160 55.5228 155.5228 60 150 60 c\n")
+	require.True(t, strings.HasSuffix(elements[2], "h\nf\n"))
+}
+
+func TestPage(t *testing.T) {
+	p := NewPage(100, 50)
+	p.Draw(&svg.DrawingInstruction{Kind: svg.LineInstruction, M: &svg.Tuple{10, 10}})
+	p.Draw(&svg.DrawingInstruction{Kind: svg.PaintInstruction})
+	var b bytes.Buffer
+	n, err := p.WriteTo(&b)
+	require.NoError(t, err)
+	require.EqualValues(t, b.Len(), n)
+	page, content := readPDF(t, b.Bytes())
+	require.Contains(t, page, "/MediaBox [0 0 100 50] /Resources << >>")
+	require.Equal(t, "q\n1 0 0 -1 0 50 cm\n0 0 0 rg\n0 0 m\n10 10 l\nf\nQ\n", content)
+}
@@ -4,10 +4,11 @@ import mt "github.com/rustyoz/Mtransform"
 
 // Polygon is a closed shape of straight line segments
 type Polygon struct {
-	ID        string `xml:"id,attr"`
-	Transform string `xml:"transform,attr"`
-	Style     string `xml:"style,attr"`
-	Points    string `xml:"points,attr"`
+	ID        string     `xml:"id,attr"`
+	Transform string     `xml:"transform,attr"`
+	Style     string     `xml:"style,attr"`
+	Points    string     `xml:"points,attr"`
+	Attrs     Attributes `xml:",any,attr"`
 
 	transform mt.Transform
 	group     *Group
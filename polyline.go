@@ -5,10 +5,11 @@ import mt "github.com/rustyoz/Mtransform"
 // PolyLine is a set of connected line segments that typically form a
 // closed shape
 type PolyLine struct {
-	ID        string `xml:"id,attr"`
-	Transform string `xml:"transform,attr"`
-	Style     string `xml:"style,attr"`
-	Points    string `xml:"points,attr"`
+	ID        string     `xml:"id,attr"`
+	Transform string     `xml:"transform,attr"`
+	Style     string     `xml:"style,attr"`
+	Points    string     `xml:"points,attr"`
+	Attrs     Attributes `xml:",any,attr"`
 
 	transform mt.Transform
 	group     *Group
@@ -1,29 +1,124 @@
 package svg
 
-import mt "github.com/rustyoz/Mtransform"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+)
 
 // Rect is an SVG XML rect element
 type Rect struct {
-	ID        string `xml:"id,attr"`
-	Width     string `xml:"width,attr"`
-	Height    string `xml:"height,attr"`
-	Transform string `xml:"transform,attr"`
-	Style     string `xml:"style,attr"`
-	Rx        string `xml:"rx,attr"`
-	Ry        string `xml:"ry,attr"`
+	ID         string     `xml:"id,attr"`
+	X          string     `xml:"x,attr"`
+	Y          string     `xml:"y,attr"`
+	Width      string     `xml:"width,attr"`
+	Height     string     `xml:"height,attr"`
+	Transform  string     `xml:"transform,attr"`
+	Style      string     `xml:"style,attr"`
+	Rx         string     `xml:"rx,attr"`
+	Ry         string     `xml:"ry,attr"`
+	Display    string     `xml:"display,attr"`
+	Visibility string     `xml:"visibility,attr"`
+	Attrs      Attributes `xml:",any,attr"`
 
 	transform mt.Transform
 	group     *Group
+	source    *xmlSource
 }
 
 // ParseDrawingInstructions implements the DrawingInstructionParser
 // interface
 func (r *Rect) ParseDrawingInstructions() (chan *DrawingInstruction, chan error) {
 	draw := make(chan *DrawingInstruction)
-	errs := make(chan error)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(draw)
+		defer close(errs)
 
-	defer close(draw)
-	defer close(errs)
+		g, err := r.Geometry()
+		if err != nil {
+			errs <- err
+			return
+		}
+		if len(g.Subpaths) > 0 {
+			g.sendInstructions(draw)
+			draw <- r.Paint()
+		}
+	}()
 
 	return draw, errs
 }
+
+// Geometry returns the outline of the rect, with rounded corners when
+// rx or ry are set, with its transform and the transform of its group
+// applied. Rects without a width or height have no curves. Lengths are
+// read in user units; percentages are not supported.
+func (r *Rect) Geometry() (*PathGeometry, error) {
+	var v [6]float64
+	for i, s := range []string{r.X, r.Y, r.Width, r.Height, r.Rx, r.Ry} {
+		f, err := rectLength(s)
+		if err != nil {
+			return &PathGeometry{}, fmt.Errorf("rect %s: %s", r.ID, err)
+		}
+		v[i] = f
+	}
+	x, y, w, h, rx, ry := v[0], v[1], v[2], v[3], v[4], v[5]
+	g := &PathGeometry{}
+	if w <= 0 || h <= 0 {
+		return g, nil
+	}
+	// A missing radius takes the value of the other one.
+	if strings.TrimSpace(r.Rx) == "" {
+		rx = ry
+	}
+	if strings.TrimSpace(r.Ry) == "" {
+		ry = rx
+	}
+	rx = math.Min(math.Max(rx, 0), w/2)
+	ry = math.Min(math.Max(ry, 0), h/2)
+	if rx == 0 || ry == 0 {
+		rx, ry = 0, 0
+	}
+
+	var curves []Curve
+	line := func(a, b [2]float64) {
+		if a != b {
+			curves = append(curves, LineCurve{a, b})
+		}
+	}
+	corner := func(cx, cy, start float64) {
+		if rx > 0 {
+			curves = append(curves, ArcCurve{Center: [2]float64{cx, cy}, U: [2]float64{rx, 0}, V: [2]float64{0, ry}, Start: start, Sweep: math.Pi / 2})
+		}
+	}
+	line([2]float64{x + rx, y}, [2]float64{x + w - rx, y})
+	corner(x+w-rx, y+ry, -math.Pi/2)
+	line([2]float64{x + w, y + ry}, [2]float64{x + w, y + h - ry})
+	corner(x+w-rx, y+h-ry, 0)
+	line([2]float64{x + w - rx, y + h}, [2]float64{x + rx, y + h})
+	corner(x+rx, y+h-ry, math.Pi/2)
+	line([2]float64{x, y + h - ry}, [2]float64{x, y + ry})
+	corner(x+rx, y+ry, math.Pi)
+	g.Subpaths = []Subpath{{Curves: curves, Closed: true}}
+	return g.Transform(elementTransform(r.group, r.Transform)), nil
+}
+
+// Paint returns the paint instruction ending the drawing instructions
+// of the rect; see shapePaint.
+func (r *Rect) Paint() *DrawingInstruction {
+	return shapePaint(r.Style, r.Attrs, nil, r.group)
+}
+
+// rectLength parses a length of a rect in user units, allowing a px
+// unit. Empty lengths are 0.
+func rectLength(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "px")
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
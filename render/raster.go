@@ -0,0 +1,160 @@
+package render
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/rustyoz/svg"
+)
+
+// subsamples is the number of scanlines sampled per pixel row. Coverage
+// along a scanline is computed exactly, so this only limits the
+// anti-aliasing quality of edges close to horizontal.
+const subsamples = 8
+
+type point [2]float64
+
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+// rasterizer scan-converts polygons into an anti-aliased coverage mask.
+type rasterizer struct {
+	edges []edge
+}
+
+// addPolygon adds a closed polygon. The last point is connected to the
+// first one.
+func (r *rasterizer) addPolygon(pts []point) {
+	for i := range pts {
+		r.addEdge(pts[i], pts[(i+1)%len(pts)])
+	}
+}
+
+func (r *rasterizer) addEdge(a, b point) {
+	switch {
+	case a[1] == b[1]:
+		return
+	case a[1] < b[1]:
+		r.edges = append(r.edges, edge{a[0], a[1], b[0], b[1], 1})
+	default:
+		r.edges = append(r.edges, edge{b[0], b[1], a[0], a[1], -1})
+	}
+}
+
+func (r *rasterizer) reset() {
+	r.edges = r.edges[:0]
+}
+
+func (r *rasterizer) bounds() image.Rectangle {
+	if len(r.edges) == 0 {
+		return image.Rectangle{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, e := range r.edges {
+		minX = math.Min(minX, math.Min(e.x0, e.x1))
+		maxX = math.Max(maxX, math.Max(e.x0, e.x1))
+		minY = math.Min(minY, e.y0)
+		maxY = math.Max(maxY, e.y1)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// mask returns the coverage of the polygons within clip.
+func (r *rasterizer) mask(rule svg.FillRule, clip image.Rectangle) *image.Alpha {
+	b := r.bounds().Intersect(clip)
+	if b.Empty() {
+		return nil
+	}
+	m := image.NewAlpha(b)
+
+	edges := append([]edge(nil), r.edges...)
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	cov := make([]float64, b.Dx()+1)
+	var active []edge
+	var crossings []crossing
+	next := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range cov {
+			cov[i] = 0
+		}
+		fy := float64(y)
+		for next < len(edges) && edges[next].y0 < fy+1 {
+			active = append(active, edges[next])
+			next++
+		}
+		n := 0
+		for _, e := range active {
+			if e.y1 > fy {
+				active[n] = e
+				n++
+			}
+		}
+		active = active[:n]
+
+		for s := 0; s < subsamples; s++ {
+			sy := fy + (float64(s)+0.5)/subsamples
+			crossings = crossings[:0]
+			for _, e := range active {
+				if sy < e.y0 || sy >= e.y1 {
+					continue
+				}
+				x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+				crossings = append(crossings, crossing{x, e.dir})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i, c := range crossings {
+				winding += c.dir
+				inside := winding != 0
+				if rule == svg.EvenOdd {
+					inside = winding%2 != 0
+				}
+				if inside && i+1 < len(crossings) {
+					accumulate(cov, c.x-float64(b.Min.X), crossings[i+1].x-float64(b.Min.X))
+				}
+			}
+		}
+
+		row := m.Pix[(y-b.Min.Y)*m.Stride:]
+		for x := 0; x < b.Dx(); x++ {
+			a := cov[x] / subsamples
+			if a > 1 {
+				a = 1
+			}
+			row[x] = uint8(a*255 + 0.5)
+		}
+	}
+	return m
+}
+
+// accumulate adds the horizontal coverage of the span [x0, x1) to cov.
+func accumulate(cov []float64, x0, x1 float64) {
+	limit := float64(len(cov) - 1)
+	x0 = math.Max(0, math.Min(x0, limit))
+	x1 = math.Max(0, math.Min(x1, limit))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		cov[i0] += x1 - x0
+		return
+	}
+	cov[i0] += float64(i0+1) - x0
+	for i := i0 + 1; i < i1; i++ {
+		cov[i]++
+	}
+	cov[i1] += x1 - float64(i1)
+}
@@ -0,0 +1,251 @@
+// Package render rasterizes SVG documents into images. It consumes the
+// drawing instructions of a parsed document and is written in pure Go.
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+	"github.com/rustyoz/svg"
+)
+
+// DefaultTolerance is the default maximum distance in pixels between a
+// curve and the line segments it is drawn with.
+const DefaultTolerance = 0.1
+
+type subpath struct {
+	points []point
+	closed bool
+}
+
+// Renderer draws drawing instructions onto an image. Instructions are
+// collected into a path until a paint instruction fills and strokes it.
+type Renderer struct {
+	Image *image.RGBA
+	// Transform maps the coordinates of the drawing instructions to
+	// pixels.
+	Transform mt.Transform
+	// Tolerance is the maximum distance in pixels between a curve and
+	// the line segments it is drawn with.
+	Tolerance float64
+
+	subpaths []subpath
+	start    point
+	pos      point
+	raster   rasterizer
+}
+
+// NewRenderer returns a renderer drawing onto img with an identity
+// transform.
+func NewRenderer(img *image.RGBA) *Renderer {
+	return &Renderer{Image: img, Transform: mt.Identity(), Tolerance: DefaultTolerance}
+}
+
+// Render draws the document onto a new image of the given size. The view
+// box of the document is scaled to fit and centred in the image.
+func Render(s *svg.Svg, width, height int) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	r := NewRenderer(img)
+	r.Transform = ViewBoxTransform(s, width, height)
+
+	instrs, first := svg.CollectInstructions(s)
+	for _, di := range instrs {
+		r.Draw(di)
+	}
+	return img, first
+}
+
+// ViewBoxTransform returns the transform which fits the view box of the
+// document into an image of the given size keeping its aspect ratio.
+// Without a view box the width and height attributes are used.
+func ViewBoxTransform(s *svg.Svg, width, height int) mt.Transform {
+	var minX, minY, w, h float64
+	if vb, err := s.ViewBoxValues(); err == nil && len(vb) == 4 {
+		minX, minY, w, h = vb[0], vb[1], vb[2], vb[3]
+	} else {
+		// Without a view box user units are pixels.
+		px := 25.4 / 96
+		w, _ = svg.ParseLength(s.Width)
+		h, _ = svg.ParseLength(s.Height)
+		w, h = w/px, h/px
+	}
+	t := mt.Identity()
+	if w <= 0 || h <= 0 {
+		return t
+	}
+	sc := math.Min(float64(width)/w, float64(height)/h)
+	t.Translate((float64(width)-w*sc)/2, (float64(height)-h*sc)/2)
+	t.Scale(sc, sc)
+	t.Translate(-minX, -minY)
+	return t
+}
+
+// DrawInstructions draws all instructions received from the channel.
+func (r *Renderer) DrawInstructions(instrs chan *svg.DrawingInstruction) {
+	for di := range instrs {
+		r.Draw(di)
+	}
+}
+
+func (r *Renderer) apply(t svg.Tuple) point {
+	x, y := r.Transform.Apply(t[0], t[1])
+	return point{x, y}
+}
+
+func (r *Renderer) current() *subpath {
+	if len(r.subpaths) == 0 {
+		r.subpaths = append(r.subpaths, subpath{points: []point{r.pos}})
+	}
+	return &r.subpaths[len(r.subpaths)-1]
+}
+
+func (r *Renderer) lineTo(p point) {
+	sp := r.current()
+	if sp.closed {
+		r.subpaths = append(r.subpaths, subpath{points: []point{r.pos}})
+		sp = r.current()
+	}
+	sp.points = append(sp.points, p)
+	r.pos = p
+}
+
+// Draw processes a single drawing instruction.
+func (r *Renderer) Draw(di *svg.DrawingInstruction) {
+	switch di.Kind {
+	case svg.MoveInstruction:
+		r.start = r.apply(*di.M)
+		r.pos = r.start
+		r.subpaths = append(r.subpaths, subpath{points: []point{r.pos}})
+	case svg.LineInstruction:
+		r.lineTo(r.apply(*di.M))
+	case svg.CurveInstruction:
+		cp := di.CurvePoints
+		r.curveTo(r.apply(*cp.C1), r.apply(*cp.C2), r.apply(*cp.T))
+	case svg.CloseInstruction:
+		if len(r.subpaths) > 0 {
+			r.current().closed = true
+		}
+		r.pos = r.start
+	case svg.CircleInstruction:
+		r.circle(*di.M, *di.Radius)
+	case svg.PaintInstruction:
+		r.paint(di)
+		r.subpaths = r.subpaths[:0]
+	}
+}
+
+func (r *Renderer) curveTo(c1, c2, p point) {
+	for _, v := range svg.FlattenCubic(r.pos, c1, c2, p, r.tolerance(), 0)[1:] {
+		r.lineTo(v)
+	}
+	r.pos = p
+}
+
+func (r *Renderer) circle(c svg.Tuple, radius float64) {
+	pixels := radius * r.scale()
+	n := 8
+	if pixels > r.tolerance() {
+		n = int(math.Ceil(math.Pi / math.Acos(1-r.tolerance()/pixels)))
+	}
+	if n < 8 {
+		n = 8
+	}
+	if n > 1000 {
+		n = 1000
+	}
+	sp := subpath{closed: true}
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		sp.points = append(sp.points, r.apply(svg.Tuple{c[0] + radius*math.Cos(a), c[1] + radius*math.Sin(a)}))
+	}
+	r.subpaths = append(r.subpaths, sp)
+	r.start = sp.points[0]
+	r.pos = r.start
+}
+
+func (r *Renderer) tolerance() float64 {
+	if r.Tolerance <= 0 {
+		return DefaultTolerance
+	}
+	return r.Tolerance
+}
+
+// scale returns the factor by which the transform scales lengths.
+func (r *Renderer) scale() float64 {
+	t := r.Transform
+	return math.Sqrt(math.Abs(t[0][0]*t[1][1] - t[0][1]*t[1][0]))
+}
+
+func (r *Renderer) paint(di *svg.DrawingInstruction) {
+	opacity := 1.0
+	if di.Opacity != nil {
+		opacity = math.Max(0, math.Min(1, *di.Opacity))
+	}
+
+	fill := "black"
+	if di.Fill != nil && strings.TrimSpace(*di.Fill) != "" {
+		fill = *di.Fill
+	}
+	if c, ok := svg.ParseColor(fill); ok {
+		r.raster.reset()
+		for _, sp := range r.subpaths {
+			if len(sp.points) > 2 {
+				r.raster.addPolygon(sp.points)
+			}
+		}
+		rule := svg.NonZero
+		if di.FillRule != nil {
+			rule = svg.ParseFillRule(strings.TrimSpace(*di.FillRule))
+		}
+		r.composite(rule, c, opacity)
+	}
+
+	if di.Stroke == nil || di.StrokeWidth == nil {
+		return
+	}
+	c, ok := svg.ParseColor(*di.Stroke)
+	if !ok {
+		return
+	}
+	seg := svg.Segment{Width: *di.StrokeWidth * r.scale()}
+	if di.StrokeLineCap != nil {
+		seg.LineCap = strings.TrimSpace(*di.StrokeLineCap)
+	}
+	if di.StrokeLineJoin != nil {
+		seg.LineJoin = strings.TrimSpace(*di.StrokeLineJoin)
+	}
+	if di.StrokeMiterLimit != nil {
+		seg.MiterLimit = *di.StrokeMiterLimit
+	}
+	r.raster.reset()
+	for _, sp := range r.subpaths {
+		seg.Closed = sp.closed
+		seg.Points = seg.Points[:0]
+		for _, p := range sp.points {
+			seg.Points = append(seg.Points, p)
+		}
+		for _, o := range seg.StrokeOutline(r.tolerance()) {
+			pts := make([]point, len(o.Points))
+			for i, p := range o.Points {
+				pts[i] = p
+			}
+			r.raster.addPolygon(pts)
+		}
+	}
+	r.composite(svg.NonZero, c, opacity)
+}
+
+// composite blends a colour onto the image through the coverage of the
+// polygons in the rasterizer.
+func (r *Renderer) composite(rule svg.FillRule, c color.NRGBA, opacity float64) {
+	m := r.raster.mask(rule, r.Image.Bounds())
+	if m == nil {
+		return
+	}
+	c.A = uint8(float64(c.A)*opacity + 0.5)
+	draw.DrawMask(r.Image, m.Bounds(), image.NewUniform(c), image.Point{}, m, m.Bounds().Min, draw.Over)
+}
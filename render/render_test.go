@@ -0,0 +1,130 @@
+package render
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rustyoz/svg"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden images in testdata")
+
+var renderTests = []struct {
+	name string
+	svg  string
+}{
+	{"nonzero", `<svg viewBox="0 0 64 64">
+		<path d="M32 4 L50 60 L2 24 L62 24 L14 60 Z" fill="navy"/>
+	</svg>`},
+	{"evenodd", `<svg viewBox="0 0 64 64">
+		<path d="M32 4 L50 60 L2 24 L62 24 L14 60 Z" fill="navy" fill-rule="evenodd"/>
+	</svg>`},
+	{"holes", `<svg viewBox="0 0 64 64">
+		<path d="M4 4 H60 V60 H4 Z M16 16 V48 H48 V16 Z" fill="#c00"/>
+		<path d="M24 24 H40 V40 H24 Z M28 28 H36 V36 H28 Z" fill="green" fill-rule="evenodd"/>
+	</svg>`},
+	{"caps", `<svg viewBox="0 0 64 64">
+		<path d="M12 12 H52" stroke="black" stroke-width="8" fill="none"/>
+		<path d="M12 32 H52" stroke="black" stroke-width="8" stroke-linecap="round" fill="none"/>
+		<path d="M12 52 H52" stroke="black" stroke-width="8" stroke-linecap="square" fill="none"/>
+	</svg>`},
+	{"joins", `<svg viewBox="0 0 64 64">
+		<path d="M6 18 L16 6 L26 18" stroke="black" stroke-width="6" fill="none"/>
+		<path d="M38 18 L48 6 L58 18" stroke="black" stroke-width="6" stroke-linejoin="round" fill="none"/>
+		<path d="M6 44 L16 32 L26 44" stroke="black" stroke-width="6" stroke-linejoin="bevel" fill="none"/>
+		<path d="M38 58 L48 34 L58 58" stroke="black" stroke-width="6" stroke-miterlimit="1" fill="none"/>
+	</svg>`},
+	{"curves", `<svg viewBox="0 0 64 64">
+		<path d="M8 56 C8 8 56 8 56 56 Z" fill="orange" stroke="purple" stroke-width="3"/>
+	</svg>`},
+	{"circle", `<svg viewBox="0 0 64 64">
+		<circle cx="32" cy="32" r="24" fill="teal"/>
+	</svg>`},
+	{"opacity", `<svg viewBox="0 0 64 64">
+		<path d="M4 4 H44 V44 H4 Z" fill="red"/>
+		<path d="M20 20 H60 V60 H20 Z" fill="blue" opacity="0.5"/>
+	</svg>`},
+}
+
+func TestRender(t *testing.T) {
+	for _, tc := range renderTests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := svg.ParseSvg(tc.svg, tc.name, 1)
+			require.NoError(t, err)
+			img, err := Render(s, 64, 64)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", tc.name+".png")
+			if *update {
+				f, err := os.Create(golden)
+				require.NoError(t, err)
+				require.NoError(t, png.Encode(f, img))
+				require.NoError(t, f.Close())
+				return
+			}
+			f, err := os.Open(golden)
+			require.NoError(t, err)
+			defer f.Close()
+			want, err := png.Decode(f)
+			require.NoError(t, err)
+			requireSimilar(t, want, img)
+		})
+	}
+}
+
+// requireSimilar fails unless both images have the same size and no
+// colour channel differs by more than a small tolerance.
+func requireSimilar(t *testing.T, want, got image.Image) {
+	require.Equal(t, want.Bounds(), got.Bounds())
+	const tolerance = 2 << 8
+	b := want.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r0, g0, b0, a0 := want.At(x, y).RGBA()
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			for i, d := range []int{int(r0) - int(r1), int(g0) - int(g1), int(b0) - int(b1), int(a0) - int(a1)} {
+				if d > tolerance || d < -tolerance {
+					t.Fatalf("pixel (%d, %d) channel %d: want %v, got %v", x, y, i, want.At(x, y), got.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestFillRules(t *testing.T) {
+	star := `<svg viewBox="0 0 64 64"><path d="M32 4 L50 60 L2 24 L62 24 L14 60 Z" fill="black"%s/></svg>`
+	centre := func(rule string) uint8 {
+		s, err := svg.ParseSvg(strings.Replace(star, "%s", rule, 1), "star", 1)
+		require.NoError(t, err)
+		img, err := Render(s, 64, 64)
+		require.NoError(t, err)
+		return img.RGBAAt(32, 34).A
+	}
+	require.Equal(t, uint8(255), centre(""))
+	require.Equal(t, uint8(0), centre(` fill-rule="evenodd"`))
+}
+
+func TestAntiAliasing(t *testing.T) {
+	r := &rasterizer{}
+	r.addPolygon([]point{{0.5, 0}, {2, 0}, {2, 1}, {0.5, 1}})
+	m := r.mask(svg.NonZero, image.Rect(0, 0, 4, 4))
+	require.Equal(t, uint8(128), m.AlphaAt(0, 0).A)
+	require.Equal(t, uint8(255), m.AlphaAt(1, 0).A)
+	require.Equal(t, uint8(0), m.AlphaAt(2, 0).A)
+}
+
+func TestViewBoxTransformWithoutViewBox(t *testing.T) {
+	// Lengths are converted to user units, which are pixels.
+	s, err := svg.ParseSvg(`<svg width="1in" height="48px"></svg>`, "size", 1)
+	require.NoError(t, err)
+	m := ViewBoxTransform(s, 48, 24)
+	x, y := m.Apply(96, 48)
+	require.InDelta(t, 48, x, 1e-9)
+	require.InDelta(t, 24, y, 1e-9)
+}
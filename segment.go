@@ -0,0 +1,188 @@
+package svg
+
+import (
+	"math"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// kappa is the distance of the control points from the on-curve points
+// when approximating a quarter circle with a cubic bezier.
+const kappa = 0.5522847498307936
+
+// FlattenOptions control how curves are approximated by line segments.
+type FlattenOptions struct {
+	// Tolerance is the maximum distance between a curve and its line
+	// segments in output units. Zero means DefaultTolerance.
+	Tolerance float64
+	// MaxSegmentLength limits the length of the line segments of
+	// curves in output units. Zero means no limit.
+	MaxSegmentLength float64
+	// Transform maps the points of the document to output units before
+	// curves are flattened. Stroke widths are scaled along. Nil means
+	// the identity. The scale the document was parsed with applies to
+	// stroke widths but not to points, so include it here to scale
+	// points alike.
+	Transform *mt.Transform
+	// Simplify drops points of the segments that matter less than this
+	// distance in output units to their shape, see Segment.Simplify.
+	// Zero keeps all points.
+	Simplify float64
+	// SimplifyMethod is the algorithm used with Simplify.
+	SimplifyMethod SimplifyMethod
+}
+
+// segmentsFromInstructions flattens a stream of drawing instructions
+// into segments. Segments are buffered until the paint instruction of
+// their element is received so they carry its stroke properties.
+func segmentsFromInstructions(instrs chan *DrawingInstruction, errs chan error, opts FlattenOptions) (chan Segment, chan error) {
+	segments := make(chan Segment, 100)
+	segErrs := make(chan error, 100)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errs {
+			segErrs <- err
+		}
+	}()
+
+	go func() {
+		defer close(segments)
+		defer func() { <-done; close(segErrs) }()
+
+		var pending []Segment
+		var current *Segment
+		var start, pos [2]float64
+
+		t := mt.Identity()
+		if opts.Transform != nil {
+			t = *opts.Transform
+		}
+		apply := func(p Tuple) [2]float64 {
+			x, y := t.Apply(p[0], p[1])
+			return [2]float64{x, y}
+		}
+		widthScale := math.Sqrt(math.Abs(t[0][0]*t[1][1] - t[0][1]*t[1][0]))
+		tolerance := opts.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultTolerance
+		}
+
+		finish := func() {
+			if current != nil && len(current.Points) > 1 {
+				if opts.Simplify > 0 {
+					*current = current.Simplify(opts.Simplify, opts.SimplifyMethod)
+				}
+				pending = append(pending, *current)
+			}
+			current = nil
+		}
+		lineTo := func(p [2]float64) {
+			if current == nil {
+				current = &Segment{}
+				current.addPoint(pos)
+			}
+			if p != current.Points[len(current.Points)-1] {
+				current.addPoint(p)
+			}
+			pos = p
+		}
+		curveTo := func(c1, c2, p [2]float64) {
+			for _, v := range FlattenCubic(pos, c1, c2, p, tolerance, opts.MaxSegmentLength)[1:] {
+				lineTo(v)
+			}
+			pos = p
+		}
+
+		for di := range instrs {
+			switch di.Kind {
+			case MoveInstruction:
+				finish()
+				start = apply(*di.M)
+				pos = start
+			case LineInstruction:
+				lineTo(apply(*di.M))
+			case CurveInstruction:
+				cp := di.CurvePoints
+				curveTo(apply(*cp.C1), apply(*cp.C2), apply(*cp.T))
+			case CloseInstruction:
+				if current != nil {
+					lineTo(start)
+					current.Closed = true
+				}
+				finish()
+				pos = start
+			case CircleInstruction:
+				finish()
+				cx, cy, r := di.M[0], di.M[1], *di.Radius
+				start = apply(Tuple{cx + r, cy})
+				pos = start
+				for q := 0; q < 4; q++ {
+					a0 := float64(q) * math.Pi / 2
+					a1 := a0 + math.Pi/2
+					p1 := Tuple{cx + r*math.Cos(a1), cy + r*math.Sin(a1)}
+					c1 := Tuple{cx + r*math.Cos(a0) - kappa*r*math.Sin(a0), cy + r*math.Sin(a0) + kappa*r*math.Cos(a0)}
+					c2 := Tuple{p1[0] + kappa*r*math.Sin(a1), p1[1] - kappa*r*math.Cos(a1)}
+					curveTo(apply(c1), apply(c2), apply(p1))
+				}
+				// A circle of radius 0 draws nothing.
+				if current != nil {
+					current.Points[len(current.Points)-1] = start
+					current.Closed = true
+				}
+				finish()
+			case PaintInstruction:
+				finish()
+				for _, s := range pending {
+					if di.StrokeWidth != nil {
+						s.Width = *di.StrokeWidth * widthScale
+					}
+					if di.StrokeLineCap != nil {
+						s.LineCap = *di.StrokeLineCap
+					}
+					if di.StrokeLineJoin != nil {
+						s.LineJoin = *di.StrokeLineJoin
+					}
+					if di.StrokeMiterLimit != nil {
+						s.MiterLimit = *di.StrokeMiterLimit
+					}
+					segments <- s
+				}
+				pending = nil
+			}
+		}
+		finish()
+		for _, s := range pending {
+			segments <- s
+		}
+	}()
+
+	return segments, segErrs
+}
+
+// ParseSegments flattens all the elements of the group that would be
+// drawn by ParseDrawingInstructions into segments.
+func (g *Group) ParseSegments() (chan Segment, chan error) {
+	return g.ParseSegmentsWith(FlattenOptions{})
+}
+
+// ParseSegmentsWith is like ParseSegments with control over the
+// transform and the precision of flattening.
+func (g *Group) ParseSegmentsWith(opts FlattenOptions) (chan Segment, chan error) {
+	instrs, errs := g.ParseDrawingInstructions()
+	return segmentsFromInstructions(instrs, errs, opts)
+}
+
+// ParseSegments flattens all the elements of the document that would be
+// drawn by ParseDrawingInstructions into segments.
+func (s *Svg) ParseSegments() (chan Segment, chan error) {
+	return s.ParseSegmentsWith(FlattenOptions{})
+}
+
+// ParseSegmentsWith is like ParseSegments with control over the
+// transform and the precision of flattening.
+func (s *Svg) ParseSegmentsWith(opts FlattenOptions) (chan Segment, chan error) {
+	instrs, errs := s.ParseDrawingInstructions()
+	return segmentsFromInstructions(instrs, errs, opts)
+}
@@ -0,0 +1,198 @@
+package svg
+
+import (
+	"container/heap"
+	"math"
+)
+
+// SimplifyMethod selects the algorithm used to simplify segments.
+type SimplifyMethod int
+
+// These are the algorithms for simplifying segments.
+const (
+	// DouglasPeucker keeps the points needed to stay within the
+	// tolerance of the original points, with the Ramer-Douglas-Peucker
+	// algorithm.
+	DouglasPeucker SimplifyMethod = iota
+	// Visvalingam repeatedly drops the point forming the smallest
+	// triangle with its neighbours, as long as the area of that triangle
+	// is below the square of the tolerance.
+	Visvalingam
+)
+
+// Simplify returns the segment with points dropped that matter less than
+// tolerance to its shape. The first and the last point are kept, and
+// closed segments stay closed with at least three distinct points if
+// they had them.
+func (s Segment) Simplify(tolerance float64, method SimplifyMethod) Segment {
+	pts := s.Points
+	repeated := len(pts) > 1 && pts[0] == pts[len(pts)-1]
+	if s.Closed && !repeated && len(pts) > 0 {
+		pts = append(append([][2]float64{}, pts...), pts[0])
+	}
+	least := 2
+	if s.Closed {
+		least = 4
+	}
+	if len(pts) <= least || tolerance <= 0 {
+		return s
+	}
+
+	var keep []bool
+	switch method {
+	case Visvalingam:
+		keep = visvalingam(pts, tolerance*tolerance, least)
+	default:
+		keep = douglasPeucker(pts, tolerance)
+		if s.Closed {
+			keepTriangle(pts, keep)
+		}
+	}
+	out := make([][2]float64, 0, len(pts))
+	for i, p := range pts {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	if s.Closed && !repeated {
+		out = out[:len(out)-1]
+	}
+	s.Points = out
+	return s
+}
+
+// SimplifySegments simplifies each of the segments.
+func SimplifySegments(segments []Segment, tolerance float64, method SimplifyMethod) []Segment {
+	out := make([]Segment, len(segments))
+	for i, s := range segments {
+		out[i] = s.Simplify(tolerance, method)
+	}
+	return out
+}
+
+// douglasPeucker marks the points to keep so that no point is further
+// than tolerance from the line through the kept points around it.
+func douglasPeucker(pts [][2]float64, tolerance float64) []bool {
+	keep := make([]bool, len(pts))
+	keep[0], keep[len(pts)-1] = true, true
+	stack := [][2]int{{0, len(pts) - 1}}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		far, farDist := -1, tolerance
+		for i := r[0] + 1; i < r[1]; i++ {
+			if d := distanceToSegment(pts[i], pts[r[0]], pts[r[1]]); d > farDist {
+				far, farDist = i, d
+			}
+		}
+		if far >= 0 {
+			keep[far] = true
+			stack = append(stack, [2]int{r[0], far}, [2]int{far, r[1]})
+		}
+	}
+	return keep
+}
+
+// keepTriangle keeps the points of a closed polyline furthest from its
+// start and from the line through both when fewer than three distinct
+// points are kept.
+func keepTriangle(pts [][2]float64, keep []bool) {
+	var n int
+	for i := range pts[:len(pts)-1] {
+		if keep[i] {
+			n++
+		}
+	}
+	if n >= 3 {
+		return
+	}
+	far, farDist := 0, 0.0
+	for i, p := range pts {
+		if d := vlen(vsub(p, pts[0])); d > farDist {
+			far, farDist = i, d
+		}
+	}
+	side, sideDist := 0, 0.0
+	for i, p := range pts {
+		if d := distanceToLine(p, pts[0], pts[far]); d > sideDist {
+			side, sideDist = i, d
+		}
+	}
+	keep[far], keep[side] = true, true
+}
+
+// distanceToSegment returns the distance from p to the line segment from
+// a to b.
+func distanceToSegment(p, a, b [2]float64) float64 {
+	d := vsub(b, a)
+	t := 0.0
+	if l2 := vdot(d, d); l2 > 0 {
+		t = math.Max(0, math.Min(1, vdot(vsub(p, a), d)/l2))
+	}
+	return vlen(vsub(p, vadd(a, vscale(d, t))))
+}
+
+// visvalingam marks the points to keep after dropping the points with
+// the smallest triangles while their area is below threshold and more
+// than least points are left.
+func visvalingam(pts [][2]float64, threshold float64, least int) []bool {
+	n := len(pts)
+	keep := make([]bool, n)
+	prev := make([]int, n)
+	next := make([]int, n)
+	version := make([]int, n)
+	area := func(i int) float64 {
+		return math.Abs(vcross(vsub(pts[i], pts[prev[i]]), vsub(pts[next[i]], pts[prev[i]]))) / 2
+	}
+	q := &triangleQueue{}
+	for i := range pts {
+		keep[i] = true
+		prev[i], next[i] = i-1, i+1
+	}
+	for i := 1; i < n-1; i++ {
+		heap.Push(q, triangle{point: i, area: area(i)})
+	}
+	left := n
+	for q.Len() > 0 && left > least {
+		t := heap.Pop(q).(triangle)
+		if t.version != version[t.point] {
+			continue
+		}
+		if t.area >= threshold {
+			break
+		}
+		i := t.point
+		keep[i] = false
+		left--
+		p, nx := prev[i], next[i]
+		next[p], prev[nx] = nx, p
+		for _, j := range []int{p, nx} {
+			if j > 0 && j < n-1 {
+				version[j]++
+				heap.Push(q, triangle{point: j, area: area(j), version: version[j]})
+			}
+		}
+	}
+	return keep
+}
+
+// triangleQueue is a priority queue of the points of a polyline by the
+// area of the triangle they form with their neighbours.
+type triangleQueue []triangle
+
+type triangle struct {
+	point   int
+	area    float64
+	version int
+}
+
+func (q triangleQueue) Len() int            { return len(q) }
+func (q triangleQueue) Less(i, j int) bool  { return q[i].area < q[j].area }
+func (q triangleQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *triangleQueue) Push(x interface{}) { *q = append(*q, x.(triangle)) }
+func (q *triangleQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,103 @@
+package svg
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplify(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// A noisy line up to a corner and down again.
+	var open Segment
+	for i := 0; i <= 100; i++ {
+		open.Points = append(open.Points, [2]float64{float64(i), (r.Float64() - 0.5) * 0.01})
+	}
+	for i := 1; i <= 100; i++ {
+		open.Points = append(open.Points, [2]float64{100, float64(i) + (r.Float64()-0.5)*0.01})
+	}
+
+	// Visvalingam compares areas, which grow with the distance between
+	// the points left.
+	for method, tolerance := range map[SimplifyMethod]float64{DouglasPeucker: 0.1, Visvalingam: 1} {
+		s := open.Simplify(tolerance, method)
+		require.Len(t, s.Points, 3, method)
+		require.Equal(t, open.Points[0], s.Points[0])
+		require.Equal(t, open.Points[100], s.Points[1])
+		require.Equal(t, open.Points[200], s.Points[2])
+		require.False(t, s.Closed)
+		require.Len(t, open.Points, 201)
+	}
+
+	// DouglasPeucker stays within the tolerance.
+	var wave Segment
+	for i := 0; i <= 1000; i++ {
+		x := float64(i) / 10
+		wave.Points = append(wave.Points, [2]float64{x, 10 * math.Sin(x/5)})
+	}
+	simplified := wave.Simplify(0.05, DouglasPeucker)
+	require.Less(t, len(simplified.Points), 100)
+	for _, p := range wave.Points {
+		require.LessOrEqual(t, distanceToSegments([]Segment{simplified}, p), 0.05+1e-12)
+	}
+	require.Len(t, wave.Simplify(0, DouglasPeucker).Points, len(wave.Points))
+}
+
+func TestSimplifyClosed(t *testing.T) {
+	// A square with points along its sides, ending at its start.
+	var square Segment
+	square.Closed = true
+	corners := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	for c := 0; c < 4; c++ {
+		for i := 0; i < 10; i++ {
+			square.Points = append(square.Points, lerp(corners[c], corners[c+1], float64(i)/10))
+		}
+	}
+	square.Points = append(square.Points, [2]float64{0, 0})
+
+	for _, method := range []SimplifyMethod{DouglasPeucker, Visvalingam} {
+		s := square.Simplify(0.1, method)
+		require.True(t, s.Closed)
+		require.Equal(t, corners, s.Points, method)
+
+		// Without the repeated start point.
+		open := square
+		open.Points = square.Points[:len(square.Points)-1]
+		s = open.Simplify(0.1, method)
+		require.Equal(t, corners[:4], s.Points, method)
+
+		// Tiny loops keep a triangle.
+		s = square.Simplify(100, method)
+		require.True(t, s.Closed)
+		require.Len(t, s.Points, 4, method)
+		require.Equal(t, s.Points[0], s.Points[3])
+		require.NotEqual(t, s.Points[0], s.Points[1])
+		require.NotEqual(t, s.Points[1], s.Points[2])
+	}
+}
+
+func TestParseSimplifiedSegments(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 100 100">
+		<circle cx="50" cy="50" r="40"/>
+		<path d="M0 0 C0 50 100 50 100 0"/>
+	</svg>`, "simplify", 1)
+	require.NoError(t, err)
+
+	count := func(opts FlattenOptions) int {
+		segs, errs := s.ParseSegmentsWith(opts)
+		var n int
+		for seg := range segs {
+			n += len(seg.Points)
+		}
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		return n
+	}
+	fine := FlattenOptions{Tolerance: 0.001}
+	simplified := fine
+	simplified.Simplify = 0.5
+	require.Less(t, count(simplified)*3, count(fine))
+}
@@ -0,0 +1,295 @@
+package svg
+
+import "math"
+
+// defaultMiterLimit is the initial value of stroke-miterlimit.
+const defaultMiterLimit = 4
+
+func vsub(a, b [2]float64) [2]float64           { return [2]float64{a[0] - b[0], a[1] - b[1]} }
+func vadd(a, b [2]float64) [2]float64           { return [2]float64{a[0] + b[0], a[1] + b[1]} }
+func vscale(a [2]float64, f float64) [2]float64 { return [2]float64{a[0] * f, a[1] * f} }
+func vcross(a, b [2]float64) float64            { return a[0]*b[1] - a[1]*b[0] }
+func vdot(a, b [2]float64) float64              { return a[0]*b[0] + a[1]*b[1] }
+func vlen(a [2]float64) float64                 { return math.Hypot(a[0], a[1]) }
+
+// vnormal returns the unit vector perpendicular to the direction from a
+// to b, rotated by +90 degrees.
+func vnormal(a, b [2]float64) [2]float64 {
+	d := vsub(b, a)
+	l := vlen(d)
+	return [2]float64{-d[1] / l, d[0] / l}
+}
+
+// outliner expands the centre line of a segment into its stroke outline.
+type outliner struct {
+	hw         float64
+	cap        string
+	join       string
+	miterLimit float64
+	tolerance  float64
+	// pivot routes all inner corners through their vertex, which keeps
+	// the winding of overshooting offsets reversed.
+	pivot bool
+	out   [][2]float64
+}
+
+func (o *outliner) add(p [2]float64) {
+	if n := len(o.out); n == 0 || o.out[n-1] != p {
+		o.out = append(o.out, p)
+	}
+}
+
+// arc adds the points of the arc of radius hw around c from the
+// direction v1 to v1 rotated by sweep radians. The first point is left
+// out.
+func (o *outliner) arc(c, v1 [2]float64, sweep float64) {
+	step := math.Pi / 2
+	if o.hw > o.tolerance {
+		step = 2 * math.Acos(1-o.tolerance/o.hw)
+	}
+	n := int(math.Ceil(math.Abs(sweep) / step))
+	if n < 1 {
+		n = 1
+	}
+	for i := 1; i <= n; i++ {
+		a := sweep * float64(i) / float64(n)
+		sin, cos := math.Sincos(a)
+		o.add(vadd(c, vscale([2]float64{v1[0]*cos - v1[1]*sin, v1[0]*sin + v1[1]*cos}, o.hw)))
+	}
+}
+
+// joinAt adds the corner at p between the segments prev-p and p-next on
+// the side of the positive normal.
+func (o *outliner) joinAt(prev, p, next [2]float64) {
+	n1, n2 := vnormal(prev, p), vnormal(p, next)
+	d1, d2 := vsub(p, prev), vsub(next, p)
+	o1, o2 := vadd(p, vscale(n1, o.hw)), vadd(p, vscale(n2, o.hw))
+	turn := vcross(d1, d2)
+	if turn == 0 && vdot(d1, d2) > 0 {
+		o.add(o1)
+		return
+	}
+	if turn > 0 {
+		// Inner corner: cut at the crossing of both offset edges. When
+		// the edges are too short to cross, route through the vertex so
+		// the outline stays inside the stroke.
+		a, b := vadd(prev, vscale(n1, o.hw)), vadd(next, vscale(n2, o.hw))
+		e1, e2 := vsub(o1, a), vsub(b, o2)
+		den := vcross(e1, e2)
+		t := vcross(vsub(o2, a), e2) / den
+		u := vcross(vsub(o2, a), e1) / den
+		if !o.pivot && t >= 0 && t <= 1 && u >= 0 && u <= 1 {
+			o.add(vadd(a, vscale(e1, t)))
+			return
+		}
+		o.add(o1)
+		o.add(p)
+		o.add(o2)
+		return
+	}
+	o.add(o1)
+	switch o.join {
+	case "round":
+		sweep := math.Atan2(vcross(n1, n2), vdot(n1, n2))
+		if turn == 0 {
+			sweep = -math.Pi
+		}
+		o.arc(p, n1, sweep)
+	case "bevel":
+	case "square":
+		// Not an SVG line join: the corner is cut square at distance hw
+		// from the vertex, as used by offsetting.
+		angle := math.Pi
+		if turn != 0 {
+			angle = math.Abs(math.Atan2(vcross(n1, n2), vdot(n1, n2)))
+		}
+		x := o.hw * math.Tan(angle/4)
+		o.add(vadd(o1, vscale(d1, x/vlen(d1))))
+		o.add(vsub(o2, vscale(d2, x/vlen(d2))))
+	default:
+		sum := vadd(n1, n2)
+		l2 := vdot(sum, sum)
+		if l2 > 0 && 2/math.Sqrt(l2) <= o.miterLimit {
+			o.add(vadd(p, vscale(sum, o.hw*2/l2)))
+		}
+	}
+	o.add(o2)
+}
+
+// capAt adds the cap at the end p of the segment from prev to p. It
+// starts at the positive side and ends at the negative side.
+func (o *outliner) capAt(prev, p [2]float64) {
+	n := vnormal(prev, p)
+	d := vsub(p, prev)
+	d = vscale(d, o.hw/vlen(d))
+	left, right := vadd(p, vscale(n, o.hw)), vsub(p, vscale(n, o.hw))
+	o.add(left)
+	switch o.cap {
+	case "round":
+		o.arc(p, n, -math.Pi)
+	case "square":
+		o.add(vadd(left, d))
+		o.add(vadd(right, d))
+	}
+	o.add(right)
+}
+
+// side adds the offset of the open polyline pts on the side of the
+// positive normal, without the end points.
+func (o *outliner) side(pts [][2]float64) {
+	for i := 1; i < len(pts)-1; i++ {
+		o.joinAt(pts[i-1], pts[i], pts[i+1])
+	}
+}
+
+// loop returns the closed offset of the closed polyline pts on the side
+// of the positive normal.
+func (o *outliner) loop(pts [][2]float64) [][2]float64 {
+	o.out = nil
+	n := len(pts)
+	for i := range pts {
+		o.joinAt(pts[(i+n-1)%n], pts[i], pts[(i+1)%n])
+	}
+	if len(o.out) > 1 && o.out[0] == o.out[len(o.out)-1] {
+		o.out = o.out[:len(o.out)-1]
+	}
+	return o.out
+}
+
+func reversed(pts [][2]float64) [][2]float64 {
+	r := make([][2]float64, len(pts))
+	for i, p := range pts {
+		r[len(pts)-1-i] = p
+	}
+	return r
+}
+
+// StrokeOutline returns the outline of the area covered when the
+// segment is stroked with its Width, LineCap, LineJoin and MiterLimit.
+// Round caps and joins are flattened so that no point of the outline is
+// further than tolerance from the true arc; a tolerance of 0 picks one
+// relative to the width.
+//
+// The outlines are simple rings that do not overlap, as a cutter
+// follows them: like the result of Clip, outer boundaries have a
+// positive signed area and holes, such as the inside of a stroked
+// closed segment, a negative one.
+func (s Segment) StrokeOutline(tolerance float64) []Segment {
+	return Clip(Union, s.strokeOutline(tolerance), NonZero, nil, NonZero)
+}
+
+// strokeOutline returns the raw outline of the stroke. Open segments
+// give one closed outline. Closed segments give one outline on each
+// side of the centre line, with opposite orientations. The outlines
+// overlap and may intersect themselves at sharp inner corners, so they
+// only cover the stroke when filled with the nonzero rule.
+func (s Segment) strokeOutline(tolerance float64) []Segment {
+	hw := s.Width / 2
+	if hw <= 0 {
+		return nil
+	}
+	o := &outliner{hw: hw, cap: s.LineCap, join: s.LineJoin, miterLimit: s.MiterLimit, tolerance: tolerance}
+	if o.miterLimit < 1 {
+		o.miterLimit = defaultMiterLimit
+	}
+	if o.tolerance <= 0 {
+		o.tolerance = hw / 100
+	}
+
+	var pts [][2]float64
+	for _, p := range s.Points {
+		if len(pts) == 0 || p != pts[len(pts)-1] {
+			pts = append(pts, p)
+		}
+	}
+	if s.Closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	switch {
+	case len(pts) == 0:
+		return nil
+	case len(pts) == 1:
+		p := pts[0]
+		switch {
+		case s.Closed:
+		case o.cap == "round":
+			o.add([2]float64{p[0] + hw, p[1]})
+			o.arc(p, [2]float64{1, 0}, 2*math.Pi)
+			o.out = o.out[:len(o.out)-1]
+		case o.cap == "square":
+			o.out = [][2]float64{{p[0] - hw, p[1] - hw}, {p[0] + hw, p[1] - hw}, {p[0] + hw, p[1] + hw}, {p[0] - hw, p[1] + hw}}
+		}
+		if len(o.out) == 0 {
+			return nil
+		}
+		return []Segment{{Closed: true, Points: o.out}}
+	case s.Closed && len(pts) == 2:
+		// Both sides of a closed line trace the same outline.
+		return []Segment{{Closed: true, Points: o.loop(pts)}}
+	case s.Closed:
+		left := o.loop(pts)
+		right := o.loop(reversed(pts))
+		return []Segment{{Closed: true, Points: left}, {Closed: true, Points: right}}
+	}
+
+	back := reversed(pts)
+	n := len(pts)
+	o.capAt(back[n-2], pts[0])
+	o.side(pts)
+	o.capAt(pts[n-2], pts[n-1])
+	o.side(back)
+	if o.out[0] == o.out[len(o.out)-1] {
+		o.out = o.out[:len(o.out)-1]
+	}
+	return []Segment{{Closed: true, Points: o.out}}
+}
+
+// InstructionsFromSegments converts segments into move, line and close
+// drawing instructions. Use PathStringFromDrawingInstructions to turn
+// them into a path element.
+func InstructionsFromSegments(segments []Segment) []*DrawingInstruction {
+	var dis []*DrawingInstruction
+	for _, s := range segments {
+		for i, p := range s.Points {
+			t := Tuple(p)
+			kind := LineInstruction
+			if i == 0 {
+				kind = MoveInstruction
+			}
+			dis = append(dis, &DrawingInstruction{Kind: kind, M: &t})
+		}
+		if s.Closed && len(s.Points) > 0 {
+			dis = append(dis, &DrawingInstruction{Kind: CloseInstruction})
+		}
+	}
+	return dis
+}
+
+// strokeOutlines expands each of the segments into its stroke outlines.
+func strokeOutlines(segments chan Segment, tolerance float64) chan Segment {
+	outlines := make(chan Segment, 100)
+	go func() {
+		defer close(outlines)
+		for s := range segments {
+			for _, o := range s.StrokeOutline(tolerance) {
+				outlines <- o
+			}
+		}
+	}()
+	return outlines
+}
+
+// ParseStrokeOutlines returns the stroke outlines of all the segments of
+// the group. See Segment.StrokeOutline.
+func (g *Group) ParseStrokeOutlines(tolerance float64) (chan Segment, chan error) {
+	segments, errs := g.ParseSegments()
+	return strokeOutlines(segments, tolerance), errs
+}
+
+// ParseStrokeOutlines returns the stroke outlines of all the segments of
+// the document. See Segment.StrokeOutline.
+func (s *Svg) ParseStrokeOutlines(tolerance float64) (chan Segment, chan error) {
+	segments, errs := s.ParseSegments()
+	return strokeOutlines(segments, tolerance), errs
+}
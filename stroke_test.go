@@ -0,0 +1,107 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signedArea returns the sum of the signed areas of the segments.
+func signedArea(segments []Segment) float64 {
+	var a float64
+	for _, s := range segments {
+		for i, p := range s.Points {
+			q := s.Points[(i+1)%len(s.Points)]
+			a += p[0]*q[1] - q[0]*p[1]
+		}
+	}
+	return a / 2
+}
+
+func TestStrokeOutlineCaps(t *testing.T) {
+	line := Segment{Width: 2, Points: [][2]float64{{0, 0}, {10, 0}}}
+
+	butt := line.StrokeOutline(0)
+	require.Len(t, butt, 1)
+	require.True(t, butt[0].Closed)
+	require.Equal(t, [][2]float64{{0, 1}, {0, -1}, {10, -1}, {10, 1}}, butt[0].Points)
+
+	line.LineCap = "square"
+	require.InDelta(t, 24, math.Abs(signedArea(line.StrokeOutline(0))), 1e-9)
+
+	line.LineCap = "round"
+	require.InDelta(t, 20+math.Pi, math.Abs(signedArea(line.StrokeOutline(0.001))), 0.01)
+
+	dot := Segment{Width: 2, LineCap: "round", Points: [][2]float64{{5, 5}, {5, 5}}}
+	require.InDelta(t, math.Pi, math.Abs(signedArea(dot.StrokeOutline(0.001))), 0.01)
+	dot.LineCap = ""
+	require.Empty(t, dot.StrokeOutline(0))
+
+	require.Empty(t, Segment{Points: line.Points}.StrokeOutline(0))
+}
+
+func TestStrokeOutlineJoins(t *testing.T) {
+	// A right angle turning clockwise on screen, so the outer corner is
+	// at the top right.
+	corner := Segment{Width: 2, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}}}
+	area := func(join string, limit float64) float64 {
+		s := corner
+		s.LineJoin, s.MiterLimit = join, limit
+		return math.Abs(signedArea(s.StrokeOutline(0.0001)))
+	}
+	// Two 10x2 rectangles overlapping in a 1x1 square at the inner side
+	// of the corner, plus the outer corner piece.
+	require.InDelta(t, 40-1+1, area("miter", 0), 1e-9)
+	require.InDelta(t, 40-1+0.5, area("bevel", 0), 1e-9)
+	require.InDelta(t, 40-1+math.Pi/4, area("round", 0), 1e-3)
+	require.InDelta(t, 40-1+0.5, area("miter", 1.2), 1e-9)
+}
+
+func TestStrokeOutlineClosed(t *testing.T) {
+	square := Segment{Width: 2, Closed: true, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	outlines := square.StrokeOutline(0)
+	require.Len(t, outlines, 2)
+	a, b := signedArea(outlines[:1]), signedArea(outlines[1:])
+	require.True(t, a*b < 0, "outlines must have opposite orientation")
+	require.InDelta(t, 144, math.Max(math.Abs(a), math.Abs(b)), 1e-9)
+	require.InDelta(t, 64, math.Min(math.Abs(a), math.Abs(b)), 1e-9)
+	require.InDelta(t, 80, math.Abs(signedArea(outlines)), 1e-9)
+}
+
+func TestStrokeOutlineSimple(t *testing.T) {
+	// Outlines can be followed by a cutter: they neither overlap nor
+	// cross themselves, also at sharp inner corners.
+	square := Segment{Width: 2, Closed: true, Points: [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	sharp := Segment{Width: 2, LineJoin: "round", Points: [][2]float64{{0, 0}, {10, 0}, {0, 1}}}
+	for _, s := range []Segment{square, sharp} {
+		rings := s.StrokeOutline(0.01)
+		g := GeometryFromInstructions(InstructionsFromSegments(rings))
+		require.Empty(t, g.SelfIntersections())
+	}
+
+	rings := square.StrokeOutline(0)
+	require.Len(t, rings, 2)
+	require.InDelta(t, 80, signedArea(rings), 1e-9)
+}
+
+func TestParseStrokeOutlines(t *testing.T) {
+	s, err := ParseSvg(`<svg viewBox="0 0 20 20">
+		<path d="M0 0 L10 0" stroke="black" stroke-width="2" stroke-linecap="square"/>
+	</svg>`, "outline", 1)
+	require.NoError(t, err)
+
+	outlines, errs := s.ParseStrokeOutlines(0)
+	var got []Segment
+	for o := range outlines {
+		got = append(got, o)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, got, 1)
+	require.Equal(t, [][2]float64{{-1, -1}, {11, -1}, {11, 1}, {-1, 1}}, got[0].Points)
+
+	d := PathStringFromDrawingInstructions(InstructionsFromSegments(got))
+	require.Equal(t, `<path d=" M-1 -1 L11 -1 L11 1 L-1 1 Z" />`, d)
+}
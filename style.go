@@ -1,6 +1,7 @@
 package svg
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -18,3 +19,65 @@ func splitStyle(style string) map[string]string {
 
 	return r
 }
+
+// shapePaint resolves the paint of an element without paint fields of
+// its own, such as a circle or rect. A property is taken from the style,
+// then from the attributes, and fill, stroke, stroke-width and fill-rule
+// finally from the group, which may be nil. A fill that is not nil is
+// the fill attribute of the element.
+func shapePaint(style string, attrs Attributes, fill *string, g *Group) *DrawingInstruction {
+	props := make(map[string]string)
+	for k, v := range splitStyle(style) {
+		props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	property := func(key, inherited string) *string {
+		if v, ok := props[key]; ok {
+			return &v
+		}
+		if v, ok := attrs.Get(key); ok {
+			v = strings.TrimSpace(v)
+			return &v
+		}
+		if inherited != "" {
+			return &inherited
+		}
+		return nil
+	}
+	number := func(key string) *float64 {
+		if v := property(key, ""); v != nil {
+			if f, err := strconv.ParseFloat(*v, 64); err == nil {
+				return &f
+			}
+		}
+		return nil
+	}
+
+	di := &DrawingInstruction{Kind: PaintInstruction}
+	var inherited struct{ fill, stroke, fillRule string }
+	width, scale := 1.0, 1.0
+	if g != nil {
+		inherited.fill, inherited.stroke, inherited.fillRule = g.Fill, g.Stroke, g.FillRule
+		if g.StrokeWidth != 0 {
+			width = g.StrokeWidth
+		}
+		if g.Owner != nil {
+			scale = g.Owner.scale
+		}
+	}
+	if fill != nil && strings.TrimSpace(*fill) != "" {
+		inherited.fill = strings.TrimSpace(*fill)
+	}
+	di.Fill = property("fill", inherited.fill)
+	di.Stroke = property("stroke", inherited.stroke)
+	di.FillRule = property("fill-rule", inherited.fillRule)
+	di.StrokeLineCap = property("stroke-linecap", "")
+	di.StrokeLineJoin = property("stroke-linejoin", "")
+	di.StrokeMiterLimit = number("stroke-miterlimit")
+	di.Opacity = number("opacity")
+	if w := number("stroke-width"); w != nil {
+		width = *w
+	}
+	width *= scale
+	di.StrokeWidth = &width
+	return di
+}
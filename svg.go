@@ -19,24 +19,59 @@ type DrawingInstructionParser interface {
 	ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 }
 
+// CollectInstructions returns all drawing instructions of an element and
+// the first error reported with them.
+func CollectInstructions(e DrawingInstructionParser) ([]*DrawingInstruction, error) {
+	instrs, errs := e.ParseDrawingInstructions()
+	done := make(chan error)
+	go func() {
+		var first error
+		for err := range errs {
+			if first == nil {
+				first = err
+			}
+		}
+		done <- first
+	}()
+	var out []*DrawingInstruction
+	for di := range instrs {
+		out = append(out, di)
+	}
+	return out, <-done
+}
+
 // Tuple is an X,Y coordinate
 type Tuple [2]float64
 
 // Svg represents an SVG file containing at least a top level group or a
 // number of Paths
+//
+// Children holds the top level elements and groups in document order.
+// Groups and Elements hold the same groups and elements split by kind.
+// Elements and groups which are added to Groups or Elements but not to
+// Children are drawn after the children. Parsed groups keep their place
+// when Groups is appended to and reallocated.
 type Svg struct {
-	Title        string  `xml:"title"`
-	Groups       []Group `xml:"g"`
-	Width        string  `xml:"width,attr"`
-	Height       string  `xml:"height,attr"`
-	ViewBox      string  `xml:"viewBox,attr"`
-	Elements     []DrawingInstructionParser
-	Name         string
-	Transform    *mt.Transform
-	scale        float64
-	instructions chan *DrawingInstruction
-	errors       chan error
-	segments     chan Segment
+	Title         string  `xml:"title"`
+	Groups        []Group `xml:"g"`
+	Width         string  `xml:"width,attr"`
+	Height        string  `xml:"height,attr"`
+	ViewBox       string  `xml:"viewBox,attr"`
+	Elements      []DrawingInstructionParser
+	Children      []DrawingInstructionParser
+	Name          string
+	Transform     *mt.Transform
+	Attrs         Attributes
+	scale         float64
+	includeHidden bool
+	layers        map[string]bool
+	source        *xmlSource
+	content       []interface{}
+	prolog        []xml.Token
+	epilog        []xml.Token
+	instructions  chan *DrawingInstruction
+	errors        chan error
+	segments      chan Segment
 }
 
 // Group represents an SVG group (usually located in a 'g' XML element)
@@ -47,16 +82,29 @@ type Group struct {
 	Fill            string
 	Opacity         float64
 	FillRule        string
+	Style           string
+	Display         string
+	Visibility      string
+	Layer           *Layer
+	Attrs           Attributes
 	Elements        []DrawingInstructionParser
 	TransformString string
 	Transform       *mt.Transform // row, column
 	Parent          *Group
 	Owner           *Svg
+	source          *xmlSource
+	content         []interface{}
+	key             *groupKey
 	instructions    chan *DrawingInstruction
 	errors          chan error
 	segments        chan Segment
 }
 
+// groupKey identifies a top level group parsed from a document. It is
+// copied along with the group, so the group is found in Groups after
+// the slice was reallocated.
+type groupKey struct{ _ byte }
+
 // ParseDrawingInstructions implements the DrawingInstructionParser interface
 //
 // This method makes it easier to get all the drawing instructions.
@@ -69,7 +117,15 @@ func (g *Group) ParseDrawingInstructions() (chan *DrawingInstruction, chan error
 	go func() {
 		defer close(g.instructions)
 		defer func() { errWg.Wait(); close(g.errors) }()
+		visibility := g.computedVisibility()
+		selected := g.inSelectedLayer()
 		for _, e := range g.Elements {
+			if !g.Owner.showsHidden() && !rendered(e, visibility) {
+				continue
+			}
+			if _, ok := e.(*Group); !ok && !selected {
+				continue
+			}
 			instrs, errs := e.ParseDrawingInstructions()
 			errWg.Add(1)
 			go func() {
@@ -89,7 +145,30 @@ func (g *Group) ParseDrawingInstructions() (chan *DrawingInstruction, chan error
 
 // UnmarshalXML implements the encoding.xml.Unmarshaler interface
 func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var groupmode string
+	var layer Layer
 	for _, attr := range start.Attr {
+		switch {
+		case inNamespace(attr.Name, "inkscape", inkscapeNamespace):
+			switch attr.Name.Local {
+			case "groupmode":
+				groupmode = attr.Value
+			case "label":
+				layer.Label = attr.Value
+			}
+			g.Attrs.Set(attr.Name, attr.Value)
+			continue
+		case inNamespace(attr.Name, "sodipodi", sodipodiNamespace):
+			if attr.Name.Local == "insensitive" {
+				layer.Locked = attr.Value == "true"
+			}
+			g.Attrs.Set(attr.Name, attr.Value)
+			continue
+		case attr.Name.Space != "":
+			g.Attrs.Set(attr.Name, attr.Value)
+			continue
+		}
+
 		switch attr.Name.Local {
 		case "id":
 			g.ID = attr.Value
@@ -111,6 +190,12 @@ func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error
 			g.Opacity = floatValue
 		case "fill-rule":
 			g.FillRule = attr.Value
+		case "style":
+			g.Style = attr.Value
+		case "display":
+			g.Display = attr.Value
+		case "visibility":
+			g.Visibility = attr.Value
 		case "transform":
 			g.TransformString = attr.Value
 			t, err := parseTransform(g.TransformString)
@@ -118,9 +203,21 @@ func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error
 				fmt.Println(err)
 			}
 			g.Transform = &t
+		default:
+			g.Attrs.Set(attr.Name, attr.Value)
 		}
 	}
+	newXMLSource(g, start)
+	if groupmode == "layer" {
+		layer.ID = g.ID
+		layer.Hidden = g.display() == "none" || g.visibility() == "hidden"
+		layer.Group = g
+		g.Layer = &layer
+	}
 
+	// depth counts the unknown elements the decoder is in. Their
+	// content is parsed as if it was part of the group.
+	var depth int
 	for {
 		token, err := decoder.Token()
 		if err != nil {
@@ -139,18 +236,29 @@ func (g *Group) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error
 			case "circle":
 				elementStruct = &Circle{group: g}
 			case "path":
-				elementStruct = &Path{group: g, StrokeWidth: float64(g.StrokeWidth), Stroke: &g.Stroke, Fill: &g.Fill}
+				// Copies, so that decoding the attributes of the path
+				// leaves the group and its other paths alone.
+				stroke, fill, fillRule := g.Stroke, g.Fill, g.FillRule
+				elementStruct = &Path{group: g, StrokeWidth: float64(g.StrokeWidth), Stroke: &stroke, Fill: &fill, FillRule: &fillRule}
 			default:
+				g.content = append(g.content, tok.Copy())
+				depth++
 				continue
 			}
 			if err = decoder.DecodeElement(elementStruct, &tok); err != nil {
 				return fmt.Errorf("error decoding element of Group: %s", err)
 			}
+			setSource(elementStruct, tok)
 			g.Elements = append(g.Elements, elementStruct)
+			g.content = append(g.content, elementStruct)
 		case xml.EndElement:
-			if tok.Name.Local == "g" {
+			if depth == 0 {
 				return nil
 			}
+			g.content = append(g.content, tok)
+			depth--
+		default:
+			g.content = append(g.content, xml.CopyToken(tok))
 		}
 	}
 }
@@ -167,31 +275,33 @@ func (s *Svg) ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 		var elecount int
 		defer close(s.instructions)
 		defer func() { errWg.Wait(); close(s.errors) }()
-		for _, e := range s.Elements {
-			elecount++
+		for _, e := range s.children() {
+			if !s.showsHidden() && !rendered(e, "visible") {
+				continue
+			}
+			_, isGroup := e.(*Group)
+			if !isGroup && s.layers != nil {
+				continue
+			}
 			instrs, errs := e.ParseDrawingInstructions()
 			errWg.Add(1)
-			go func(count int) {
-				for er := range errs {
-					s.errors <- fmt.Errorf("error when parsing element nr. %d: %s", count, er)
-				}
-				errWg.Done()
-			}(elecount)
-
-			for is := range instrs {
-				s.instructions <- is
+			if isGroup {
+				go func() {
+					for er := range errs {
+						s.errors <- er
+					}
+					errWg.Done()
+				}()
+			} else {
+				elecount++
+				go func(count int) {
+					for er := range errs {
+						s.errors <- fmt.Errorf("error when parsing element nr. %d: %s", count, er)
+					}
+					errWg.Done()
+				}(elecount)
 			}
-		}
 
-		for _, g := range s.Groups {
-			instrs, errs := g.ParseDrawingInstructions()
-			errWg.Add(1)
-			go func() {
-				for er := range errs {
-					s.errors <- er
-				}
-				errWg.Done()
-			}()
 			for is := range instrs {
 				s.instructions <- is
 			}
@@ -203,19 +313,21 @@ func (s *Svg) ParseDrawingInstructions() (chan *DrawingInstruction, chan error)
 
 // UnmarshalXML implements the encoding.xml.Unmarshaler interface
 func (s *Svg) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
-	for {
-		for _, attr := range start.Attr {
-			if attr.Name.Local == "viewBox" {
-				s.ViewBox = attr.Value
-			}
-			if attr.Name.Local == "width" {
-				s.Width = attr.Value
-			}
-			if attr.Name.Local == "height" {
-				s.Height = attr.Value
-			}
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "viewBox":
+			s.ViewBox = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "width":
+			s.Width = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "height":
+			s.Height = attr.Value
+		default:
+			s.Attrs.Set(attr.Name, attr.Value)
 		}
+	}
+	newXMLSource(s, start)
 
+	for {
 		token, err := decoder.Token()
 		if err != nil {
 			return err
@@ -227,11 +339,13 @@ func (s *Svg) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
 
 			switch tok.Name.Local {
 			case "g":
-				g := &Group{Owner: s, Transform: mt.NewTransform()}
+				g := &Group{Owner: s, Transform: mt.NewTransform(), key: new(groupKey)}
 				if err = decoder.DecodeElement(g, &tok); err != nil {
 					return fmt.Errorf("error decoding group element within SVG struct: %s", err)
 				}
 				s.Groups = append(s.Groups, *g)
+				s.Children = append(s.Children, g)
+				s.content = append(s.content, g)
 				continue
 			case "rect":
 				dip = &Rect{}
@@ -240,32 +354,42 @@ func (s *Svg) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
 			case "path":
 				dip = &Path{}
 			case "svg":
-				
 				dip = &Svg{}
 			default:
-				// For any other elements (like defs, style, etc.), skip them completely
-				if err = decoder.Skip(); err != nil {
-					return fmt.Errorf("error skipping element %s: %s", tok.Name.Local, err)
+				// Any other elements (like defs, style, etc.) are not
+				// interpreted but kept so they can be written back.
+				raw, err := readRawElement(decoder, tok)
+				if err != nil {
+					return fmt.Errorf("error reading element %s: %s", tok.Name.Local, err)
 				}
+				s.content = append(s.content, raw)
 				continue
 			}
 
 			if err = decoder.DecodeElement(dip, &tok); err != nil {
 				return fmt.Errorf("error decoding element of SVG struct: %s", err)
 			}
+			setSource(dip, tok)
 
 			s.Elements = append(s.Elements, dip)
+			s.Children = append(s.Children, dip)
+			s.content = append(s.content, dip)
 
 		case xml.EndElement:
 			if tok.Name.Local == "svg" {
+				s.linkGroups()
 				return nil
 			}
+		default:
+			s.content = append(s.content, xml.CopyToken(tok))
 		}
 	}
 }
 
-// ParseSvg parses an SVG string into an SVG struct
-func ParseSvg(str string, name string, scale float64) (*Svg, error) {
+// ParseSvg parses an SVG string into an SVG struct. A negative scale is
+// taken as its inverse. The scale multiplies stroke widths only; the
+// points of drawing instructions and segments stay in user units.
+func ParseSvg(str string, name string, scale float64, opts ...ParseOption) (*Svg, error) {
 	var svg Svg
 	svg.Name = name
 	svg.Transform = mt.NewTransform()
@@ -277,8 +401,11 @@ func ParseSvg(str string, name string, scale float64) (*Svg, error) {
 		svg.Transform.Scale(1.0/-scale, 1.0/-scale)
 		svg.scale = 1.0 / -scale
 	}
+	for _, opt := range opts {
+		opt(&svg)
+	}
 
-	err := xml.Unmarshal([]byte(str), &svg)
+	err := svg.decode(xml.NewDecoder(strings.NewReader(str)))
 	if err != nil {
 		return nil, fmt.Errorf("ParseSvg Error: %v", err)
 	}
@@ -292,8 +419,9 @@ func ParseSvg(str string, name string, scale float64) (*Svg, error) {
 	return &svg, nil
 }
 
-// ParseSvgFromReader parses an SVG struct from an io.Reader
-func ParseSvgFromReader(r io.Reader, name string, scale float64) (*Svg, error) {
+// ParseSvgFromReader parses an SVG struct from an io.Reader, with the
+// scale as in ParseSvg.
+func ParseSvgFromReader(r io.Reader, name string, scale float64, opts ...ParseOption) (*Svg, error) {
 	var svg Svg
 	svg.Name = name
 	svg.Transform = mt.NewTransform()
@@ -305,8 +433,11 @@ func ParseSvgFromReader(r io.Reader, name string, scale float64) (*Svg, error) {
 		svg.Transform.Scale(1.0/-scale, 1.0/-scale)
 		svg.scale = 1.0 / -scale
 	}
+	for _, opt := range opts {
+		opt(&svg)
+	}
 
-	if err := xml.NewDecoder(r).Decode(&svg); err != nil {
+	if err := svg.decode(xml.NewDecoder(r)); err != nil {
 		return nil, fmt.Errorf("ParseSvg Error: %v", err)
 	}
 
@@ -319,6 +450,110 @@ func ParseSvgFromReader(r io.Reader, name string, scale float64) (*Svg, error) {
 	return &svg, nil
 }
 
+// linkGroups points the group entries of Children and the content at the
+// groups stored in Groups.
+func (s *Svg) linkGroups() {
+	var i int
+	for j, c := range s.Children {
+		if _, ok := c.(*Group); ok {
+			s.Children[j] = &s.Groups[i]
+			i++
+		}
+	}
+	i = 0
+	for j, c := range s.content {
+		if _, ok := c.(*Group); ok {
+			s.content[j] = &s.Groups[i]
+			i++
+		}
+	}
+}
+
+// currentGroups returns a function mapping a group of Children or the
+// content to its entry in Groups, which differs once Groups was
+// reallocated. Groups which are no longer in Groups are returned as
+// they are.
+func (s *Svg) currentGroups() func(*Group) *Group {
+	byKey := make(map[*groupKey]*Group)
+	for i := range s.Groups {
+		if k := s.Groups[i].key; k != nil {
+			byKey[k] = &s.Groups[i]
+		}
+	}
+	return func(g *Group) *Group {
+		if c, ok := byKey[g.key]; ok && g.key != nil {
+			return c
+		}
+		return g
+	}
+}
+
+// children returns the top level elements in drawing order: Children
+// followed by elements and groups which were only added to Elements or
+// Groups. Elements which were removed from Elements or Groups are left
+// out.
+func (s *Svg) children() []DrawingInstructionParser {
+	present := make(map[DrawingInstructionParser]bool)
+	for _, e := range s.Elements {
+		present[e] = true
+	}
+	for i := range s.Groups {
+		present[&s.Groups[i]] = true
+	}
+
+	current := s.currentGroups()
+	var children []DrawingInstructionParser
+	for _, c := range s.Children {
+		if g, ok := c.(*Group); ok {
+			c = current(g)
+		}
+		if present[c] {
+			children = append(children, c)
+			delete(present, c)
+		}
+	}
+	for _, e := range s.Elements {
+		if present[e] {
+			children = append(children, e)
+			delete(present, e)
+		}
+	}
+	for i := range s.Groups {
+		if present[&s.Groups[i]] {
+			children = append(children, &s.Groups[i])
+		}
+	}
+	return children
+}
+
+// decode reads a complete document. The tokens before and after the
+// root element are kept so the document can be written back.
+func (s *Svg) decode(decoder *xml.Decoder) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if err := decoder.DecodeElement(s, &start); err != nil {
+				return err
+			}
+			break
+		}
+		s.prolog = append(s.prolog, xml.CopyToken(token))
+	}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.epilog = append(s.epilog, xml.CopyToken(token))
+	}
+}
+
 // ViewBoxValues returns all the numerical values in the viewBox
 // attribute.
 func (s *Svg) ViewBoxValues() ([]float64, error) {
@@ -344,13 +579,21 @@ func (s *Svg) ViewBoxValues() ([]float64, error) {
 // SetOwner sets the owner of a SVG Group
 func (g *Group) SetOwner(svg *Svg) {
 	g.Owner = svg
+	if g.Layer != nil {
+		g.Layer.Group = g
+	}
 	for _, gn := range g.Elements {
 		switch gn.(type) {
 		case *Group:
+			gn.(*Group).Parent = g
 			gn.(*Group).Owner = g.Owner
 			gn.(*Group).SetOwner(svg)
 		case *Path:
 			gn.(*Path).group = g
+		case *Circle:
+			gn.(*Circle).group = g
+		case *Rect:
+			gn.(*Rect).group = g
 		}
 	}
 }
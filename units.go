@@ -0,0 +1,86 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	mt "github.com/rustyoz/Mtransform"
+)
+
+// Millimetres per unit of the absolute length units of CSS. Lengths
+// without a unit are pixels.
+var millimetersPerUnit = map[string]float64{
+	"":   25.4 / 96,
+	"px": 25.4 / 96,
+	"pt": 25.4 / 72,
+	"pc": 25.4 / 6,
+	"in": 25.4,
+	"cm": 10,
+	"mm": 1,
+	"q":  0.25,
+}
+
+// ParseLength returns a length with an absolute unit, such as the width
+// and height of a document, in millimetres. Relative lengths like
+// percentages are not converted.
+func ParseLength(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	end := len(s)
+	for end > 0 && (s[end-1] >= 'a' && s[end-1] <= 'z' || s[end-1] >= 'A' && s[end-1] <= 'Z' || s[end-1] == '%') {
+		end--
+	}
+	factor, ok := millimetersPerUnit[strings.ToLower(s[end:])]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s[:end]), 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * factor, true
+}
+
+// PhysicalSize returns the width and height of the document in
+// millimetres. A missing width or height is taken from the view box in
+// pixels, keeping its aspect ratio. ok is false when neither gives a
+// size.
+func (s *Svg) PhysicalSize() (width, height float64, ok bool) {
+	w, wok := ParseLength(s.Width)
+	h, hok := ParseLength(s.Height)
+	if wok && hok && w > 0 && h > 0 {
+		return w, h, true
+	}
+	vb, err := s.ViewBoxValues()
+	if err != nil || len(vb) != 4 || vb[2] <= 0 || vb[3] <= 0 {
+		return 0, 0, false
+	}
+	switch {
+	case wok && w > 0:
+		return w, w * vb[3] / vb[2], true
+	case hok && h > 0:
+		return h * vb[2] / vb[3], h, true
+	}
+	px := millimetersPerUnit["px"]
+	return vb[2] * px, vb[3] * px, true
+}
+
+// MillimeterTransform returns the transform from the user units of the
+// document to millimetres. The view box is scaled uniformly to fit the
+// physical size and centred in it. Without a view box user units are
+// pixels.
+func (s *Svg) MillimeterTransform() mt.Transform {
+	t := mt.Identity()
+	px := millimetersPerUnit["px"]
+	w, h, ok := s.PhysicalSize()
+	vb, err := s.ViewBoxValues()
+	if !ok || err != nil || len(vb) != 4 || vb[2] <= 0 || vb[3] <= 0 {
+		t.Scale(px, px)
+		return t
+	}
+	sc := math.Min(w/vb[2], h/vb[3])
+	t.Translate((w-vb[2]*sc)/2, (h-vb[3]*sc)/2)
+	t.Scale(sc, sc)
+	t.Translate(-vb[0], -vb[1])
+	return t
+}
@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLength(t *testing.T) {
+	for s, mm := range map[string]float64{
+		"10mm":  10,
+		"2cm":   20,
+		"1in":   25.4,
+		"72pt":  25.4,
+		"96px":  25.4,
+		"96":    25.4,
+		" 4Q ":  1,
+		"1.5in": 38.1,
+	} {
+		v, ok := ParseLength(s)
+		require.True(t, ok, s)
+		require.InDelta(t, mm, v, 1e-9, s)
+	}
+	for _, s := range []string{"", "50%", "2em", "mm"} {
+		_, ok := ParseLength(s)
+		require.False(t, ok, s)
+	}
+}
+
+func TestPhysicalSize(t *testing.T) {
+	size := func(attrs string) []float64 {
+		s, err := ParseSvg(`<svg `+attrs+`></svg>`, "size", 1)
+		require.NoError(t, err)
+		w, h, ok := s.PhysicalSize()
+		if !ok {
+			return nil
+		}
+		return []float64{w, h}
+	}
+	require.Equal(t, []float64{100, 50}, size(`width="100mm" height="5cm"`))
+	require.Equal(t, []float64{100, 25}, size(`width="100mm" viewBox="0 0 200 50"`))
+	require.Equal(t, []float64{20, 10}, size(`height="10mm" viewBox="0 0 200 100"`))
+	require.Equal(t, []float64{25.4, 12.7}, size(`width="100%" viewBox="0 0 96 48"`))
+	require.Nil(t, size(`width="100%"`))
+}
+
+func TestMillimeterTransform(t *testing.T) {
+	s, err := ParseSvg(`<svg width="100mm" height="100mm" viewBox="10 10 200 100"></svg>`, "mm", 1)
+	require.NoError(t, err)
+	m := s.MillimeterTransform()
+	// The view box is scaled by half and centred vertically.
+	x, y := m.Apply(10, 10)
+	requirePoint(t, [2]float64{0, 25}, [2]float64{x, y}, 1e-9)
+	x, y = m.Apply(210, 110)
+	requirePoint(t, [2]float64{100, 75}, [2]float64{x, y}, 1e-9)
+
+	s, err = ParseSvg(`<svg></svg>`, "px", 1)
+	require.NoError(t, err)
+	m = s.MillimeterTransform()
+	x, y = m.Apply(96, 48)
+	requirePoint(t, [2]float64{25.4, 12.7}, [2]float64{x, y}, 1e-9)
+}
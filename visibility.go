@@ -0,0 +1,132 @@
+package svg
+
+import "strings"
+
+// presentation is implemented by elements carrying the display and
+// visibility properties, either as attributes or in their style.
+type presentation interface {
+	display() string
+	visibility() string
+}
+
+// presentationValue returns the value of a property, giving the style
+// attribute precedence over the presentation attribute.
+func presentationValue(style, attr, key string) string {
+	for k, v := range splitStyle(style) {
+		if strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return strings.TrimSpace(attr)
+}
+
+func (g *Group) display() string {
+	return presentationValue(g.Style, g.Display, "display")
+}
+
+func (g *Group) visibility() string {
+	return presentationValue(g.Style, g.Visibility, "visibility")
+}
+
+func (p *Path) display() string {
+	return presentationValue(p.Style, p.Display, "display")
+}
+
+func (p *Path) visibility() string {
+	return presentationValue(p.Style, p.Visibility, "visibility")
+}
+
+func (c *Circle) display() string {
+	return presentationValue(c.Style, c.Display, "display")
+}
+
+func (c *Circle) visibility() string {
+	return presentationValue(c.Style, c.Visibility, "visibility")
+}
+
+func (r *Rect) display() string {
+	return presentationValue(r.Style, r.Display, "display")
+}
+
+func (r *Rect) visibility() string {
+	return presentationValue(r.Style, r.Visibility, "visibility")
+}
+
+// resolveVisibility applies an element's own visibility value to the
+// value inherited from its parent.
+func resolveVisibility(own, inherited string) string {
+	switch own {
+	case "visible", "hidden", "collapse":
+		return own
+	}
+	return inherited
+}
+
+// computedVisibility returns the visibility inherited by the children
+// of the group.
+func (g *Group) computedVisibility() string {
+	inherited := "visible"
+	if g.Parent != nil {
+		inherited = g.Parent.computedVisibility()
+	}
+	return resolveVisibility(g.visibility(), inherited)
+}
+
+// rendered reports whether an element produces any output. Elements
+// with display none are skipped together with their children. Hidden
+// groups are still descended into because their children may override
+// the inherited visibility.
+func rendered(e DrawingInstructionParser, inherited string) bool {
+	pe, ok := e.(presentation)
+	if !ok {
+		return true
+	}
+	if pe.display() == "none" {
+		return false
+	}
+	if _, ok := e.(*Group); ok {
+		return true
+	}
+	return resolveVisibility(pe.visibility(), inherited) == "visible"
+}
+
+// VisitElements calls fn for the elements of the document that would be
+// drawn by ParseDrawingInstructions, in paint order, together with the
+// innermost group holding the element, nil at the top level. Groups are
+// descended into rather than visited themselves.
+func (s *Svg) VisitElements(fn func(e DrawingInstructionParser, g *Group)) {
+	s.drawnElements(fn)
+}
+
+func (s *Svg) drawnElements(fn func(DrawingInstructionParser, *Group)) {
+	for _, e := range s.children() {
+		if !s.showsHidden() && !rendered(e, "visible") {
+			continue
+		}
+		switch e := e.(type) {
+		case *Group:
+			e.drawnElements(fn)
+		case *Svg:
+			e.drawnElements(fn)
+		default:
+			if s.layers == nil {
+				fn(e, nil)
+			}
+		}
+	}
+}
+
+func (g *Group) drawnElements(fn func(DrawingInstructionParser, *Group)) {
+	visibility := g.computedVisibility()
+	selected := g.inSelectedLayer()
+	for _, e := range g.Elements {
+		if !g.Owner.showsHidden() && !rendered(e, visibility) {
+			continue
+		}
+		if sub, ok := e.(*Group); ok {
+			sub.drawnElements(fn)
+		} else if selected {
+			fn(e, g)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const hiddenSvg = `<svg viewBox="0 0 100 100">
+<path d="M0 0 L10 0" display="none"/>
+<path d="M0 1 L10 1"/>
+<g style="display:none"><path d="M0 2 L10 2"/></g>
+<g visibility="hidden">
+	<path d="M0 3 L10 3"/>
+	<path d="M0 4 L10 4" visibility="visible"/>
+	<g><path d="M0 5 L10 5"/></g>
+	<g style="visibility: visible"><path d="M0 6 L10 6"/></g>
+</g>
+<g><path d="M0 7 L10 7" style="visibility:hidden"/></g>
+</svg>`
+
+func movedToY(t *testing.T, s *Svg) []float64 {
+	dis, errChan := s.ParseDrawingInstructions()
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+	var ys []float64
+	for di := range dis {
+		if di.Kind == MoveInstruction {
+			ys = append(ys, di.M[1])
+		}
+	}
+	return ys
+}
+
+func TestHiddenElementsSkipped(t *testing.T) {
+	s, err := ParseSvg(hiddenSvg, "hidden", 0)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 4, 6}, movedToY(t, s))
+}
+
+func TestIncludeHidden(t *testing.T) {
+	s, err := ParseSvg(hiddenSvg, "hidden", 0, IncludeHidden())
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 1, 2, 3, 4, 5, 6, 7}, movedToY(t, s))
+}